@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/term"
+	"rentacar/backend/internal/migrations"
+	"rentacar/backend/internal/repositories"
+)
+
+const minAdminPasswordLength = 12
+
+func runCommand(db *sql.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		return migrateCommand(db, args[1:])
+	case "bootstrap-admin":
+		return bootstrapAdminCommand(db, args[1:])
+	default:
+		return fmt.Errorf("unknown command %q (expected migrate or bootstrap-admin)", args[0])
+	}
+}
+
+func newMigrator(db *sql.DB) *migrations.Migrator {
+	return &migrations.Migrator{DB: db, Dir: env("MIGRATIONS_DIR", "migrations")}
+}
+
+func runMigrations(db *sql.DB) error {
+	ran, err := newMigrator(db).Up()
+	for _, m := range ran {
+		log.Printf("applied migration %03d_%s", m.Version, m.Name)
+	}
+	return err
+}
+
+func migrateCommand(db *sql.DB, args []string) error {
+	m := newMigrator(db)
+	action := "up"
+	if len(args) > 0 {
+		action = args[0]
+	}
+	switch action {
+	case "up":
+		return runMigrations(db)
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		reverted, err := m.Down(steps)
+		for _, mg := range reverted {
+			log.Printf("reverted migration %03d_%s", mg.Version, mg.Name)
+		}
+		return err
+	case "status":
+		items, err := m.Status()
+		for _, it := range items {
+			state := "pending"
+			if it.Applied {
+				state = "applied " + it.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Printf("%03d_%-30s %s\n", it.Version, it.Name, state)
+		}
+		return err
+	default:
+		return fmt.Errorf("unknown migrate action %q (expected up, down or status)", action)
+	}
+}
+
+func readPassword(prompt string, in *bufio.Reader) (string, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(int(os.Stdin.Fd())) {
+		b, err := term.ReadPassword(int(os.Stdin.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(b), err
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// bootstrapAdminCommand creates the first admin account. It refuses to run
+// once any admin exists, so it cannot be used to take over an installation.
+func bootstrapAdminCommand(db *sql.DB, args []string) error {
+	if err := runMigrations(db); err != nil {
+		return err
+	}
+	users := &repositories.UserRepository{DB: db}
+	n, err := users.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		return errors.New("an admin account already exists; manage users from the admin panel")
+	}
+
+	in := bufio.NewReader(os.Stdin)
+	username := ""
+	if len(args) > 0 {
+		username = args[0]
+	} else {
+		fmt.Fprint(os.Stderr, "Admin username: ")
+		line, err := in.ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		username = line
+	}
+	username = strings.TrimSpace(username)
+	if len(username) < 3 {
+		return errors.New("username must be at least 3 characters")
+	}
+	if _, err := users.FindByUsername(username); err == nil {
+		return fmt.Errorf("user %q already exists", username)
+	}
+
+	password, err := readPassword("Password: ", in)
+	if err != nil {
+		return err
+	}
+	if len(password) < minAdminPasswordLength {
+		return fmt.Errorf("admin password must be at least %d characters", minAdminPasswordLength)
+	}
+	confirm, err := readPassword("Repeat password: ", in)
+	if err != nil {
+		return err
+	}
+	if password != confirm {
+		return errors.New("passwords do not match")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	u, err := users.Create(username, string(hash), "admin")
+	if err != nil {
+		return err
+	}
+	audit := &repositories.AuditLogRepository{DB: db}
+	_ = audit.Create("system", "bootstrap-admin", "create", "user", u.ID, "initial admin "+username)
+	log.Printf("created admin %q", username)
+	return nil
+}
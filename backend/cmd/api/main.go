@@ -9,15 +9,18 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	_ "github.com/mattn/go-sqlite3"
-	"golang.org/x/crypto/bcrypt"
+	"rentacar/backend/internal/email"
 	"rentacar/backend/internal/handlers"
 	"rentacar/backend/internal/middleware"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
 	"rentacar/backend/internal/repositories"
 	"rentacar/backend/internal/services"
 )
@@ -30,6 +33,199 @@ func env(k, d string) string {
 	return v
 }
 
+func envDuration(k string, d time.Duration) time.Duration {
+	v := os.Getenv(k)
+	if v == "" {
+		return d
+	}
+	parsed, err := time.ParseDuration(v)
+	if err != nil {
+		log.Fatalf("%s: %v", k, err)
+	}
+	return parsed
+}
+
+func envFloat(k string, d float64) float64 {
+	v := os.Getenv(k)
+	if v == "" {
+		return d
+	}
+	parsed, err := strconv.ParseFloat(v, 64)
+	if err != nil || parsed < 0 {
+		log.Fatalf("%s must be a non-negative number, got %q", k, v)
+	}
+	return parsed
+}
+
+func currency() string {
+	code, err := services.NormalizeBaseCurrency(env("CURRENCY", services.DefaultCurrency))
+	if err != nil {
+		log.Fatalf("CURRENCY: %v", err)
+	}
+	return code
+}
+
+// checkPriceCurrency refuses a CURRENCY that stored prices are not in.
+// Prices are never converted, and the ones from before amounts carried a
+// currency were in dollars.
+func checkPriceCurrency(db *sql.DB, base string) error {
+	var other string
+	err := db.QueryRow(`SELECT currency FROM cars WHERE currency<>?1
+	UNION SELECT currency FROM extras WHERE currency<>?1
+	UNION SELECT currency FROM pricing_rules WHERE currency<>?1
+	UNION SELECT currency FROM category_deposits WHERE currency<>?1
+	UNION SELECT currency FROM promo_codes WHERE currency<>?1 LIMIT 1`, base).Scan(&other)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("CURRENCY is %s but prices are stored in %s; set CURRENCY=%s", base, other, other)
+}
+
+const (
+	defaultJWTSecret     = "supersecret"
+	minJWTSecretLength   = 32
+	defaultWebhookSecret = "dev-webhook-secret"
+)
+
+func rentalPolicy() services.RentalPolicy {
+	mode := strings.ToLower(env("RENTAL_DAY_MODE", services.RentalDay24h))
+	if mode != services.RentalDay24h && mode != services.RentalDayCalendar {
+		log.Fatalf("RENTAL_DAY_MODE must be 24h or calendar, got %q", mode)
+	}
+	return services.RentalPolicy{
+		DayMode:          mode,
+		GracePeriod:      envDuration("RENTAL_GRACE_PERIOD", services.DefaultGracePeriod),
+		TurnaroundBuffer: envDuration("TURNAROUND_BUFFER", services.DefaultTurnaroundBuffer),
+	}
+}
+
+func appEnv() string {
+	v := strings.ToLower(env("APP_ENV", "development"))
+	switch v {
+	case "development", "production", "test":
+		return v
+	default:
+		log.Fatalf("APP_ENV must be development, production or test, got %q", v)
+		return ""
+	}
+}
+
+// jwtSecret falls back to a well-known value only outside production.
+func jwtSecret(appEnv string) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if appEnv != "production" {
+		if secret == "" {
+			log.Printf("JWT_SECRET is not set, using the insecure development default")
+			return defaultJWTSecret, nil
+		}
+		return secret, nil
+	}
+	if secret == "" || secret == defaultJWTSecret {
+		return "", fmt.Errorf("JWT_SECRET must be set to a non-default value in production")
+	}
+	if len(secret) < minJWTSecretLength {
+		return "", fmt.Errorf("JWT_SECRET must be at least %d characters in production", minJWTSecretLength)
+	}
+	return secret, nil
+}
+
+// newMailer picks how mail goes out. The log driver prints password reset
+// links, so production only sends through SMTP.
+func newMailer(appEnv string) email.Mailer {
+	from := env("MAIL_FROM", "RentACar <no-reply@rentacar.local>")
+	driver := env("MAIL_DRIVER", "log")
+	if appEnv == "production" {
+		driver = env("MAIL_DRIVER", "smtp")
+	}
+	switch driver {
+	case "smtp":
+		port, err := strconv.Atoi(env("SMTP_PORT", "587"))
+		if err != nil {
+			log.Fatalf("SMTP_PORT: %v", err)
+		}
+		return &email.SMTPMailer{Host: env("SMTP_HOST", "localhost"), Port: port, Username: os.Getenv("SMTP_USERNAME"), Password: os.Getenv("SMTP_PASSWORD"), From: from}
+	case "log":
+		if appEnv == "production" {
+			log.Fatalf("MAIL_DRIVER=log would write password reset links to the logs; use smtp in production")
+		}
+		return &email.LogMailer{Dir: os.Getenv("MAIL_DIR"), From: from}
+	default:
+		log.Fatalf("MAIL_DRIVER must be smtp or log")
+		return nil
+	}
+}
+
+// webhookSecret verifies gateway webhooks. It must differ from the JWT
+// secret, or whoever can sign webhooks could also sign access tokens, and
+// falls back to a well-known value only outside production.
+func webhookSecret(appEnv, jwtSecret string) (string, error) {
+	secret := os.Getenv("PAYMENT_WEBHOOK_SECRET")
+	if secret == "" && appEnv != "production" {
+		log.Printf("PAYMENT_WEBHOOK_SECRET is not set, using the insecure development default")
+		secret = defaultWebhookSecret
+	}
+	if secret == "" || (appEnv == "production" && secret == defaultWebhookSecret) {
+		return "", fmt.Errorf("PAYMENT_WEBHOOK_SECRET must be set to a non-default value in production")
+	}
+	if secret == jwtSecret {
+		return "", fmt.Errorf("PAYMENT_WEBHOOK_SECRET must differ from JWT_SECRET")
+	}
+	return secret, nil
+}
+
+// paymentProvider picks the gateway. The fake one moves no money and lets
+// customers choose the outcome, so production only runs it when
+// ALLOW_FAKE_PAYMENTS=1 says so.
+func paymentProvider(mode, jwtSecret string) payments.PaymentProvider {
+	switch env("PAYMENT_PROVIDER", "fake") {
+	case "fake":
+		if mode == "production" {
+			if os.Getenv("ALLOW_FAKE_PAYMENTS") != "1" {
+				log.Fatalf("PAYMENT_PROVIDER=fake confirms bookings without taking payments; set ALLOW_FAKE_PAYMENTS=1 to run it in production anyway")
+			}
+			log.Printf("WARNING: PAYMENT_PROVIDER=fake in production: bookings are confirmed without taking any payment")
+		}
+		secret, err := webhookSecret(mode, jwtSecret)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return &payments.FakeProvider{Secret: secret}
+	default:
+		log.Fatalf("PAYMENT_PROVIDER must be fake")
+		return nil
+	}
+}
+
+// expirePaymentHolds cancels bookings whose payment never came through,
+// once at startup and then every interval.
+func expirePaymentHolds(p *services.PaymentService, interval time.Duration) {
+	for {
+		if n, err := p.ExpireHolds(time.Now()); err != nil {
+			log.Printf("expire payment holds: %v", err)
+		} else if n > 0 {
+			log.Printf("cancelled %d bookings whose payment hold expired", n)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// invoiceSeller is the company printed on invoices.
+func invoiceSeller(mode string) models.InvoiceParty {
+	seller := models.InvoiceParty{
+		Name:    env("COMPANY_NAME", "RentACar"),
+		Address: os.Getenv("COMPANY_ADDRESS"),
+		TaxID:   os.Getenv("COMPANY_TAX_ID"),
+		Email:   os.Getenv("COMPANY_EMAIL"),
+	}
+	if mode == "production" && (seller.Address == "" || seller.TaxID == "") {
+		log.Printf("COMPANY_ADDRESS and COMPANY_TAX_ID should be set: they are printed on invoices")
+	}
+	return seller
+}
+
 func sqliteDSN(raw string) string {
 	if strings.Contains(raw, "?") {
 		return raw + "&_busy_timeout=5000&_journal_mode=WAL"
@@ -73,20 +269,6 @@ func isAllowedOrigin(origin string, allowed []string) bool {
 	return false
 }
 
-func runMigrations(db *sql.DB) error {
-	files, err := filepath.Glob("migrations/*.sql")
-	if err != nil {
-		return err
-	}
-	for _, f := range files {
-		b, _ := os.ReadFile(f)
-		if _, err := db.Exec(string(b)); err != nil {
-			return fmt.Errorf("%s: %w", f, err)
-		}
-	}
-	return nil
-}
-
 func imageExtFromDataURL(dataURL string) string {
 	switch {
 	case strings.HasPrefix(dataURL, "data:image/png"):
@@ -170,71 +352,39 @@ func migrateDataImages(db *sql.DB) error {
 	return nil
 }
 
-func seedDev(db *sql.DB) error {
-	var c int
-	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&c); err != nil {
-		return err
-	}
-
-	if c == 0 {
-		seedUsers := []struct{ U, P, R string }{{"admin", "admin", "admin"}, {"user1", "admin", "user"}, {"user2", "admin", "user"}}
-		for _, u := range seedUsers {
-			h, _ := bcrypt.GenerateFromPassword([]byte(u.P), bcrypt.DefaultCost)
-			_, _ = db.Exec(`INSERT INTO users(id, username, password_hash, role) VALUES(lower(hex(randomblob(16))),?,?,?)`, u.U, string(h), u.R)
-		}
-		extra := []string{"Child seat|6", "Additional driver|12", "Insurance|15"}
-		for _, e := range extra {
-			p := strings.Split(e, "|")
-			_, _ = db.Exec(`INSERT INTO extras(id,name,price_per_day) VALUES(lower(hex(randomblob(16))),?,?)`, p[0], p[1])
-		}
-		_, _ = db.Exec(`INSERT INTO cars(id,brand,model,year,category,transmission,fuel,seats,daily_price,status,mileage,description,images) VALUES
-	(lower(hex(randomblob(16))),'Toyota','Corolla',2022,'sedan','automatic','gasoline',5,55,'available',32000,'Reliable city sedan','["https://i.gaw.to/vehicles/photos/40/27/402780-2022-toyota-corolla.jpg?1024x640","https://di-uploads-pod16.dealerinspire.com/toyotaofnorthcharlotte/uploads/2022/07/N-Charlotte-Toyota-sedan.png"]'),
-	(lower(hex(randomblob(16))),'BMW','X5',2023,'suv','automatic','diesel',5,120,'available',12000,'Premium SUV','["https://media.autoexpress.co.uk/image/private/s--X-WVjvBW--/f_auto,t_content-image-full-desktop@1/v1675682840/autoexpress/2023/02/BMW%20X5%20facelift%202023-9.jpg","https://hips.hearstapps.com/hmg-prod/images/2023-bmw-x5-interior-1660571768.jpg"]')`)
-		_, _ = db.Exec(`DELETE FROM extras WHERE LOWER(TRIM(name))='gps'`)
-		return nil
-	}
-
-	adminHash, err := bcrypt.GenerateFromPassword([]byte("admin"), bcrypt.DefaultCost)
-	if err != nil {
-		return err
-	}
-	_, err = db.Exec(`UPDATE users SET password_hash=?, role='admin' WHERE username='admin'`, string(adminHash))
+func main() {
+	db, err := sql.Open("sqlite3", sqliteDSN(env("DATABASE_URL", "./rentacar.db")))
 	if err != nil {
-		return err
-	}
-	var adminExists int
-	if err := db.QueryRow(`SELECT COUNT(*) FROM users WHERE username='admin'`).Scan(&adminExists); err != nil {
-		return err
+		log.Fatal(err)
 	}
-	if adminExists == 0 {
-		if _, err := db.Exec(`INSERT INTO users(id, username, password_hash, role) VALUES(lower(hex(randomblob(16))),'admin',?,'admin')`, string(adminHash)); err != nil {
-			return err
+	defer db.Close()
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1:]); err != nil {
+			log.Fatal(err)
 		}
+		return
 	}
-
-	// GPS is intentionally removed from paid extras.
-	_, _ = db.Exec(`DELETE FROM extras WHERE LOWER(TRIM(name))='gps'`)
-
-	return nil
-}
-
-func main() {
-	db, err := sql.Open("sqlite3", sqliteDSN(env("DATABASE_URL", "./rentacar.db")))
+	mode := appEnv()
+	secret, err := jwtSecret(mode)
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer db.Close()
 	if err := runMigrations(db); err != nil {
 		log.Fatal(err)
 	}
+	if err := checkPriceCurrency(db, currency()); err != nil {
+		log.Fatal(err)
+	}
 	if err := os.MkdirAll("uploads", 0o755); err != nil {
 		log.Fatal(err)
 	}
 	if err := migrateDataImages(db); err != nil {
 		log.Fatal(err)
 	}
-	if err := seedDev(db); err != nil {
-		log.Fatal(err)
+	if mode == "development" {
+		if err := seedDev(db, currency()); err != nil {
+			log.Fatal(err)
+		}
 	}
 
 	users := &repositories.UserRepository{DB: db}
@@ -242,8 +392,87 @@ func main() {
 	extras := &repositories.ExtraRepository{DB: db}
 	reservations := &repositories.ReservationRepository{DB: db}
 	reviews := &repositories.ReviewRepository{DB: db}
+	branches := &repositories.BranchRepository{DB: db}
+	pricingRules := &repositories.PricingRuleRepository{DB: db}
 	audit := &repositories.AuditLogRepository{DB: db}
-	h := &handlers.Handler{Auth: &services.AuthService{Users: users, JWTSecret: env("JWT_SECRET", "supersecret")}, Cars: cars, Extras: extras, Reservations: reservations, Reviews: reviews, Audit: audit, ReservationService: &services.ReservationService{Cars: cars, Reservations: reservations, Extras: extras}}
+	sessions := &repositories.SessionRepository{DB: db}
+	paymentRepo := &repositories.PaymentRepository{DB: db}
+	deposits := &repositories.DepositRepository{DB: db}
+	cancellationPolicies := &repositories.CancellationPolicyRepository{DB: db}
+	promoCodes := &repositories.PromoCodeRepository{DB: db}
+	paymentService := &services.PaymentService{Payments: paymentRepo, Reservations: reservations, Provider: paymentProvider(mode, secret), Audit: audit, HoldTTL: envDuration("PAYMENT_HOLD_TTL", services.DefaultPaymentHoldTTL)}
+	userCache := &services.UserCache{Users: users, TTL: envDuration("USER_CACHE_TTL", services.DefaultUserCacheTTL)}
+	authService := &services.AuthService{
+		Users:           users,
+		Sessions:        sessions,
+		UserCache:       userCache,
+		Throttle:        &services.LoginThrottle{Throttles: &repositories.LoginThrottleRepository{DB: db}, Audit: audit},
+		TOTP:            &repositories.TOTPRepository{DB: db},
+		TOTPIssuer:      env("TOTP_ISSUER", services.DefaultTOTPIssuer),
+		JWTSecret:       secret,
+		AccessTokenTTL:  envDuration("ACCESS_TOKEN_TTL", services.DefaultAccessTokenTTL),
+		RefreshTokenTTL: envDuration("REFRESH_TOKEN_TTL", services.DefaultRefreshTokenTTL),
+		PreAuthTokenTTL: envDuration("PRE_AUTH_TOKEN_TTL", services.DefaultPreAuthTokenTTL),
+	}
+	passwordResets := &services.PasswordResetService{
+		Users:     users,
+		Resets:    &repositories.PasswordResetRepository{DB: db},
+		Sessions:  sessions,
+		UserCache: userCache,
+		Mailer:    newMailer(mode),
+		BaseURL:   env("APP_BASE_URL", "http://localhost:5173"),
+		TTL:       envDuration("PASSWORD_RESET_TTL", services.DefaultPasswordResetTTL),
+	}
+	invoiceService := &services.InvoiceService{
+		Invoices:     &repositories.InvoiceRepository{DB: db},
+		Reservations: reservations,
+		Users:        users,
+		Payments:     paymentRepo,
+		Audit:        audit,
+		Seller:       invoiceSeller(mode),
+		Prefix:       env("INVOICE_PREFIX", services.DefaultInvoicePrefix),
+	}
+	reservationService := &services.ReservationService{
+		Cars:                 cars,
+		Reservations:         reservations,
+		Extras:               extras,
+		Audit:                audit,
+		Branches:             branches,
+		PricingRules:         pricingRules,
+		Users:                users,
+		Payments:             paymentService,
+		Deposits:             deposits,
+		CancellationPolicies: cancellationPolicies,
+		Invoices:             invoiceService,
+		PromoCodes:           promoCodes,
+		Policy:               rentalPolicy(),
+		Currency:             currency(),
+		TaxRate:              envFloat("TAX_RATE", 0),
+		Deposit:              models.MoneyFromFloat(envFloat("DEPOSIT_AMOUNT", 0)),
+		QuoteSecret:          secret,
+		QuoteTTL:             envDuration("QUOTE_TTL", services.DefaultQuoteTTL),
+	}
+	permissions := &services.PermissionService{Permissions: &repositories.PermissionRepository{DB: db}}
+	h := &handlers.Handler{
+		Auth:                 authService,
+		Cars:                 cars,
+		Extras:               extras,
+		Branches:             branches,
+		PricingRules:         pricingRules,
+		Payments:             paymentRepo,
+		Deposits:             deposits,
+		CancellationPolicies: cancellationPolicies,
+		PromoCodes:           promoCodes,
+		ExchangeRates:        &repositories.ExchangeRateRepository{DB: db},
+		Reservations:         reservations,
+		Reviews:              reviews,
+		Audit:                audit,
+		ReservationService:   reservationService,
+		UserService:          &services.UserService{Users: users, Sessions: sessions, UserCache: userCache, Payments: paymentService, Reservations: reservationService},
+		InvoiceService:       invoiceService,
+		PasswordResets:       passwordResets,
+		Permissions:          permissions,
+	}
 
 	r := gin.Default()
 	allowedOrigins := corsOrigins()
@@ -263,27 +492,82 @@ func main() {
 	api := r.Group("/api")
 	api.POST("/auth/register", h.Register)
 	api.POST("/auth/login", h.Login)
+	api.POST("/auth/login/totp", h.LoginTOTP)
+	api.POST("/auth/login/totp/setup", h.LoginTOTPSetup)
+	api.POST("/auth/refresh", h.Refresh)
+	api.POST("/auth/logout", h.Logout)
+	api.POST("/auth/forgot", h.ForgotPassword)
+	api.POST("/auth/reset", h.ResetPassword)
 	api.GET("/cars", h.ListCars)
 	api.GET("/cars/:id", h.GetCar)
 	api.GET("/cars/:id/availability", h.CarAvailability)
 	api.GET("/cars/:id/reviews", h.ListCarReviews)
 	api.GET("/extras", h.ListExtras)
+	api.GET("/branches", h.ListBranches)
+	api.GET("/exchange-rates", h.ListExchangeRates)
+	api.POST("/payments/webhook", h.PaymentWebhook)
 	auth := api.Group("")
-	auth.Use(middleware.AuthRequired(env("JWT_SECRET", "supersecret")))
+	auth.Use(middleware.AuthRequired(authService))
 	auth.GET("/auth/me", h.Me)
+	auth.PUT("/auth/me", h.UpdateMe)
+	auth.DELETE("/auth/me", h.DeleteMe)
+	auth.POST("/auth/password", h.ChangePassword)
+	auth.POST("/auth/logout-all", h.LogoutAll)
+	auth.GET("/auth/totp", h.TOTPStatus)
+	auth.POST("/auth/totp/setup", h.SetupTOTP)
+	auth.POST("/auth/totp/enable", h.EnableTOTP)
+	auth.POST("/auth/totp/disable", h.DisableTOTP)
+	auth.POST("/auth/totp/recovery-codes", h.RegenerateRecoveryCodes)
 	auth.POST("/reservations", h.CreateReservation)
+	auth.POST("/quotes", h.CreateQuote)
 	auth.POST("/cars/:id/reviews", h.CreateCarReview)
 	auth.GET("/reservations/my", h.ListMyReservations)
+	auth.GET("/reservations/:id/cancellation", h.CancellationQuote)
 	auth.PATCH("/reservations/:id/cancel", h.CancelReservation)
+	auth.POST("/reservations/:id/pay", h.PayReservation)
+	auth.GET("/reservations/:id/invoice", h.ReservationInvoice)
 	admin := auth.Group("/admin")
-	admin.Use(middleware.RequireRole("admin"))
-	admin.POST("/cars", h.CreateCar)
-	admin.POST("/uploads", h.AdminUploadImages)
-	admin.PUT("/cars/:id", h.UpdateCar)
-	admin.DELETE("/cars/:id", h.DeleteCar)
-	admin.GET("/reservations", h.AdminListReservations)
-	admin.PATCH("/reservations/:id/status", h.AdminUpdateReservationStatus)
-	admin.GET("/dashboard", h.AdminDashboard)
-	admin.GET("/audit-logs", h.AdminAuditLogs)
+	can := func(permission string) gin.HandlerFunc { return middleware.RequirePermission(permissions, permission) }
+	admin.POST("/cars", can(services.PermCarsWrite), h.CreateCar)
+	admin.POST("/uploads", can(services.PermCarsWrite), h.AdminUploadImages)
+	admin.PUT("/cars/:id", can(services.PermCarsWrite), h.UpdateCar)
+	admin.DELETE("/cars/:id", can(services.PermCarsWrite), h.DeleteCar)
+	admin.POST("/branches", can(services.PermBranchesWrite), h.AdminCreateBranch)
+	admin.PUT("/branches/:id", can(services.PermBranchesWrite), h.AdminUpdateBranch)
+	admin.GET("/pricing-rules", can(services.PermPricingManage), h.AdminListPricingRules)
+	admin.POST("/pricing-rules", can(services.PermPricingManage), h.AdminCreatePricingRule)
+	admin.PUT("/pricing-rules/:id", can(services.PermPricingManage), h.AdminUpdatePricingRule)
+	admin.DELETE("/pricing-rules/:id", can(services.PermPricingManage), h.AdminDeletePricingRule)
+	admin.PUT("/exchange-rates/:currency", can(services.PermPricingManage), h.AdminSetExchangeRate)
+	admin.DELETE("/exchange-rates/:currency", can(services.PermPricingManage), h.AdminDeleteExchangeRate)
+	admin.GET("/deposits", can(services.PermPricingManage), h.AdminListDeposits)
+	admin.PUT("/deposits/:category", can(services.PermPricingManage), h.AdminSetDeposit)
+	admin.DELETE("/deposits/:category", can(services.PermPricingManage), h.AdminDeleteDeposit)
+	admin.GET("/cancellation-policies", can(services.PermPricingManage), h.AdminListCancellationPolicies)
+	admin.POST("/cancellation-policies", can(services.PermPricingManage), h.AdminCreateCancellationPolicy)
+	admin.PUT("/cancellation-policies/:id", can(services.PermPricingManage), h.AdminUpdateCancellationPolicy)
+	admin.DELETE("/cancellation-policies/:id", can(services.PermPricingManage), h.AdminDeleteCancellationPolicy)
+	admin.GET("/promo-codes", can(services.PermPricingManage), h.AdminListPromoCodes)
+	admin.POST("/promo-codes", can(services.PermPricingManage), h.AdminCreatePromoCode)
+	admin.PUT("/promo-codes/:id", can(services.PermPricingManage), h.AdminUpdatePromoCode)
+	admin.DELETE("/promo-codes/:id", can(services.PermPricingManage), h.AdminDeletePromoCode)
+	admin.GET("/reservations", can(services.PermReservationsRead), h.AdminListReservations)
+	admin.GET("/invoices", can(services.PermReservationsRead), h.AdminListInvoices)
+	admin.GET("/invoices/:id", can(services.PermReservationsRead), h.AdminGetInvoice)
+	admin.PATCH("/reservations/:id/status", can(services.PermReservationsApprove), h.AdminUpdateReservationStatus)
+	admin.GET("/dashboard", can(services.PermDashboardRead), h.AdminDashboard)
+	admin.GET("/audit-logs", can(services.PermAuditRead), h.AdminAuditLogs)
+	admin.GET("/users", can(services.PermUsersRead), h.AdminListUsers)
+	admin.GET("/users/:id", can(services.PermUsersRead), h.AdminGetUser)
+	admin.PATCH("/users/:id/role", can(services.PermRolesManage), h.AdminUpdateUserRole)
+	admin.PATCH("/users/:id/status", can(services.PermUsersWrite), h.AdminUpdateUserStatus)
+	admin.POST("/users/:id/password-reset", can(services.PermUsersWrite), h.AdminForcePasswordReset)
+	admin.POST("/users/:id/unlock", can(services.PermUsersWrite), h.AdminUnlockUser)
+	admin.DELETE("/users/:id", can(services.PermUsersWrite), h.AdminDeleteUser)
+	admin.GET("/users/:id/reservations", can(services.PermUsersRead), h.AdminUserReservations)
+	admin.GET("/users/:id/reviews", can(services.PermUsersRead), h.AdminUserReviews)
+	admin.GET("/permissions", can(services.PermRolesManage), h.AdminListPermissions)
+	admin.PUT("/roles/:role/permissions", can(services.PermRolesManage), h.AdminSetRolePermissions)
+	go expirePaymentHolds(paymentService, time.Minute)
 	log.Fatal(r.Run(":" + env("PORT", "8080")))
 }
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"database/sql"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// seedDev fills an empty development database with demo users, cars and
+// extras priced in currency. It never runs outside APP_ENV=development and
+// never touches existing accounts.
+func seedDev(db *sql.DB, currency string) error {
+	var c int
+	if err := db.QueryRow("SELECT COUNT(*) FROM users").Scan(&c); err != nil {
+		return err
+	}
+	if c > 0 {
+		return nil
+	}
+
+	seedUsers := []struct{ U, P, R string }{{"admin", "admin", "admin"}, {"user1", "admin", "user"}, {"user2", "admin", "user"}}
+	for _, u := range seedUsers {
+		h, _ := bcrypt.GenerateFromPassword([]byte(u.P), bcrypt.DefaultCost)
+		_, _ = db.Exec(`INSERT INTO users(id, username, password_hash, role) VALUES(lower(hex(randomblob(16))),?,?,?)`, u.U, string(h), u.R)
+	}
+	extra := []string{"Child seat|600", "Additional driver|1200", "Insurance|1500"}
+	for _, e := range extra {
+		p := strings.Split(e, "|")
+		_, _ = db.Exec(`INSERT INTO extras(id,name,price_per_day_minor,currency) VALUES(lower(hex(randomblob(16))),?,?,?)`, p[0], p[1], currency)
+	}
+	_, _ = db.Exec(`INSERT INTO cars(id,brand,model,year,category,transmission,fuel,seats,daily_price_minor,currency,status,mileage,description,images) VALUES
+	(lower(hex(randomblob(16))),'Toyota','Corolla',2022,'sedan','automatic','gasoline',5,5500,?,'available',32000,'Reliable city sedan','["https://i.gaw.to/vehicles/photos/40/27/402780-2022-toyota-corolla.jpg?1024x640","https://di-uploads-pod16.dealerinspire.com/toyotaofnorthcharlotte/uploads/2022/07/N-Charlotte-Toyota-sedan.png"]'),
+	(lower(hex(randomblob(16))),'BMW','X5',2023,'suv','automatic','diesel',5,12000,?,'available',12000,'Premium SUV','["https://media.autoexpress.co.uk/image/private/s--X-WVjvBW--/f_auto,t_content-image-full-desktop@1/v1675682840/autoexpress/2023/02/BMW%20X5%20facelift%202023-9.jpg","https://hips.hearstapps.com/hmg-prod/images/2023-bmw-x5-interior-1660571768.jpg"]')`, currency, currency)
+	return nil
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// PurposeQuote marks a signed price quote. Quotes share the JWT secret, so
+// the purpose keeps them from ever being accepted as access tokens.
+const PurposeQuote = "quote"
+
+// QuoteClaims pin a quoted price to the exact booking it was calculated for.
+// Price is the breakdown as the service encoded it.
+type QuoteClaims struct {
+	UserID   string          `json:"userId"`
+	Purpose  string          `json:"purpose"`
+	CarID    string          `json:"carId"`
+	Pickup   string          `json:"pickup"`
+	Start    int64           `json:"start"`
+	End      int64           `json:"end"`
+	ExtraIDs []string        `json:"extras,omitempty"`
+	Promo    string          `json:"promo,omitempty"`
+	Price    json.RawMessage `json:"price"`
+	jwt.RegisteredClaims
+}
+
+func GenerateQuoteToken(secret string, q QuoteClaims, ttl time.Duration) (string, time.Time, error) {
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+	q.Purpose = PurposeQuote
+	q.RegisteredClaims = jwt.RegisteredClaims{
+		ID:        uuid.NewString(),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		IssuedAt:  jwt.NewNumericDate(now),
+	}
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, q).SignedString([]byte(secret))
+	return signed, expiresAt, err
+}
+
+func ParseQuoteToken(secret, tokenStr string) (*QuoteClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &QuoteClaims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(secret), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return nil, err
+	}
+	claims, ok := token.Claims.(*QuoteClaims)
+	if !ok || !token.Valid || claims.Purpose != PurposeQuote {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B vectors for the SHA1 secret "12345678901234567890",
+// truncated to 6 digits.
+func TestTOTPCodeMatchesRFC6238(t *testing.T) {
+	secret := totpEncoding.EncodeToString([]byte("12345678901234567890"))
+	cases := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+	for _, tc := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(tc.unix, 0)))
+		if err != nil {
+			t.Fatalf("TOTPCode(%d): %v", tc.unix, err)
+		}
+		if got != tc.want {
+			t.Fatalf("TOTPCode(%d) = %s, want %s", tc.unix, got, tc.want)
+		}
+	}
+}
+
+func TestVerifyTOTPAllowsOneStepOfSkew(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	if err != nil {
+		t.Fatalf("secret: %v", err)
+	}
+	now := time.Unix(1700000000, 0)
+	prev, _ := TOTPCode(secret, TOTPStep(now)-1)
+	if step, ok := VerifyTOTP(secret, prev, now); !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("expected previous step to verify, got step=%d ok=%t", step, ok)
+	}
+	old, _ := TOTPCode(secret, TOTPStep(now)-3)
+	if _, ok := VerifyTOTP(secret, old, now); ok {
+		t.Fatalf("expected code three steps old to be rejected")
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/services"
+)
+
+func parseOptionalDate(v string) (*time.Time, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return nil, false
+	}
+	t = t.UTC()
+	return &t, true
+}
+
+func (h *Handler) Me(c *gin.Context) {
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"user": u, "permissions": h.rolePermissions(u.Role)})
+}
+
+func (h *Handler) UpdateMe(c *gin.Context) {
+	var req struct {
+		FullName      string `json:"fullName"`
+		Email         string `json:"email"`
+		Phone         string `json:"phone"`
+		DateOfBirth   string `json:"dateOfBirth"`
+		LicenseNumber string `json:"licenseNumber"`
+		LicenseExpiry string `json:"licenseExpiry"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	dob, ok1 := parseOptionalDate(req.DateOfBirth)
+	expiry, ok2 := parseOptionalDate(req.LicenseExpiry)
+	if !ok1 || !ok2 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dates"})
+		return
+	}
+	u, err := h.UserService.UpdateProfile(c.GetString("userId"), services.ProfileInput{
+		FullName:      req.FullName,
+		Email:         req.Email,
+		Phone:         req.Phone,
+		DateOfBirth:   dob,
+		LicenseNumber: req.LicenseNumber,
+		LicenseExpiry: expiry,
+	})
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	h.addAudit(c, "update_profile", "user", u.ID, "")
+	c.JSON(http.StatusOK, gin.H{"user": u})
+}
+
+func (h *Handler) ChangePassword(c *gin.Context) {
+	var req struct {
+		CurrentPassword string `json:"currentPassword"`
+		NewPassword     string `json:"newPassword"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if err := h.UserService.ChangePassword(c.GetString("userId"), c.GetString("sessionId"), req.CurrentPassword, req.NewPassword); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	h.addAudit(c, "change_password", "user", c.GetString("userId"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "password changed"})
+}
+
+func (h *Handler) DeleteMe(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if err := h.UserService.DeleteAccount(c.GetString("userId"), req.Password); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	h.addAudit(c, "delete_account", "user", c.GetString("userId"), "anonymised")
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) ForgotPassword(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if err := h.PasswordResets.RequestReset(req.Email); err != nil {
+		log.Printf("password reset request failed: %v", err)
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": "if the address belongs to an account, a reset link has been sent"})
+}
+
+func (h *Handler) ResetPassword(c *gin.Context) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if err := h.PasswordResets.Reset(req.Token, req.Password); err != nil {
+		if errors.Is(err, services.ErrInvalidResetToken) || errors.Is(err, services.ErrWeakPassword) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "password has been reset"})
+}
@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) AdminListPermissions(c *gin.Context) {
+	roles, err := h.Permissions.Assignments()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"permissions": services.Permissions, "roles": roles})
+}
+
+func (h *Handler) AdminSetRolePermissions(c *gin.Context) {
+	var req struct {
+		Permissions []string `json:"permissions"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	role := c.Param("role")
+	perms, err := h.Permissions.SetRolePermissions(role, req.Permissions)
+	switch {
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrInvalidPermission):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrImmutableRole):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "role_permissions", "role", role, strings.Join(perms, ","))
+	c.JSON(http.StatusOK, gin.H{"role": role, "permissions": perms})
+}
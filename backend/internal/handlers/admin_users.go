@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) userServiceError(c *gin.Context, err error) {
+	switch {
+	case services.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrWeakPassword), errors.Is(err, services.ErrWrongPassword), errors.As(err, new(*services.ValidationError)):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrLastAdmin), errors.Is(err, services.ErrSelfAction), errors.Is(err, services.ErrActiveRental), errors.Is(err, services.ErrEmailExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) AdminListUsers(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	filters := map[string]string{}
+	for _, k := range []string{"q", "role", "status"} {
+		filters[k] = c.Query(k)
+	}
+	items, total, err := h.UserService.Users.List(filters, limit, (page-1)*limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "total": total, "page": page, "limit": limit})
+}
+
+func (h *Handler) AdminGetUser(c *gin.Context) {
+	u, err := h.UserService.Users.FindByID(c.Param("id"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, u)
+}
+
+func (h *Handler) AdminUpdateUserRole(c *gin.Context) {
+	var req struct {
+		Role string `json:"role"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.ChangeRole(c.GetString("userId"), c.Param("id"), req.Role)
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	h.addAudit(c, "role_change", "user", u.ID, fmt.Sprintf("%s -> %s", u.Username, req.Role))
+	c.JSON(http.StatusOK, u)
+}
+
+func (h *Handler) AdminUpdateUserStatus(c *gin.Context) {
+	var req struct {
+		Disabled bool `json:"disabled"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.SetDisabled(c.GetString("userId"), c.Param("id"), req.Disabled)
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	action := "enable"
+	if req.Disabled {
+		action = "disable"
+	}
+	h.addAudit(c, action, "user", u.ID, u.Username)
+	c.JSON(http.StatusOK, u)
+}
+
+func (h *Handler) AdminForcePasswordReset(c *gin.Context) {
+	if err := h.UserService.RequirePasswordReset(c.Param("id")); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	emailed := false
+	if u, err := h.UserService.Users.FindByID(c.Param("id")); err == nil && u.Email != "" && h.PasswordResets != nil {
+		emailed = h.PasswordResets.SendResetLink(u) == nil
+	}
+	h.addAudit(c, "force_password_reset", "user", c.Param("id"), fmt.Sprintf("emailed=%t", emailed))
+	c.JSON(http.StatusOK, gin.H{"message": "password reset required", "emailed": emailed})
+}
+
+func (h *Handler) AdminUnlockUser(c *gin.Context) {
+	var req struct {
+		IP string `json:"ip"`
+	}
+	if c.Request.ContentLength > 0 && !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.Users.FindByID(c.Param("id"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	if h.Auth.Throttle != nil {
+		if err := h.Auth.Throttle.UnlockUser(u.Username); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if req.IP != "" {
+			if err := h.Auth.Throttle.UnlockIP(req.IP); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+				return
+			}
+		}
+	}
+	details := u.Username
+	if req.IP != "" {
+		details += " ip=" + req.IP
+	}
+	h.addAudit(c, "unlock", "user", u.ID, details)
+	c.JSON(http.StatusOK, gin.H{"message": "unlocked"})
+}
+
+func (h *Handler) AdminDeleteUser(c *gin.Context) {
+	if err := h.UserService.Delete(c.GetString("userId"), c.Param("id")); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	h.addAudit(c, "delete", "user", c.Param("id"), "")
+	c.Status(http.StatusNoContent)
+}
+
+func (h *Handler) AdminUserReservations(c *gin.Context) {
+	if _, err := h.UserService.Users.FindByID(c.Param("id")); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	items, err := h.Reservations.List(c.Param("id"), false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	for i := range items {
+		extras, _ := h.Reservations.ExtrasForReservation(items[i].ID)
+		items[i].Extras = extras
+		items[i].LineItems, _ = h.Reservations.LineItems(items[i].ID)
+		items[i].Payment = h.latestPayment(items[i].ID, services.PaymentRental)
+		items[i].Deposit = h.latestPayment(items[i].ID, services.PaymentDeposit)
+	}
+	c.JSON(http.StatusOK, items)
+}
+
+func (h *Handler) AdminUserReviews(c *gin.Context) {
+	if _, err := h.UserService.Users.FindByID(c.Param("id")); err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	items, err := h.Reviews.ListByUser(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, items)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) ListBranches(c *gin.Context) {
+	items, err := h.Branches.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) AdminCreateBranch(c *gin.Context) {
+	var b models.Branch
+	if !bindAndValidate(c, &b) {
+		return
+	}
+	if err := services.ValidateBranch(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Branches.Create(&b); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "branch name already exists"})
+		return
+	}
+	h.addAudit(c, "create", "branch", b.ID, b.Name+" "+b.Timezone)
+	c.JSON(http.StatusCreated, b)
+}
+
+// AdminUpdateBranch only affects new bookings: existing reservations keep the
+// zone they were made in.
+func (h *Handler) AdminUpdateBranch(c *gin.Context) {
+	var b models.Branch
+	if !bindAndValidate(c, &b) {
+		return
+	}
+	if err := services.ValidateBranch(&b); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	ok, err := h.Branches.Update(c.Param("id"), &b)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "branch name already exists"})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	b.ID = c.Param("id")
+	h.addAudit(c, "update", "branch", b.ID, b.Name+" "+b.Timezone)
+	c.JSON(http.StatusOK, b)
+}
@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+// CancellationQuote previews what cancelling the reservation would cost.
+func (h *Handler) CancellationQuote(c *gin.Context) {
+	q, err := h.ReservationService.QuoteCancellation(c.Param("id"), actor(c, false))
+	if err != nil {
+		h.reservationStatusError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, q)
+}
+
+func (h *Handler) AdminListCancellationPolicies(c *gin.Context) {
+	items, err := h.CancellationPolicies.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func bindCancellationPolicy(c *gin.Context) (*models.CancellationPolicy, bool) {
+	var p models.CancellationPolicy
+	if !bindAndValidate(c, &p) {
+		return nil, false
+	}
+	if err := services.ValidateCancellationPolicy(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &p, true
+}
+
+func (h *Handler) AdminCreateCancellationPolicy(c *gin.Context) {
+	p, ok := bindCancellationPolicy(c)
+	if !ok {
+		return
+	}
+	if err := h.CancellationPolicies.Create(p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "create", "cancellation_policy", p.ID, p.Name)
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *Handler) AdminUpdateCancellationPolicy(c *gin.Context) {
+	p, ok := bindCancellationPolicy(c)
+	if !ok {
+		return
+	}
+	found, err := h.CancellationPolicies.Update(c.Param("id"), p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "update", "cancellation_policy", p.ID, p.Name)
+	c.JSON(http.StatusOK, p)
+}
+
+func (h *Handler) AdminDeleteCancellationPolicy(c *gin.Context) {
+	found, err := h.CancellationPolicies.Delete(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "delete", "cancellation_policy", c.Param("id"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+	"rentacar/backend/internal/services"
+)
+
+func TestListCarsFiltersByDateRange(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newTestDB(t)
+	freeID := insertTestCar(t, db)
+	bookedID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	if _, err := db.Exec(`INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price_minor)
+	VALUES(?,?,?,?,?,?,?,?,?,?)`, uuid.NewString(), bookedID, userID,
+		time.Date(2026, 7, 2, 0, 0, 0, 0, time.UTC), time.Date(2026, 7, 5, 0, 0, 0, 0, time.UTC), "A", "B", "", "approved", 24000); err != nil {
+		t.Fatalf("insert reservation: %v", err)
+	}
+
+	cars := &repositories.CarRepository{DB: db}
+	h := &Handler{
+		Cars:               cars,
+		ReservationService: &services.ReservationService{Cars: cars, Reservations: &repositories.ReservationRepository{DB: db}},
+	}
+	router := gin.New()
+	router.GET("/cars", h.ListCars)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cars?from=2026-07-01&to=2026-07-04", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d (%s)", rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Items []models.Car `json:"items"`
+		Total int          `json:"total"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp.Total != 1 || len(resp.Items) != 1 || resp.Items[0].ID != freeID {
+		t.Fatalf("expected only the free car, got %+v", resp)
+	}
+	if got := resp.Items[0].TotalPrice; got == nil || *got != 240_00 || resp.Items[0].RentalDays != 3 {
+		t.Fatalf("expected 3 days at 80 = 240, got days=%d price=%v", resp.Items[0].RentalDays, got)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/cars?from=2026-07-05&to=2026-07-04", nil))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for reversed range, got %d", rr.Code)
+	}
+}
+
+func TestUpdateCarRefusesToRelabelItsCurrency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	cars := &repositories.CarRepository{DB: db}
+	svc := &services.ReservationService{Cars: cars, Currency: "EUR"}
+	h := &Handler{Cars: cars, ReservationService: svc}
+	router := gin.New()
+	router.PUT("/cars/:id", h.UpdateCar)
+	update := func() int {
+		body := `{"brand":"Toyota","model":"Corolla","year":2022,"category":"sedan","transmission":"automatic","fuel":"gasoline","seats":5,"dailyPrice":90,"status":"available"}`
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPut, "/cars/"+carID, strings.NewReader(body)))
+		return rr.Code
+	}
+
+	if code := update(); code != http.StatusConflict {
+		t.Fatalf("a USD price must not become EUR, got %d", code)
+	}
+	if car, _ := cars.GetByID(carID); car.Currency != "USD" || car.DailyPrice == 90_00 {
+		t.Fatalf("the car should be unchanged, got %s %v", car.Currency, car.DailyPrice)
+	}
+	svc.Currency = "USD"
+	if code := update(); code != http.StatusOK {
+		t.Fatalf("expected 200 in the car's own currency, got %d", code)
+	}
+}
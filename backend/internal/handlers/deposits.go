@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+// AdminListDeposits lists the per-category deposits and the amount used for
+// categories without one.
+func (h *Handler) AdminListDeposits(c *gin.Context) {
+	items, err := h.Deposits.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "default": h.ReservationService.Deposit, "currency": h.ReservationService.BaseCurrency()})
+}
+
+func (h *Handler) AdminSetDeposit(c *gin.Context) {
+	var d models.CategoryDeposit
+	if !bindAndValidate(c, &d) {
+		return
+	}
+	d.Category = c.Param("category")
+	if err := services.ValidateCategoryDeposit(&d, h.ReservationService.BaseCurrency()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.Deposits.Set(&d); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "update", "deposit", d.Category, d.Amount.String()+" "+d.Currency)
+	c.JSON(http.StatusOK, d)
+}
+
+func (h *Handler) AdminDeleteDeposit(c *gin.Context) {
+	category := strings.ToLower(c.Param("category"))
+	found, err := h.Deposits.Delete(category)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "delete", "deposit", category, "")
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+// ListExchangeRates lists the display rates from the base currency. Prices
+// are always charged in their own currency.
+func (h *Handler) ListExchangeRates(c *gin.Context) {
+	base := h.ReservationService.BaseCurrency()
+	items, err := h.ExchangeRates.List(base)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"base": base, "items": items})
+}
+
+func (h *Handler) AdminSetExchangeRate(c *gin.Context) {
+	var x models.ExchangeRate
+	if !bindAndValidate(c, &x) {
+		return
+	}
+	x.Currency = c.Param("currency")
+	if err := services.ValidateExchangeRate(&x, h.ReservationService.BaseCurrency()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.ExchangeRates.Set(&x); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "update", "exchange_rate", x.Base+"/"+x.Currency, fmt.Sprintf("%g", x.Rate))
+	c.JSON(http.StatusOK, x)
+}
+
+func (h *Handler) AdminDeleteExchangeRate(c *gin.Context) {
+	base, code := h.ReservationService.BaseCurrency(), strings.ToUpper(c.Param("currency"))
+	found, err := h.ExchangeRates.Delete(base, code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "delete", "exchange_rate", base+"/"+code, "")
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -1,10 +1,10 @@
 package handlers
 
 import (
-	"database/sql"
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
 	"os"
 	"path/filepath"
@@ -19,13 +19,24 @@ import (
 )
 
 type Handler struct {
-	Auth               *services.AuthService
-	Cars               *repositories.CarRepository
-	Reservations       *repositories.ReservationRepository
-	Extras             *repositories.ExtraRepository
-	Reviews            *repositories.ReviewRepository
-	Audit              *repositories.AuditLogRepository
-	ReservationService *services.ReservationService
+	Auth                 *services.AuthService
+	Cars                 *repositories.CarRepository
+	Reservations         *repositories.ReservationRepository
+	Extras               *repositories.ExtraRepository
+	Branches             *repositories.BranchRepository
+	PricingRules         *repositories.PricingRuleRepository
+	Payments             *repositories.PaymentRepository
+	Deposits             *repositories.DepositRepository
+	CancellationPolicies *repositories.CancellationPolicyRepository
+	PromoCodes           *repositories.PromoCodeRepository
+	ExchangeRates        *repositories.ExchangeRateRepository
+	Reviews              *repositories.ReviewRepository
+	Audit                *repositories.AuditLogRepository
+	ReservationService   *services.ReservationService
+	UserService          *services.UserService
+	InvoiceService       *services.InvoiceService
+	PasswordResets       *services.PasswordResetService
+	Permissions          *services.PermissionService
 }
 
 func bindAndValidate(c *gin.Context, req interface{}) bool {
@@ -36,26 +47,6 @@ func bindAndValidate(c *gin.Context, req interface{}) bool {
 	return true
 }
 
-func (h *Handler) syncCarAvailability(carID string) {
-	blocking, err := h.Reservations.HasBlockingForCar(carID)
-	if err != nil {
-		return
-	}
-	car, err := h.Cars.GetByID(carID)
-	if err != nil {
-		return
-	}
-	if blocking {
-		if car.Status != "rented" {
-			_ = h.Cars.UpdateStatus(carID, "rented")
-		}
-		return
-	}
-	if car.Status == "rented" {
-		_ = h.Cars.UpdateStatus(carID, "available")
-	}
-}
-
 func (h *Handler) addAudit(c *gin.Context, action, entity, entityID, details string) {
 	if h.Audit == nil {
 		return
@@ -87,20 +78,99 @@ func (h *Handler) Register(c *gin.Context) {
 	}
 	c.JSON(http.StatusCreated, gin.H{"message": "registered"})
 }
+func clientInfo(c *gin.Context) services.ClientInfo {
+	return services.ClientInfo{IP: c.ClientIP(), UserAgent: c.Request.UserAgent()}
+}
+
+func (h *Handler) tokenResponse(t *services.TokenPair, u *models.User) gin.H {
+	return gin.H{"token": t.AccessToken, "refreshToken": t.RefreshToken, "expiresAt": t.ExpiresAt, "user": u, "permissions": h.rolePermissions(u.Role)}
+}
+
+// rolePermissions lets the frontend decide which staff pages to show. The
+// server still checks every request with RequirePermission.
+func (h *Handler) rolePermissions(role string) []string {
+	if h.Permissions == nil {
+		return []string{}
+	}
+	perms, err := h.Permissions.ForRole(role)
+	if err != nil {
+		return []string{}
+	}
+	return perms
+}
+
+func writeThrottled(c *gin.Context, err error) bool {
+	var throttled *services.ThrottledError
+	if !errors.As(err, &throttled) {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+	c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	return true
+}
+
 func (h *Handler) Login(c *gin.Context) {
 	var req struct{ Username, Password string }
 	if !bindAndValidate(c, &req) {
 		return
 	}
-	t, u, err := h.Auth.Login(req.Username, req.Password)
+	t, u, err := h.Auth.Login(req.Username, req.Password, clientInfo(c))
+	if writeThrottled(c, err) {
+		return
+	}
+	var mfa *services.MFARequiredError
+	if errors.As(err, &mfa) {
+		c.JSON(http.StatusOK, gin.H{"mfaRequired": true, "enrollmentRequired": mfa.EnrollmentRequired, "preAuthToken": mfa.PreAuthToken, "expiresAt": mfa.ExpiresAt})
+		return
+	}
+	if errors.Is(err, services.ErrAccountDisabled) || errors.Is(err, services.ErrPasswordResetNeeded) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"token": t, "user": u})
+	c.JSON(http.StatusOK, h.tokenResponse(t, u))
+}
+func (h *Handler) Refresh(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	t, u, err := h.Auth.Refresh(req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, h.tokenResponse(t, u))
+}
+func (h *Handler) Logout(c *gin.Context) {
+	var req struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	if err := h.Auth.Logout(req.RefreshToken); err != nil && !errors.Is(err, services.ErrInvalidRefreshToken) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "logged out"})
 }
-func (h *Handler) Me(c *gin.Context) {
-	c.JSON(http.StatusOK, gin.H{"user": gin.H{"id": c.GetString("userId"), "username": c.GetString("username"), "role": c.GetString("role")}})
+func (h *Handler) LogoutAll(c *gin.Context) {
+	if err := h.Auth.LogoutAll(c.GetString("userId")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "logout_all", "user", c.GetString("userId"), "revoked all sessions")
+	c.JSON(http.StatusOK, gin.H{"message": "logged out from all devices"})
 }
 
 func (h *Handler) ListCars(c *gin.Context) {
@@ -113,23 +183,91 @@ func (h *Handler) ListCars(c *gin.Context) {
 		limit = 10
 	}
 	filters := map[string]string{}
-	for _, k := range []string{"q", "brand", "model", "category", "transmission", "fuel", "status", "minPrice", "maxPrice", "minYear", "maxYear", "minMileage", "maxMileage", "seats"} {
+	for _, k := range []string{"q", "brand", "model", "category", "transmission", "fuel", "status", "minPrice", "maxPrice", "minYear", "maxYear", "minMileage", "maxMileage", "seats", "from", "to"} {
 		filters[k] = c.Query(k)
 	}
+	from, to, err := h.occupancyRange(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	policy := h.ReservationService.Policy
+	if hasDateRange(c) {
+		lo, hi := policy.BlockingWindow(from, to)
+		filters["from"], filters["to"] = lo.UTC().Format(time.RFC3339), hi.UTC().Format(time.RFC3339)
+	}
 	cars, total, err := h.Cars.List(filters, limit, (page-1)*limit, c.DefaultQuery("sort", "newest"))
 	if err != nil {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	if err := h.ReservationService.AttachOccupancy(cars, from, to); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	if hasDateRange(c) {
+		rules, err := h.ReservationService.ActivePricingRules()
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		var branch *models.Branch
+		if loc := c.Query("location"); loc != "" {
+			if branch, _, err = h.ReservationService.Branch(loc); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		for i := range cars {
+			price := h.ReservationService.PriceWith(rules, services.PricingInput{Car: &cars[i], Start: from, End: to, BookedAt: time.Now(), Branch: branch})
+			cars[i].RentalDays, cars[i].RentalHours = price.Days, price.Hours
+			cars[i].TotalPrice = &price.Total
+		}
+	}
 	c.JSON(200, gin.H{"items": cars, "total": total, "page": page, "limit": limit})
 }
+
+func hasDateRange(c *gin.Context) bool { return c.Query("from") != "" && c.Query("to") != "" }
+
+var errInvalidDates = errors.New("invalid dates")
+
+// occupancyRange reads optional from/to times (RFC 3339, or wall-clock times
+// in the zone of the optional location branch) and falls back to the
+// current day there when either is missing.
+func (h *Handler) occupancyRange(c *gin.Context) (time.Time, time.Time, error) {
+	loc, err := h.ReservationService.Zone(c.Query("location"))
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	from, to := services.TodayRange(time.Now(), loc)
+	if !hasDateRange(c) {
+		return from, to, nil
+	}
+	f, err1 := services.ParseRentalTime(c.Query("from"), loc)
+	t, err2 := services.ParseRentalTime(c.Query("to"), loc)
+	if err1 != nil || err2 != nil || !t.After(f) {
+		return from, to, errInvalidDates
+	}
+	return f, t, nil
+}
+
 func (h *Handler) GetCar(c *gin.Context) {
 	car, err := h.Cars.GetByID(c.Param("id"))
 	if err != nil {
 		c.JSON(404, gin.H{"error": "not found"})
 		return
 	}
-	c.JSON(200, car)
+	from, to, err := h.occupancyRange(c)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	cars := []models.Car{*car}
+	if err := h.ReservationService.AttachOccupancy(cars, from, to); err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(200, cars[0])
 }
 
 func (h *Handler) CarAvailability(c *gin.Context) {
@@ -249,9 +387,18 @@ func validateCarInput(car *models.Car) string {
 	if car.Mileage < 0 {
 		return "mileage must be >= 0"
 	}
-	allowedStatus := map[string]bool{"available": true, "rented": true, "maintenance": true}
-	if !allowedStatus[car.Status] {
-		return "status is invalid"
+	if !services.IsValidCarStatus(car.Status) {
+		return "status must be available, maintenance or retired"
+	}
+	prev := 0
+	for _, tier := range car.HourlyRates {
+		if tier.MaxHours <= prev || tier.MaxHours > 23 {
+			return "hourlyRates must have ascending maxHours between 1 and 23"
+		}
+		if tier.PricePerHour <= 0 {
+			return "hourlyRates pricePerHour must be greater than 0"
+		}
+		prev = tier.MaxHours
 	}
 	return ""
 }
@@ -333,6 +480,7 @@ func (h *Handler) CreateCar(c *gin.Context) {
 		return
 	}
 	normalizeCarEnumFields(&car)
+	car.Currency = h.ReservationService.BaseCurrency()
 	if car.Status == "" {
 		car.Status = "available"
 	}
@@ -359,10 +507,20 @@ func (h *Handler) UpdateCar(c *gin.Context) {
 		return
 	}
 	normalizeCarEnumFields(&car)
+	car.Currency = h.ReservationService.BaseCurrency()
 	if msg := validateCarInput(&car); msg != "" {
 		c.JSON(400, gin.H{"error": msg})
 		return
 	}
+	existing, err := h.Cars.GetByID(c.Param("id"))
+	if err != nil {
+		c.JSON(404, gin.H{"error": "not found"})
+		return
+	}
+	if existing.Currency != car.Currency {
+		c.JSON(409, gin.H{"error": services.ErrCurrencyChange.Error()})
+		return
+	}
 	normalized, err := h.normalizeImageRefs(c, car.Images)
 	if err != nil {
 		c.JSON(400, gin.H{"error": err.Error()})
@@ -394,30 +552,69 @@ func (h *Handler) ListExtras(c *gin.Context) {
 	c.JSON(200, extras)
 }
 
-func (h *Handler) CreateReservation(c *gin.Context) {
-	var req struct {
-		CarID, PickupLocation, DropoffLocation, Notes string
-		StartDate, EndDate                            string
-		ExtraIDs                                      []string `json:"extraIds"`
-	}
+type reservationRequest struct {
+	CarID, PickupLocation, DropoffLocation, Notes string
+	StartDate, EndDate                            string
+	ExtraIDs                                      []string `json:"extraIds"`
+	QuoteID                                       string   `json:"quoteId"`
+	PaymentMethod                                 string   `json:"paymentMethod"`
+	PromoCode                                     string   `json:"promoCode"`
+}
+
+// bindReservation reads a booking request and resolves its times in the
+// pickup branch's zone. It writes the error response itself.
+func (h *Handler) bindReservation(c *gin.Context) (*models.Reservation, []string, bool) {
+	var req reservationRequest
 	if !bindAndValidate(c, &req) {
+		return nil, nil, false
+	}
+	res := &models.Reservation{CarID: req.CarID, UserID: c.GetString("userId"), PickupLocation: req.PickupLocation, DropoffLocation: req.DropoffLocation, Notes: req.Notes, QuoteID: req.QuoteID, PaymentMethod: req.PaymentMethod, PromoCode: req.PromoCode}
+	if err := h.ReservationService.ResolveBooking(res, req.StartDate, req.EndDate, time.Now()); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+	return res, req.ExtraIDs, true
+}
+
+func bookingError(c *gin.Context, err error) {
+	if errors.Is(err, services.ErrCarUnavailable) || errors.Is(err, services.ErrCarNotBookable) || errors.Is(err, services.ErrCurrencyMismatch) || errors.Is(err, services.ErrPromoCodeUsedUp) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
 		return
 	}
-	start, err1 := time.Parse("2006-01-02", req.StartDate)
-	end, err2 := time.Parse("2006-01-02", req.EndDate)
-	if err1 != nil || err2 != nil {
-		c.JSON(400, gin.H{"error": "invalid dates"})
+	c.JSON(400, gin.H{"error": err.Error()})
+}
+
+func (h *Handler) CreateReservation(c *gin.Context) {
+	res, extraIDs, ok := h.bindReservation(c)
+	if !ok {
 		return
 	}
-	res := &models.Reservation{CarID: req.CarID, UserID: c.GetString("userId"), StartDate: start.UTC(), EndDate: end.UTC(), PickupLocation: req.PickupLocation, DropoffLocation: req.DropoffLocation, Notes: req.Notes}
-	if err := h.ReservationService.Create(res, req.ExtraIDs); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if err := h.ReservationService.Create(res, extraIDs); err != nil {
+		if errors.Is(err, services.ErrPaymentDeclined) {
+			c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "reservation": res})
+			return
+		}
+		bookingError(c, err)
 		return
 	}
-	h.syncCarAvailability(req.CarID)
-	h.addAudit(c, "create", "reservation", res.ID, req.CarID)
+	res.CanCancel = services.CanCancel(res)
+	h.addAudit(c, "create", "reservation", res.ID, res.CarID)
 	c.JSON(201, res)
 }
+
+// CreateQuote prices a booking without making it.
+func (h *Handler) CreateQuote(c *gin.Context) {
+	res, extraIDs, ok := h.bindReservation(c)
+	if !ok {
+		return
+	}
+	quote, err := h.ReservationService.Quote(res, extraIDs, time.Now())
+	if err != nil {
+		bookingError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
 func (h *Handler) ListMyReservations(c *gin.Context) {
 	items, err := h.Reservations.List(c.GetString("userId"), false)
 	if err != nil {
@@ -427,23 +624,41 @@ func (h *Handler) ListMyReservations(c *gin.Context) {
 	for i := range items {
 		extras, _ := h.Reservations.ExtrasForReservation(items[i].ID)
 		items[i].Extras = extras
+		items[i].LineItems, _ = h.Reservations.LineItems(items[i].ID)
+		items[i].Payment = h.latestPayment(items[i].ID, services.PaymentRental)
+		items[i].Deposit = h.latestPayment(items[i].ID, services.PaymentDeposit)
+		items[i].CanCancel = services.CanCancel(&items[i])
 	}
 	c.JSON(200, items)
 }
-func (h *Handler) CancelReservation(c *gin.Context) {
-	re, err := h.Reservations.GetByID(c.Param("id"))
-	if err != nil || re.UserID != c.GetString("userId") {
-		c.JSON(404, gin.H{"error": "not found"})
-		return
+func actor(c *gin.Context, staff bool) services.Actor {
+	return services.Actor{UserID: c.GetString("userId"), Username: c.GetString("username"), Staff: staff}
+}
+
+func (h *Handler) reservationStatusError(c *gin.Context, err error) {
+	switch {
+	case services.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, services.ErrUnknownStatus), errors.Is(err, services.ErrInvalidDepositCharge):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTransitionForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTransition), errors.Is(err, services.ErrPaymentRequired), errors.Is(err, services.ErrNoDepositHeld), errors.Is(err, services.ErrCurrencyMismatch):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrPaymentDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 	}
-	if !services.CanCancel(re) {
-		c.JSON(400, gin.H{"error": "cannot cancel"})
+}
+
+func (h *Handler) CancelReservation(c *gin.Context) {
+	re, err := h.ReservationService.Transition(c.Param("id"), "cancelled", actor(c, false))
+	if err != nil {
+		h.reservationStatusError(c, err)
 		return
 	}
-	_ = h.Reservations.UpdateStatus(re.ID, "cancelled")
-	h.syncCarAvailability(re.CarID)
-	h.addAudit(c, "cancel", "reservation", re.ID, "user cancelled reservation")
-	c.JSON(200, gin.H{"message": "cancelled"})
+	c.JSON(200, gin.H{"message": "cancelled", "reservation": re})
 }
 
 func (h *Handler) AdminListReservations(c *gin.Context) {
@@ -455,37 +670,39 @@ func (h *Handler) AdminListReservations(c *gin.Context) {
 	for i := range items {
 		extras, _ := h.Reservations.ExtrasForReservation(items[i].ID)
 		items[i].Extras = extras
+		items[i].LineItems, _ = h.Reservations.LineItems(items[i].ID)
+		items[i].Payment = h.latestPayment(items[i].ID, services.PaymentRental)
+		items[i].Deposit = h.latestPayment(items[i].ID, services.PaymentDeposit)
 	}
 	c.JSON(200, items)
 }
 func (h *Handler) AdminUpdateReservationStatus(c *gin.Context) {
 	var req struct {
-		Status string `json:"status"`
+		Status        string        `json:"status"`
+		DepositCharge *models.Money `json:"depositCharge"`
 	}
 	if !bindAndValidate(c, &req) {
 		return
 	}
-	allowed := map[string]bool{"approved": true, "denied": true, "active": true, "completed": true}
-	if !allowed[req.Status] {
-		c.JSON(400, gin.H{"error": "invalid status"})
+	var re *models.Reservation
+	var err error
+	switch {
+	case req.Status == "completed" && req.DepositCharge != nil:
+		re, err = h.ReservationService.CompleteRental(c.Param("id"), *req.DepositCharge, actor(c, true))
+	case req.DepositCharge != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "depositCharge only applies when completing a rental"})
 		return
+	default:
+		re, err = h.ReservationService.Transition(c.Param("id"), req.Status, actor(c, true))
 	}
-	re, err := h.Reservations.GetByID(c.Param("id"))
 	if err != nil {
-		c.JSON(404, gin.H{"error": "not found"})
-		return
-	}
-	if err := h.Reservations.UpdateStatus(c.Param("id"), req.Status); err != nil {
-		if err == sql.ErrNoRows {
-			c.JSON(404, gin.H{"error": "not found"})
-		} else {
-			c.JSON(400, gin.H{"error": err.Error()})
-		}
+		h.reservationStatusError(c, err)
 		return
 	}
-	h.syncCarAvailability(re.CarID)
-	h.addAudit(c, "status_change", "reservation", re.ID, req.Status)
-	c.JSON(200, gin.H{"message": "updated"})
+	re.LineItems, _ = h.Reservations.LineItems(re.ID)
+	re.Payment = h.latestPayment(re.ID, services.PaymentRental)
+	re.Deposit = h.latestPayment(re.ID, services.PaymentDeposit)
+	c.JSON(200, gin.H{"message": "updated", "reservation": re})
 }
 func (h *Handler) AdminDashboard(c *gin.Context) {
 	m, err := h.Reservations.Metrics()
@@ -493,6 +710,17 @@ func (h *Handler) AdminDashboard(c *gin.Context) {
 		c.JSON(500, gin.H{"error": err.Error()})
 		return
 	}
+	// Money taken is totalled per currency rather than added across them.
+	revenue, err := h.Payments.Captured(services.PaymentRental)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
+	depositCharges, err := h.Payments.Captured(services.PaymentDeposit)
+	if err != nil {
+		c.JSON(500, gin.H{"error": err.Error()})
+		return
+	}
 	recent, _ := h.Reservations.List("", true)
 	if len(recent) > 10 {
 		recent = recent[:10]
@@ -501,7 +729,7 @@ func (h *Handler) AdminDashboard(c *gin.Context) {
 	if h.Audit != nil {
 		logs, _ = h.Audit.ListRecent(20)
 	}
-	c.JSON(200, gin.H{"metrics": m, "recent": recent, "auditLogs": logs})
+	c.JSON(200, gin.H{"metrics": m, "revenue": revenue, "depositCharges": depositCharges, "recent": recent, "auditLogs": logs})
 }
 
 func (h *Handler) AdminAuditLogs(c *gin.Context) {
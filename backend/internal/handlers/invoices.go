@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+// ReservationInvoice returns the invoice of one of the user's completed
+// rentals.
+func (h *Handler) ReservationInvoice(c *gin.Context) {
+	inv, err := h.InvoiceService.ForReservation(c.Param("id"), actor(c, false))
+	writeInvoice(c, inv, err)
+}
+
+func (h *Handler) AdminListInvoices(c *gin.Context) {
+	year, _ := strconv.Atoi(c.Query("year"))
+	items, err := h.InvoiceService.Invoices.List(year)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+func (h *Handler) AdminGetInvoice(c *gin.Context) {
+	inv, err := h.InvoiceService.Invoices.GetByID(c.Param("id"))
+	writeInvoice(c, inv, err)
+}
+
+// writeInvoice sends JSON, or a PDF download when asked for with
+// ?format=pdf or an Accept header for application/pdf.
+func writeInvoice(c *gin.Context, inv *models.Invoice, err error) {
+	switch {
+	case err == nil:
+	case services.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, services.ErrNotInvoiced):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if c.Query("format") == "pdf" || strings.Contains(c.GetHeader("Accept"), "application/pdf") {
+		c.Header("Content-Disposition", `attachment; filename="`+inv.Number+`.pdf"`)
+		c.Data(http.StatusOK, "application/pdf", services.RenderInvoicePDF(inv))
+		return
+	}
+	c.JSON(http.StatusOK, inv)
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+	"rentacar/backend/internal/services"
+)
+
+const maxWebhookBody = 1 << 20
+
+// latestPayment returns nil for reservations without a payment of kind.
+func (h *Handler) latestPayment(reservationID, kind string) *models.Payment {
+	if h.Payments == nil {
+		return nil
+	}
+	p, err := h.Payments.Latest(reservationID, kind)
+	if err != nil {
+		return nil
+	}
+	return p
+}
+
+// PayReservation retries payment for a reservation awaiting it.
+func (h *Handler) PayReservation(c *gin.Context) {
+	var req struct {
+		PaymentMethod string `json:"paymentMethod"`
+	}
+	if c.Request.ContentLength != 0 && !bindAndValidate(c, &req) {
+		return
+	}
+	re, err := h.ReservationService.Pay(c.Param("id"), req.PaymentMethod, actor(c, false))
+	switch {
+	case err == nil:
+	case services.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	case errors.Is(err, services.ErrNotAwaitingPayment), errors.Is(err, services.ErrPaymentInProgress), errors.Is(err, services.ErrPaymentHoldExpired):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, services.ErrPaymentDeclined):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error(), "reservation": re})
+		return
+	default:
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	re.CanCancel = services.CanCancel(re)
+	h.addAudit(c, "pay", "reservation", re.ID, re.Payment.Status)
+	c.JSON(http.StatusOK, re)
+}
+
+// PaymentWebhook receives asynchronous results from the payment gateway.
+// It is public; the gateway's signature is what authenticates it.
+func (h *Handler) PaymentWebhook(c *gin.Context) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	err = h.ReservationService.Payments.HandleWebhook(body, c.Request.Header)
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, gin.H{"received": true})
+	case errors.Is(err, payments.ErrInvalidSignature):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, payments.ErrInvalidEvent):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case services.IsNotFound(err):
+		c.JSON(http.StatusNotFound, gin.H{"error": "unknown payment"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) AdminListPricingRules(c *gin.Context) {
+	items, err := h.PricingRules.List(false)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items, "kinds": services.RuleKinds})
+}
+
+// bindPricingRule reads a rule whose amounts are in the base currency.
+func (h *Handler) bindPricingRule(c *gin.Context) (*models.PricingRule, bool) {
+	var r models.PricingRule
+	if !bindAndValidate(c, &r) {
+		return nil, false
+	}
+	r.Currency = h.ReservationService.BaseCurrency()
+	if err := services.ValidatePricingRule(&r); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return &r, true
+}
+
+func (h *Handler) AdminCreatePricingRule(c *gin.Context) {
+	r, ok := h.bindPricingRule(c)
+	if !ok {
+		return
+	}
+	if err := h.PricingRules.Create(r); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "create", "pricing_rule", r.ID, r.Kind+" "+r.Name)
+	c.JSON(http.StatusCreated, r)
+}
+
+func (h *Handler) AdminUpdatePricingRule(c *gin.Context) {
+	r, ok := h.bindPricingRule(c)
+	if !ok {
+		return
+	}
+	existing, err := h.PricingRules.GetByID(c.Param("id"))
+	if err != nil {
+		if services.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		}
+		return
+	}
+	if existing.Currency != r.Currency {
+		c.JSON(http.StatusConflict, gin.H{"error": services.ErrCurrencyChange.Error()})
+		return
+	}
+	found, err := h.PricingRules.Update(c.Param("id"), r)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	r.ID = c.Param("id")
+	h.addAudit(c, "update", "pricing_rule", r.ID, r.Kind+" "+r.Name)
+	c.JSON(http.StatusOK, r)
+}
+
+func (h *Handler) AdminDeletePricingRule(c *gin.Context) {
+	found, err := h.PricingRules.Delete(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "delete", "pricing_rule", c.Param("id"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) AdminListPromoCodes(c *gin.Context) {
+	items, err := h.PromoCodes.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"items": items})
+}
+
+// bindPromoCode reads and validates a promo code; id is the one being
+// updated, which may keep its own code.
+func (h *Handler) bindPromoCode(c *gin.Context, id string) (*models.PromoCode, bool) {
+	var p models.PromoCode
+	if !bindAndValidate(c, &p) {
+		return nil, false
+	}
+	if err := services.ValidatePromoCode(&p, h.ReservationService.BaseCurrency()); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	if existing, err := h.PromoCodes.GetByCode(p.Code); err == nil && existing.ID != id {
+		c.JSON(http.StatusConflict, gin.H{"error": "promo code already exists"})
+		return nil, false
+	}
+	return &p, true
+}
+
+func (h *Handler) AdminCreatePromoCode(c *gin.Context) {
+	p, ok := h.bindPromoCode(c, "")
+	if !ok {
+		return
+	}
+	if err := h.PromoCodes.Create(p); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	h.addAudit(c, "create", "promo_code", p.ID, p.Code)
+	c.JSON(http.StatusCreated, p)
+}
+
+func (h *Handler) AdminUpdatePromoCode(c *gin.Context) {
+	p, ok := h.bindPromoCode(c, c.Param("id"))
+	if !ok {
+		return
+	}
+	found, err := h.PromoCodes.Update(c.Param("id"), p)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	p.ID = c.Param("id")
+	h.addAudit(c, "update", "promo_code", p.ID, p.Code)
+	c.JSON(http.StatusOK, p)
+}
+
+// AdminDeletePromoCode removes a code; bookings that used it keep the code
+// and discount they were made with.
+func (h *Handler) AdminDeletePromoCode(c *gin.Context) {
+	found, err := h.PromoCodes.Delete(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+		return
+	}
+	h.addAudit(c, "delete", "promo_code", c.Param("id"), "")
+	c.JSON(http.StatusOK, gin.H{"message": "deleted"})
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -14,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
+	"rentacar/backend/internal/migrations"
 	"rentacar/backend/internal/repositories"
 )
 
@@ -39,28 +39,18 @@ func runMigrationsForTest(t *testing.T, db *sql.DB) {
 		t.Fatal("runtime.Caller failed")
 	}
 	root := filepath.Join(filepath.Dir(thisFile), "..", "..")
-	files := []string{
-		filepath.Join(root, "migrations", "001_init.sql"),
-		filepath.Join(root, "migrations", "002_audit_logs.sql"),
-		filepath.Join(root, "migrations", "003_reviews.sql"),
-	}
-	for _, f := range files {
-		sqlBytes, err := os.ReadFile(f)
-		if err != nil {
-			t.Fatalf("read migration %s: %v", f, err)
-		}
-		if _, err := db.Exec(string(sqlBytes)); err != nil {
-			t.Fatalf("exec migration %s: %v", f, err)
-		}
+	m := &migrations.Migrator{DB: db, Dir: filepath.Join(root, "migrations")}
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("apply migrations: %v", err)
 	}
 }
 
 func insertTestCar(t *testing.T, db *sql.DB) string {
 	t.Helper()
 	carID := uuid.NewString()
-	_, err := db.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price, status, mileage, description, images)
+	_, err := db.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price_minor, status, mileage, description, images)
 	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`,
-		carID, "Tesla", "Model 3", 2023, "sedan", "automatic", "electric", 5, 80.0, "available", 5000, "test car", "[]")
+		carID, "Tesla", "Model 3", 2023, "sedan", "automatic", "electric", 5, 8000, "available", 5000, "test car", "[]")
 	if err != nil {
 		t.Fatalf("insert car: %v", err)
 	}
@@ -120,4 +110,3 @@ func TestCreateCarReviewRejectsInvalidRating(t *testing.T) {
 		}
 	}
 }
-
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"rentacar/backend/internal/services"
+)
+
+func (h *Handler) twoFactorError(c *gin.Context, err error) {
+	switch {
+	case writeThrottled(c, err):
+	case errors.Is(err, services.ErrInvalidPreAuthToken):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrAccountDisabled), errors.Is(err, services.ErrTOTPRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidTOTPCode), errors.Is(err, services.ErrWrongPassword):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled), errors.Is(err, services.ErrTOTPNotEnabled), errors.Is(err, services.ErrTOTPNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
+
+func (h *Handler) LoginTOTPSetup(c *gin.Context) {
+	var req struct {
+		PreAuthToken string `json:"preAuthToken"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	enrollment, err := h.Auth.BeginLoginEnrollment(req.PreAuthToken)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) LoginTOTP(c *gin.Context) {
+	var req struct {
+		PreAuthToken string `json:"preAuthToken"`
+		Code         string `json:"code"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	t, u, recovery, err := h.Auth.CompleteLogin(req.PreAuthToken, req.Code, clientInfo(c))
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	resp := h.tokenResponse(t, u)
+	if recovery != nil {
+		if h.Audit != nil {
+			_ = h.Audit.Create(u.ID, u.Username, "totp_enabled", "user", u.ID, "enrolled during login")
+		}
+		resp["recoveryCodes"] = recovery
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *Handler) TOTPStatus(c *gin.Context) {
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	st, err := h.Auth.TOTPStatus(u)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, st)
+}
+
+func (h *Handler) SetupTOTP(c *gin.Context) {
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	enrollment, err := h.Auth.SetupTOTP(u)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, enrollment)
+}
+
+func (h *Handler) EnableTOTP(c *gin.Context) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	codes, err := h.Auth.EnableTOTP(u, req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	h.addAudit(c, "totp_enabled", "user", u.ID, "")
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
+
+func (h *Handler) DisableTOTP(c *gin.Context) {
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	if err := h.Auth.DisableTOTP(u, req.Password, req.Code); err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	h.addAudit(c, "totp_disabled", "user", u.ID, "")
+	c.JSON(http.StatusOK, gin.H{"message": "two-factor authentication disabled"})
+}
+
+func (h *Handler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req struct {
+		Code string `json:"code"`
+	}
+	if !bindAndValidate(c, &req) {
+		return
+	}
+	u, err := h.UserService.Users.FindByID(c.GetString("userId"))
+	if err != nil {
+		h.userServiceError(c, err)
+		return
+	}
+	codes, err := h.Auth.RegenerateRecoveryCodes(u, req.Code)
+	if err != nil {
+		h.twoFactorError(c, err)
+		return
+	}
+	h.addAudit(c, "totp_recovery_codes", "user", u.ID, "regenerated")
+	c.JSON(http.StatusOK, gin.H{"recoveryCodes": codes})
+}
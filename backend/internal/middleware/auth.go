@@ -1,14 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
-	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/services"
 )
 
-func AuthRequired(secret string) gin.HandlerFunc {
+func AuthRequired(authService *services.AuthService) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -16,14 +17,19 @@ func AuthRequired(secret string) gin.HandlerFunc {
 			return
 		}
 		tokenStr := strings.TrimPrefix(authHeader, "Bearer ")
-		claims, err := auth.ParseToken(secret, tokenStr)
+		claims, user, err := authService.Authenticate(tokenStr)
+		if errors.Is(err, services.ErrAccountDisabled) {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "account disabled"})
+			return
+		}
 		if err != nil {
 			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
 			return
 		}
-		c.Set("userId", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("role", claims.Role)
+		c.Set("userId", user.ID)
+		c.Set("username", user.Username)
+		c.Set("role", user.Role)
+		c.Set("sessionId", claims.SessionID)
 		c.Next()
 	}
 }
@@ -37,3 +43,20 @@ func RequireRole(role string) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission allows the request when the caller's role holds the
+// given permission.
+func RequirePermission(perms *services.PermissionService, permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ok, err := perms.Has(c.GetString("role"), permission)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+			return
+		}
+		c.Next()
+	}
+}
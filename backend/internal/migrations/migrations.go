@@ -0,0 +1,260 @@
+package migrations
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Migration files are named NNN_description.sql. An optional
+// NNN_description.down.sql next to it reverts the change.
+type Migration struct {
+	Version  int
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+type Status struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+}
+
+type Migrator struct {
+	DB  *sql.DB
+	Dir string
+}
+
+var (
+	ErrChecksumMismatch = errors.New("migration checksum mismatch")
+	ErrMissingFile      = errors.New("applied migration has no file")
+	ErrNoDownMigration  = errors.New("migration has no down file")
+)
+
+type applied struct {
+	checksum  string
+	appliedAt time.Time
+}
+
+func checksum(body string) string {
+	// Normalise line endings so a checkout with autocrlf does not look like drift.
+	sum := sha256.Sum256([]byte(strings.ReplaceAll(body, "\r\n", "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func parseName(file string) (int, string, bool, error) {
+	base := filepath.Base(file)
+	down := strings.HasSuffix(base, ".down.sql")
+	base = strings.TrimSuffix(strings.TrimSuffix(base, ".sql"), ".down")
+	num, name, ok := strings.Cut(base, "_")
+	if !ok {
+		return 0, "", false, fmt.Errorf("%s: expected NNN_name.sql", file)
+	}
+	v, err := strconv.Atoi(num)
+	if err != nil || v <= 0 {
+		return 0, "", false, fmt.Errorf("%s: invalid version %q", file, num)
+	}
+	return v, name, down, nil
+}
+
+func Load(dir string) ([]Migration, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.sql"))
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, f := range files {
+		v, name, down, err := parseName(f)
+		if err != nil {
+			return nil, err
+		}
+		b, err := os.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		m := byVersion[v]
+		if m == nil {
+			m = &Migration{Version: v, Name: name}
+			byVersion[v] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("migration %03d has conflicting names %q and %q", v, m.Name, name)
+		}
+		if down {
+			m.Down = string(b)
+			continue
+		}
+		if m.Up != "" {
+			return nil, fmt.Errorf("duplicate migration version %03d", v)
+		}
+		m.Up = string(b)
+		m.Checksum = checksum(m.Up)
+	}
+	out := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %03d_%s has a down file but no up file", m.Version, m.Name)
+		}
+		out = append(out, *m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Version < out[j].Version })
+	return out, nil
+}
+
+func (m *Migrator) ensureTable() error {
+	_, err := m.DB.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+  version INTEGER PRIMARY KEY,
+  name TEXT NOT NULL,
+  checksum TEXT NOT NULL,
+  applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+)`)
+	return err
+}
+
+func (m *Migrator) applied() (map[int]applied, error) {
+	if err := m.ensureTable(); err != nil {
+		return nil, err
+	}
+	rows, err := m.DB.Query(`SELECT version, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[int]applied{}
+	for rows.Next() {
+		var v int
+		var a applied
+		if err := rows.Scan(&v, &a.checksum, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		out[v] = a
+	}
+	return out, rows.Err()
+}
+
+func verify(all []Migration, done map[int]applied) error {
+	known := map[int]bool{}
+	for _, mg := range all {
+		known[mg.Version] = true
+		if a, ok := done[mg.Version]; ok && a.checksum != mg.Checksum {
+			return fmt.Errorf("%w: %03d_%s was modified after it was applied", ErrChecksumMismatch, mg.Version, mg.Name)
+		}
+	}
+	for v := range done {
+		if !known[v] {
+			return fmt.Errorf("%w: version %03d", ErrMissingFile, v)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) load() ([]Migration, map[int]applied, error) {
+	all, err := Load(m.Dir)
+	if err != nil {
+		return nil, nil, err
+	}
+	done, err := m.applied()
+	if err != nil {
+		return nil, nil, err
+	}
+	return all, done, nil
+}
+
+// Up applies every pending migration in version order, each in its own
+// transaction. It refuses to run anything if an applied file has changed.
+func (m *Migrator) Up() ([]Migration, error) {
+	all, done, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(all, done); err != nil {
+		return nil, err
+	}
+	ran := []Migration{}
+	for _, mg := range all {
+		if _, ok := done[mg.Version]; ok {
+			continue
+		}
+		if err := m.exec(mg.Up, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`INSERT INTO schema_migrations(version, name, checksum) VALUES(?,?,?)`, mg.Version, mg.Name, mg.Checksum)
+			return err
+		}); err != nil {
+			return ran, fmt.Errorf("%03d_%s: %w", mg.Version, mg.Name, err)
+		}
+		ran = append(ran, mg)
+	}
+	return ran, nil
+}
+
+// Down reverts the latest `steps` applied migrations, newest first.
+func (m *Migrator) Down(steps int) ([]Migration, error) {
+	all, done, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if err := verify(all, done); err != nil {
+		return nil, err
+	}
+	reverted := []Migration{}
+	for i := len(all) - 1; i >= 0 && len(reverted) < steps; i-- {
+		mg := all[i]
+		if _, ok := done[mg.Version]; !ok {
+			continue
+		}
+		if strings.TrimSpace(mg.Down) == "" {
+			return reverted, fmt.Errorf("%w: %03d_%s", ErrNoDownMigration, mg.Version, mg.Name)
+		}
+		if err := m.exec(mg.Down, func(tx *sql.Tx) error {
+			_, err := tx.Exec(`DELETE FROM schema_migrations WHERE version=?`, mg.Version)
+			return err
+		}); err != nil {
+			return reverted, fmt.Errorf("%03d_%s: %w", mg.Version, mg.Name, err)
+		}
+		reverted = append(reverted, mg)
+	}
+	return reverted, nil
+}
+
+func (m *Migrator) Status() ([]Status, error) {
+	all, done, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Status, 0, len(all))
+	for _, mg := range all {
+		st := Status{Version: mg.Version, Name: mg.Name}
+		if a, ok := done[mg.Version]; ok {
+			at := a.appliedAt
+			st.Applied = true
+			st.AppliedAt = &at
+		}
+		out = append(out, st)
+	}
+	return out, verify(all, done)
+}
+
+func (m *Migrator) exec(body string, record func(tx *sql.Tx) error) error {
+	tx, err := m.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(body); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := record(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,116 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newTestMigrator(t *testing.T, files map[string]string) *Migrator {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, body := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("open sqlite: %v", err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return &Migrator{DB: db, Dir: dir}
+}
+
+func tableExists(t *testing.T, db *sql.DB, name string) bool {
+	t.Helper()
+	var c int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type='table' AND name=?`, name).Scan(&c); err != nil {
+		t.Fatalf("query sqlite_master: %v", err)
+	}
+	return c > 0
+}
+
+func TestUpAppliesPendingOnceAndDownReverts(t *testing.T) {
+	m := newTestMigrator(t, map[string]string{
+		"001_a.sql":      `CREATE TABLE a (id TEXT PRIMARY KEY);`,
+		"001_a.down.sql": `DROP TABLE a;`,
+		"002_b.sql":      `CREATE TABLE b (id TEXT PRIMARY KEY); INSERT INTO b(id) VALUES('x');`,
+		"002_b.down.sql": `DROP TABLE b;`,
+	})
+
+	ran, err := m.Up()
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if len(ran) != 2 {
+		t.Fatalf("expected 2 migrations applied, got %d", len(ran))
+	}
+	// A second run must not re-execute the non-idempotent INSERT.
+	ran, err = m.Up()
+	if err != nil || len(ran) != 0 {
+		t.Fatalf("expected no-op second run, got %d applied, err=%v", len(ran), err)
+	}
+
+	reverted, err := m.Down(1)
+	if err != nil {
+		t.Fatalf("down: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("expected to revert 002, got %#v", reverted)
+	}
+	if tableExists(t, m.DB, "b") || !tableExists(t, m.DB, "a") {
+		t.Fatalf("unexpected schema after down")
+	}
+
+	items, err := m.Status()
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	if len(items) != 2 || !items[0].Applied || items[1].Applied {
+		t.Fatalf("unexpected status: %#v", items)
+	}
+}
+
+func TestUpRollsBackFailedMigration(t *testing.T) {
+	m := newTestMigrator(t, map[string]string{
+		"001_broken.sql": `CREATE TABLE c (id TEXT); INSERT INTO missing_table VALUES(1);`,
+	})
+	if _, err := m.Up(); err == nil {
+		t.Fatalf("expected broken migration to fail")
+	}
+	if tableExists(t, m.DB, "c") {
+		t.Fatalf("failed migration must not leave partial schema behind")
+	}
+	items, _ := m.Status()
+	if len(items) != 1 || items[0].Applied {
+		t.Fatalf("failed migration must not be recorded: %#v", items)
+	}
+}
+
+func TestUpRefusesChecksumDrift(t *testing.T) {
+	m := newTestMigrator(t, map[string]string{
+		"001_a.sql": `CREATE TABLE a (id TEXT PRIMARY KEY);`,
+	})
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.Dir, "001_a.sql"), []byte(`CREATE TABLE a (id INTEGER);`), 0o644); err != nil {
+		t.Fatalf("rewrite migration: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(m.Dir, "002_b.sql"), []byte(`CREATE TABLE b (id TEXT);`), 0o644); err != nil {
+		t.Fatalf("write migration: %v", err)
+	}
+	_, err := m.Up()
+	if !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("expected ErrChecksumMismatch, got %v", err)
+	}
+	if tableExists(t, m.DB, "b") {
+		t.Fatalf("pending migrations must not run when drift is detected")
+	}
+}
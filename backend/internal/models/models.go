@@ -3,51 +3,258 @@ package models
 import "time"
 
 type User struct {
+	ID                    string     `json:"id"`
+	Username              string     `json:"username"`
+	Password              string     `json:"-"`
+	Role                  string     `json:"role"`
+	FullName              string     `json:"fullName"`
+	Email                 string     `json:"email"`
+	Phone                 string     `json:"phone"`
+	DateOfBirth           *time.Time `json:"dateOfBirth"`
+	LicenseNumber         string     `json:"licenseNumber"`
+	LicenseExpiry         *time.Time `json:"licenseExpiry"`
+	DisabledAt            *time.Time `json:"disabledAt,omitempty"`
+	DeletedAt             *time.Time `json:"deletedAt,omitempty"`
+	PasswordResetRequired bool       `json:"passwordResetRequired"`
+	CreatedAt             time.Time  `json:"createdAt"`
+}
+
+type Car struct {
+	ID           string        `json:"id"`
+	Brand        string        `json:"brand"`
+	Model        string        `json:"model"`
+	Year         int           `json:"year"`
+	Category     string        `json:"category"`
+	Transmission string        `json:"transmission"`
+	Fuel         string        `json:"fuel"`
+	Seats        int           `json:"seats"`
+	DailyPrice   Money         `json:"dailyPrice"`
+	Currency     string        `json:"currency"`
+	Status       string        `json:"status"`
+	Mileage      int           `json:"mileage"`
+	Description  string        `json:"description"`
+	Images       []string      `json:"images"`
+	HourlyRates  []HourlyRate  `json:"hourlyRates"`
+	CreatedAt    time.Time     `json:"createdAt"`
+	Occupancy    *CarOccupancy `json:"occupancy,omitempty"`
+	RentalDays   int           `json:"rentalDays,omitempty"`
+	RentalHours  int           `json:"rentalHours,omitempty"`
+	TotalPrice   *Money        `json:"totalPrice,omitempty"`
+}
+
+// HourlyRate prices rentals shorter than a day: a rental of up to MaxHours
+// hours costs PricePerHour per started hour, capped at the daily price.
+type HourlyRate struct {
+	MaxHours     int   `json:"maxHours"`
+	PricePerHour Money `json:"pricePerHour"`
+}
+
+// CarOccupancy is computed from reservations for one date range and is
+// never stored. Status on the car is only the operational state.
+type CarOccupancy struct {
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Occupied bool      `json:"occupied"`
+	Bookable bool      `json:"bookable"`
+}
+
+// Branch is a pickup location. Reservation times are entered, validated and
+// shown in its time zone (an IANA name such as "Europe/Sarajevo"), and
+// bookings picked up there are taxed at TaxRate percent; a nil rate means
+// the default rate applies.
+type Branch struct {
 	ID        string    `json:"id"`
-	Username  string    `json:"username"`
-	Password  string    `json:"-"`
-	Role      string    `json:"role"`
+	Name      string    `json:"name"`
+	Timezone  string    `json:"timezone"`
+	TaxRate   *float64  `json:"taxRate"`
+	TaxName   string    `json:"taxName"`
 	CreatedAt time.Time `json:"createdAt"`
 }
 
-type Car struct {
+// PricingRule adjusts the rental rate or adds a fee. Active rules are
+// evaluated in Position order; which fields matter depends on Kind.
+type PricingRule struct {
 	ID           string    `json:"id"`
-	Brand        string    `json:"brand"`
-	Model        string    `json:"model"`
-	Year         int       `json:"year"`
+	Name         string    `json:"name"`
+	Kind         string    `json:"kind"`
+	Position     int       `json:"position"`
+	Active       bool      `json:"active"`
+	Percent      float64   `json:"percent"`
+	AmountPerDay Money     `json:"amountPerDay"`
+	Currency     string    `json:"currency"`
 	Category     string    `json:"category"`
-	Transmission string    `json:"transmission"`
-	Fuel         string    `json:"fuel"`
-	Seats        int       `json:"seats"`
-	DailyPrice   float64   `json:"dailyPrice"`
-	Status       string    `json:"status"`
-	Mileage      int       `json:"mileage"`
-	Description  string    `json:"description"`
-	Images       []string  `json:"images"`
+	SeasonStart  string    `json:"seasonStart,omitempty"`
+	SeasonEnd    string    `json:"seasonEnd,omitempty"`
+	MinDays      int       `json:"minDays,omitempty"`
+	MinLeadDays  int       `json:"minLeadDays,omitempty"`
+	MaxLeadHours int       `json:"maxLeadHours,omitempty"`
+	MaxDriverAge int       `json:"maxDriverAge,omitempty"`
 	CreatedAt    time.Time `json:"createdAt"`
 }
 
+// LineItem is one row of a price: a base rate, a pricing rule, an extra, a
+// fee, tax or the deposit. Reservations keep the lines they were priced
+// with, so later price changes do not rewrite what the customer agreed to.
+// Discounts are negative; the deposit is not part of the total. Amounts are
+// in the currency of the price they belong to.
+type LineItem struct {
+	Kind      string `json:"kind"`
+	Code      string `json:"code"`
+	Label     string `json:"label"`
+	Quantity  int    `json:"quantity"`
+	UnitPrice Money  `json:"unitPrice"`
+	Amount    Money  `json:"amount"`
+}
+
 type Extra struct {
-	ID          string  `json:"id"`
-	Name        string  `json:"name"`
-	PricePerDay float64 `json:"pricePerDay"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	PricePerDay Money  `json:"pricePerDay"`
+	Currency    string `json:"currency"`
+}
+
+// ExchangeRate converts prices for display: one unit of Base is Rate units
+// of Currency.
+type ExchangeRate struct {
+	Base      string    `json:"base"`
+	Currency  string    `json:"currency"`
+	Rate      float64   `json:"rate"`
+	UpdatedAt time.Time `json:"updatedAt"`
 }
 
 type Reservation struct {
-	ID              string    `json:"id"`
-	CarID           string    `json:"carId"`
-	UserID          string    `json:"userId"`
-	StartDate       time.Time `json:"startDate"`
-	EndDate         time.Time `json:"endDate"`
-	PickupLocation  string    `json:"pickupLocation"`
-	DropoffLocation string    `json:"dropoffLocation"`
-	Notes           string    `json:"notes"`
-	Status          string    `json:"status"`
-	TotalPrice      float64   `json:"totalPrice"`
-	CreatedAt       time.Time `json:"createdAt"`
-	Extras          []Extra   `json:"extras,omitempty"`
-	Car             *Car      `json:"car,omitempty"`
-	Username        string    `json:"username,omitempty"`
+	ID              string     `json:"id"`
+	CarID           string     `json:"carId"`
+	UserID          string     `json:"userId"`
+	StartDate       time.Time  `json:"startDate"`
+	EndDate         time.Time  `json:"endDate"`
+	PickupLocation  string     `json:"pickupLocation"`
+	DropoffLocation string     `json:"dropoffLocation"`
+	Notes           string     `json:"notes"`
+	Status          string     `json:"status"`
+	TotalPrice      Money      `json:"totalPrice"`
+	Currency        string     `json:"currency"`
+	Timezone        string     `json:"timezone"`
+	QuoteID         string     `json:"quoteId,omitempty"`
+	PromoCode       string     `json:"promoCode,omitempty"`
+	PromoCodeID     string     `json:"-"`
+	Discount        Money      `json:"discount,omitempty"`
+	PaymentMethod   string     `json:"-"`
+	CanCancel       bool       `json:"canCancel"`
+	ApprovedAt      *time.Time `json:"approvedAt,omitempty"`
+	PickedUpAt      *time.Time `json:"pickedUpAt,omitempty"`
+	ReturnedAt      *time.Time `json:"returnedAt,omitempty"`
+	CancelledAt     *time.Time `json:"cancelledAt,omitempty"`
+	HoldExpiresAt   *time.Time `json:"holdExpiresAt,omitempty"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	Extras          []Extra    `json:"extras,omitempty"`
+	LineItems       []LineItem `json:"lineItems,omitempty"`
+	Payment         *Payment   `json:"payment,omitempty"`
+	Deposit         *Payment   `json:"deposit,omitempty"`
+	Car             *Car       `json:"car,omitempty"`
+	Username        string     `json:"username,omitempty"`
+	// CancellationPolicy is the policy in force when the car was booked.
+	// CancellationFee and Refund are set once the reservation is cancelled.
+	CancellationPolicy *CancellationPolicy `json:"cancellationPolicy,omitempty"`
+	CancellationFee    *Money              `json:"cancellationFee,omitempty"`
+	Refund             *Money              `json:"refund,omitempty"`
+}
+
+// Payment is one attempt to pay for a reservation through a gateway, or to
+// hold its security deposit. The latest attempt of each kind is the one
+// that counts.
+type Payment struct {
+	ID            string    `json:"id"`
+	ReservationID string    `json:"reservationId"`
+	Kind          string    `json:"kind"`
+	Method        string    `json:"-"`
+	Provider      string    `json:"provider"`
+	ProviderRef   string    `json:"providerRef"`
+	Status        string    `json:"status"`
+	Amount        Money     `json:"amount"`
+	Captured      Money     `json:"captured"`
+	Refunded      Money     `json:"refunded"`
+	Currency      string    `json:"currency"`
+	FailureReason string    `json:"failureReason,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	UpdatedAt     time.Time `json:"updatedAt"`
+}
+
+// CategoryDeposit is the security deposit held at pickup for cars of a
+// category.
+type CategoryDeposit struct {
+	Category  string    `json:"category"`
+	Amount    Money     `json:"amount"`
+	Currency  string    `json:"currency"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// CancellationPolicy decides what a customer pays to cancel. It applies to
+// cars of its Categories, or to every other car when IsDefault is set.
+type CancellationPolicy struct {
+	ID         string             `json:"id"`
+	Name       string             `json:"name"`
+	Tiers      []CancellationTier `json:"tiers"`
+	Categories []string           `json:"categories,omitempty"`
+	IsDefault  bool               `json:"isDefault"`
+	CreatedAt  time.Time          `json:"createdAt"`
+}
+
+// CancellationTier charges FeePercent of what was paid for cancelling
+// less than HoursBefore hours before pickup.
+type CancellationTier struct {
+	HoursBefore int     `json:"hoursBefore"`
+	FeePercent  float64 `json:"feePercent"`
+}
+
+// PromoCode discounts bookings that meet its restrictions: a percentage
+// code takes Percent off the rental, a fixed one Amount. Zero limits and
+// an empty Categories mean no restriction. Uses and Discounted report the
+// bookings that were not cancelled or denied.
+type PromoCode struct {
+	ID             string     `json:"id"`
+	Code           string     `json:"code"`
+	Kind           string     `json:"kind"`
+	Percent        float64    `json:"percent,omitempty"`
+	Amount         Money      `json:"amount,omitempty"`
+	Currency       string     `json:"currency"`
+	MinDays        int        `json:"minDays"`
+	ValidFrom      *time.Time `json:"validFrom,omitempty"`
+	ValidTo        *time.Time `json:"validTo,omitempty"`
+	MaxUses        int        `json:"maxUses"`
+	MaxUsesPerUser int        `json:"maxUsesPerUser"`
+	Categories     []string   `json:"categories"`
+	Active         bool       `json:"active"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	Uses           int        `json:"uses"`
+	Discounted     Money      `json:"discounted"`
+}
+
+// Invoice is issued once a rental is completed. Its number is unique and
+// gap-free within the year, and the seller and customer details are copied
+// at the time of issue.
+type Invoice struct {
+	ID            string       `json:"id"`
+	Number        string       `json:"number"`
+	Year          int          `json:"year"`
+	Sequence      int          `json:"sequence"`
+	ReservationID string       `json:"reservationId"`
+	UserID        string       `json:"userId"`
+	IssuedAt      time.Time    `json:"issuedAt"`
+	Seller        InvoiceParty `json:"seller"`
+	Customer      InvoiceParty `json:"customer"`
+	Lines         []LineItem   `json:"lines,omitempty"`
+	Subtotal      Money        `json:"subtotal"`
+	Tax           Money        `json:"tax"`
+	Total         Money        `json:"total"`
+	Currency      string       `json:"currency"`
+}
+
+type InvoiceParty struct {
+	Name    string `json:"name"`
+	Address string `json:"address,omitempty"`
+	TaxID   string `json:"taxId,omitempty"`
+	Email   string `json:"email,omitempty"`
 }
 
 type AuditLog struct {
@@ -70,3 +277,39 @@ type Review struct {
 	Comment   string    `json:"comment"`
 	CreatedAt time.Time `json:"createdAt"`
 }
+
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"userId"`
+	TokenHash  string     `json:"-"`
+	UserAgent  string     `json:"userAgent"`
+	IP         string     `json:"ip"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	// MFAVerified is set when the session was started with a second factor.
+	MFAVerified bool      `json:"-"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type TOTP struct {
+	UserID       string
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
+
+type PasswordResetToken struct {
+	ID        string
+	UserID    string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+}
+
+type LoginThrottle struct {
+	Key           string     `json:"key"`
+	Failures      int        `json:"failures"`
+	LastFailureAt time.Time  `json:"lastFailureAt"`
+	LockedUntil   *time.Time `json:"lockedUntil,omitempty"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Money is an amount in minor units (cents) of a currency that is stored
+// next to it. Only currencies with two decimal places are supported. JSON
+// carries the decimal amount, so the API reads and writes 12.5 for 1250.
+type Money int64
+
+// MoneyFromFloat rounds a decimal amount to the nearest minor unit.
+func MoneyFromFloat(v float64) Money { return Money(math.Round(v * 100)) }
+
+func (m Money) Float() float64 { return float64(m) / 100 }
+
+// Times multiplies by a quantity.
+func (m Money) Times(n int) Money { return m * Money(n) }
+
+// Percent returns p percent of m, rounded half away from zero.
+func (m Money) Percent(p float64) Money { return Money(math.Round(float64(m) * p / 100)) }
+
+func (m Money) String() string {
+	sign, v := "", int64(m)
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) { return []byte(m.String()), nil }
+
+func (m *Money) UnmarshalJSON(b []byte) error {
+	if string(b) == "null" {
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(b, &n); err != nil {
+		return fmt.Errorf("amount must be a number: %w", err)
+	}
+	v, err := strconv.ParseFloat(n.String(), 64)
+	if err != nil || math.IsInf(v, 0) || math.Abs(v) > math.MaxInt64/100 {
+		return fmt.Errorf("invalid amount %s", n)
+	}
+	*m = MoneyFromFloat(v)
+	return nil
+}
@@ -0,0 +1,100 @@
+package payments
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"rentacar/backend/internal/models"
+)
+
+// Test payment methods understood by FakeProvider. Any other method,
+// including none, is authorized straight away.
+const (
+	FakeMethodDecline        = "fake_decline"
+	FakeMethodAsync          = "fake_async"
+	FakeMethodDeclineCapture = "fake_decline_capture"
+)
+
+const (
+	FakeSignatureHeader = "X-Fake-Signature"
+	fakeNoCapturePrefix = "fake_nocapture_"
+)
+
+// FakeProvider is a local gateway for development and tests. It moves no
+// money and keeps no state: the outcome of each call follows from the
+// payment method, which is encoded in the reference it hands out, so it
+// keeps working across restarts. Webhooks are signed with Secret.
+type FakeProvider struct {
+	Secret string
+}
+
+func (f *FakeProvider) Name() string { return "fake" }
+
+func (f *FakeProvider) Authorize(req AuthorizeRequest) (Result, error) {
+	if req.Amount < 0 {
+		return Result{}, fmt.Errorf("amount must not be negative")
+	}
+	prefix := "fake_"
+	if req.Method == FakeMethodDeclineCapture {
+		prefix = fakeNoCapturePrefix
+	}
+	ref, err := fakeRef(prefix)
+	if err != nil {
+		return Result{}, err
+	}
+	switch req.Method {
+	case FakeMethodDecline:
+		return Result{Ref: ref, Status: StatusFailed, Reason: "card declined"}, nil
+	case FakeMethodAsync:
+		return Result{Ref: ref, Status: StatusPending}, nil
+	default:
+		return Result{Ref: ref, Status: StatusAuthorized}, nil
+	}
+}
+
+func (f *FakeProvider) Capture(ref string, amount models.Money) error {
+	if strings.HasPrefix(ref, fakeNoCapturePrefix) {
+		return fmt.Errorf("%w: insufficient funds", ErrDeclined)
+	}
+	return nil
+}
+
+func (f *FakeProvider) Void(ref string) error { return nil }
+
+func (f *FakeProvider) Refund(ref string, amount models.Money) error { return nil }
+
+// Sign returns the signature header value for a webhook body, so tests and
+// local scripts can play the gateway's side.
+func (f *FakeProvider) Sign(body []byte) string {
+	mac := hmac.New(sha256.New, []byte(f.Secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (f *FakeProvider) ParseWebhook(body []byte, header http.Header) (Event, error) {
+	if f.Secret == "" || !hmac.Equal([]byte(header.Get(FakeSignatureHeader)), []byte(f.Sign(body))) {
+		return Event{}, ErrInvalidSignature
+	}
+	var e Event
+	if err := json.Unmarshal(body, &e); err != nil {
+		return Event{}, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if e.ID == "" || e.Ref == "" || e.Status == "" {
+		return Event{}, fmt.Errorf("%w: id, ref and status are required", ErrInvalidEvent)
+	}
+	return e, nil
+}
+
+func fakeRef(prefix string) (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return prefix + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,71 @@
+// Package payments talks to payment gateways. The rest of the app only sees
+// PaymentProvider, so a real gateway can replace the local fake without
+// touching reservations.
+package payments
+
+import (
+	"errors"
+	"net/http"
+
+	"rentacar/backend/internal/models"
+)
+
+// Payment statuses. A payment is authorized (funds held) when it is made,
+// captured when the booking is confirmed and voided or refunded when it is
+// called off. Pending means the gateway will report the outcome later
+// through a webhook.
+const (
+	StatusPending           = "pending"
+	StatusAuthorized        = "authorized"
+	StatusCaptured          = "captured"
+	StatusPartiallyRefunded = "partially_refunded"
+	StatusRefunded          = "refunded"
+	StatusVoided            = "voided"
+	StatusFailed            = "failed"
+)
+
+var (
+	ErrDeclined         = errors.New("payment declined")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+	ErrInvalidEvent     = errors.New("invalid webhook event")
+)
+
+type AuthorizeRequest struct {
+	// Reference is our reservation ID; gateways echo it back in webhooks.
+	Reference string
+	Amount    models.Money
+	Currency  string
+	// Method is the gateway's token for the customer's card or wallet.
+	Method string
+}
+
+// Result is the gateway's answer to an authorization. Status is authorized,
+// pending or failed; Reason explains a failure.
+type Result struct {
+	Ref    string
+	Status string
+	Reason string
+}
+
+// Event is an asynchronous result reported by a webhook. Status is the
+// payment status the gateway moved the payment to.
+type Event struct {
+	ID     string       `json:"id"`
+	Ref    string       `json:"ref"`
+	Status string       `json:"status"`
+	Amount models.Money `json:"amount"`
+	Reason string       `json:"reason,omitempty"`
+}
+
+// PaymentProvider is a payment gateway. Capturing less than was authorized
+// releases the rest. Capture, Void and Refund return an error wrapping
+// ErrDeclined when the gateway refuses the operation.
+type PaymentProvider interface {
+	Name() string
+	Authorize(req AuthorizeRequest) (Result, error)
+	Capture(ref string, amount models.Money) error
+	Void(ref string) error
+	Refund(ref string, amount models.Money) error
+	// ParseWebhook checks the signature of a webhook delivery and decodes it.
+	ParseWebhook(body []byte, header http.Header) (Event, error)
+}
@@ -0,0 +1,39 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+)
+
+func TestCrossReferenceTablePointsAtObjects(t *testing.T) {
+	doc := New()
+	doc.Text(50, 800, 12, true, "Invoice (draft) 100\\")
+	doc.AddPage()
+	doc.TextRight(545, 800, 10, false, "Škoda 12.50 €")
+	out := doc.Bytes()
+
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(out)
+	if m == nil {
+		t.Fatalf("missing trailer:\n%s", out)
+	}
+	xref, _ := strconv.Atoi(string(m[1]))
+	if !bytes.HasPrefix(out[xref:], []byte("xref\n0 9\n")) {
+		t.Fatalf("startxref does not point at the table: %q", out[xref:xref+20])
+	}
+	offsets := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xref:], -1)
+	if len(offsets) != 8 {
+		t.Fatalf("expected 8 objects, got %d", len(offsets))
+	}
+	for i, o := range offsets {
+		at, _ := strconv.Atoi(string(o[1]))
+		if want := fmt.Sprintf("%d 0 obj", i+1); !bytes.HasPrefix(out[at:], []byte(want)) {
+			t.Fatalf("offset %d does not start %q", at, want)
+		}
+	}
+	if !bytes.Contains(out, []byte(`(Invoice \(draft\) 100\\)`)) || !bytes.Contains(out, []byte(`(\212koda 12.50 \200)`)) {
+		t.Fatalf("text not escaped for WinAnsiEncoding:\n%s", out)
+	}
+}
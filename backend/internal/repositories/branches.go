@@ -0,0 +1,49 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type BranchRepository struct{ DB *sql.DB }
+
+func (r *BranchRepository) List() ([]models.Branch, error) {
+	rows, err := r.DB.Query(`SELECT id, name, timezone, tax_rate, tax_name, created_at FROM branches ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Branch{}
+	for rows.Next() {
+		var b models.Branch
+		if err := rows.Scan(&b.ID, &b.Name, &b.Timezone, &b.TaxRate, &b.TaxName, &b.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+// GetByName looks a branch up case-insensitively, the way customers type it.
+func (r *BranchRepository) GetByName(name string) (*models.Branch, error) {
+	var b models.Branch
+	if err := r.DB.QueryRow(`SELECT id, name, timezone, tax_rate, tax_name, created_at FROM branches WHERE LOWER(name)=LOWER(?)`, name).Scan(&b.ID, &b.Name, &b.Timezone, &b.TaxRate, &b.TaxName, &b.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+func (r *BranchRepository) Create(b *models.Branch) error {
+	b.ID = uuid.NewString()
+	_, err := r.DB.Exec(`INSERT INTO branches(id, name, timezone, tax_rate, tax_name) VALUES(?,?,?,?,?)`, b.ID, b.Name, b.Timezone, b.TaxRate, b.TaxName)
+	return err
+}
+func (r *BranchRepository) Update(id string, b *models.Branch) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE branches SET name=?, timezone=?, tax_rate=?, tax_name=? WHERE id=?`, b.Name, b.Timezone, b.TaxRate, b.TaxName, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
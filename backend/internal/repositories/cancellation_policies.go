@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type CancellationPolicyRepository struct{ DB *sql.DB }
+
+const cancellationPolicyColumns = `p.id, p.name, p.tiers, p.is_default, p.created_at,
+	(SELECT json_group_array(category) FROM (SELECT category FROM cancellation_policy_categories WHERE policy_id=p.id ORDER BY category))`
+
+func scanCancellationPolicy(row interface{ Scan(...interface{}) error }) (*models.CancellationPolicy, error) {
+	var p models.CancellationPolicy
+	var tiers, categories string
+	if err := row.Scan(&p.ID, &p.Name, &tiers, &p.IsDefault, &p.CreatedAt, &categories); err != nil {
+		return nil, err
+	}
+	p.Tiers = []models.CancellationTier{}
+	_ = json.Unmarshal([]byte(tiers), &p.Tiers)
+	p.Categories = []string{}
+	_ = json.Unmarshal([]byte(categories), &p.Categories)
+	return &p, nil
+}
+
+func (r *CancellationPolicyRepository) List() ([]models.CancellationPolicy, error) {
+	rows, err := r.DB.Query(`SELECT ` + cancellationPolicyColumns + ` FROM cancellation_policies p ORDER BY p.is_default DESC, p.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.CancellationPolicy{}
+	for rows.Next() {
+		p, err := scanCancellationPolicy(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// ForCategory returns the policy assigned to category, or the default one.
+func (r *CancellationPolicyRepository) ForCategory(category string) (*models.CancellationPolicy, error) {
+	return scanCancellationPolicy(r.DB.QueryRow(`SELECT `+cancellationPolicyColumns+` FROM cancellation_policies p
+	LEFT JOIN cancellation_policy_categories c ON c.policy_id=p.id AND c.category=?
+	WHERE c.category IS NOT NULL OR p.is_default=1 ORDER BY c.category IS NULL LIMIT 1`, category))
+}
+
+func (r *CancellationPolicyRepository) Create(p *models.CancellationPolicy) error {
+	p.ID = uuid.NewString()
+	return r.save(p, true)
+}
+
+func (r *CancellationPolicyRepository) Update(id string, p *models.CancellationPolicy) (bool, error) {
+	p.ID = id
+	err := r.save(p, false)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// save writes the policy and takes over its categories from whichever
+// policy had them. Only one policy can be the default.
+func (r *CancellationPolicyRepository) save(p *models.CancellationPolicy, create bool) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	tiers, _ := json.Marshal(p.Tiers)
+	if create {
+		err = tx.QueryRow(`INSERT INTO cancellation_policies(id, name, tiers, is_default) VALUES(?,?,?,?) RETURNING created_at`, p.ID, p.Name, string(tiers), p.IsDefault).Scan(&p.CreatedAt)
+	} else {
+		err = tx.QueryRow(`UPDATE cancellation_policies SET name=?, tiers=?, is_default=? WHERE id=? RETURNING created_at`, p.Name, string(tiers), p.IsDefault, p.ID).Scan(&p.CreatedAt)
+	}
+	if err != nil {
+		return err
+	}
+	if p.IsDefault {
+		if _, err := tx.Exec(`UPDATE cancellation_policies SET is_default=0 WHERE id<>?`, p.ID); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec(`DELETE FROM cancellation_policy_categories WHERE policy_id=?`, p.ID); err != nil {
+		return err
+	}
+	for _, c := range p.Categories {
+		if _, err := tx.Exec(`INSERT INTO cancellation_policy_categories(category, policy_id) VALUES(?,?)
+		ON CONFLICT(category) DO UPDATE SET policy_id=excluded.policy_id`, c, p.ID); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *CancellationPolicyRepository) Delete(id string) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.Exec(`DELETE FROM cancellation_policy_categories WHERE policy_id=?`, id); err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`DELETE FROM cancellation_policies WHERE id=?`, id)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	return true, tx.Commit()
+}
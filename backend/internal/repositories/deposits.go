@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"rentacar/backend/internal/models"
+)
+
+type DepositRepository struct{ DB *sql.DB }
+
+func (r *DepositRepository) List() ([]models.CategoryDeposit, error) {
+	rows, err := r.DB.Query(`SELECT category, amount_minor, currency, updated_at FROM category_deposits ORDER BY category`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.CategoryDeposit{}
+	for rows.Next() {
+		var d models.CategoryDeposit
+		if err := rows.Scan(&d.Category, &d.Amount, &d.Currency, &d.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
+
+func (r *DepositRepository) Get(category string) (*models.CategoryDeposit, error) {
+	var d models.CategoryDeposit
+	err := r.DB.QueryRow(`SELECT category, amount_minor, currency, updated_at FROM category_deposits WHERE category=?`, category).Scan(&d.Category, &d.Amount, &d.Currency, &d.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Set adds the deposit for a category or replaces the existing one.
+func (r *DepositRepository) Set(d *models.CategoryDeposit) error {
+	return r.DB.QueryRow(`INSERT INTO category_deposits(category, amount_minor, currency) VALUES(?,?,?)
+	ON CONFLICT(category) DO UPDATE SET amount_minor=excluded.amount_minor, currency=excluded.currency, updated_at=CURRENT_TIMESTAMP RETURNING updated_at`, d.Category, d.Amount, d.Currency).Scan(&d.UpdatedAt)
+}
+
+func (r *DepositRepository) Delete(category string) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM category_deposits WHERE category=?`, category)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
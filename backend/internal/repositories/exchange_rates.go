@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"rentacar/backend/internal/models"
+)
+
+type ExchangeRateRepository struct{ DB *sql.DB }
+
+// List returns the rates from base, by currency code.
+func (r *ExchangeRateRepository) List(base string) ([]models.ExchangeRate, error) {
+	rows, err := r.DB.Query(`SELECT base, currency, rate, updated_at FROM exchange_rates WHERE base=? ORDER BY currency`, base)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.ExchangeRate{}
+	for rows.Next() {
+		var x models.ExchangeRate
+		if err := rows.Scan(&x.Base, &x.Currency, &x.Rate, &x.UpdatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, x)
+	}
+	return out, rows.Err()
+}
+
+// Set adds a rate or replaces the existing one for the same pair.
+func (r *ExchangeRateRepository) Set(x *models.ExchangeRate) error {
+	return r.DB.QueryRow(`INSERT INTO exchange_rates(base, currency, rate) VALUES(?,?,?)
+	ON CONFLICT(base, currency) DO UPDATE SET rate=excluded.rate, updated_at=CURRENT_TIMESTAMP RETURNING updated_at`, x.Base, x.Currency, x.Rate).Scan(&x.UpdatedAt)
+}
+
+func (r *ExchangeRateRepository) Delete(base, currency string) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM exchange_rates WHERE base=? AND currency=?`, base, currency)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
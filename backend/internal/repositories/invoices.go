@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type InvoiceRepository struct{ DB *sql.DB }
+
+const invoiceColumns = `id, number, year, sequence, reservation_id, user_id, issued_at, seller_name, seller_address, seller_tax_id, seller_email, customer_name, customer_email, subtotal_minor, tax_minor, total_minor, currency`
+
+func scanInvoice(row interface{ Scan(...interface{}) error }) (*models.Invoice, error) {
+	var inv models.Invoice
+	if err := row.Scan(&inv.ID, &inv.Number, &inv.Year, &inv.Sequence, &inv.ReservationID, &inv.UserID, &inv.IssuedAt,
+		&inv.Seller.Name, &inv.Seller.Address, &inv.Seller.TaxID, &inv.Seller.Email, &inv.Customer.Name, &inv.Customer.Email,
+		&inv.Subtotal, &inv.Tax, &inv.Total, &inv.Currency); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
+
+// Create numbers and saves an invoice as prefix-year-sequence. The sequence
+// is taken in the same BEGIN IMMEDIATE transaction as the insert, so
+// numbers are handed out in order and a failed insert does not use one up.
+// A reservation has at most one invoice; a second one fails.
+func (r *InvoiceRepository) Create(inv *models.Invoice, prefix string) error {
+	ctx := context.Background()
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return err
+	}
+	rollback := func() { _, _ = conn.ExecContext(ctx, `ROLLBACK`) }
+	inv.ID = uuid.NewString()
+	inv.Year = inv.IssuedAt.Year()
+	if err := conn.QueryRowContext(ctx, `INSERT INTO invoice_sequences(year, last_number) VALUES(?, 1)
+	ON CONFLICT(year) DO UPDATE SET last_number=last_number+1 RETURNING last_number`, inv.Year).Scan(&inv.Sequence); err != nil {
+		rollback()
+		return err
+	}
+	inv.Number = fmt.Sprintf("%s-%d-%06d", prefix, inv.Year, inv.Sequence)
+	if _, err := conn.ExecContext(ctx, `INSERT INTO invoices(`+invoiceColumns+`) VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`,
+		inv.ID, inv.Number, inv.Year, inv.Sequence, inv.ReservationID, inv.UserID, inv.IssuedAt.UTC(),
+		inv.Seller.Name, inv.Seller.Address, inv.Seller.TaxID, inv.Seller.Email, inv.Customer.Name, inv.Customer.Email,
+		inv.Subtotal, inv.Tax, inv.Total, inv.Currency); err != nil {
+		rollback()
+		return err
+	}
+	for i, li := range inv.Lines {
+		if _, err := conn.ExecContext(ctx, `INSERT INTO invoice_lines(invoice_id, position, kind, code, label, quantity, unit_price_minor, amount_minor) VALUES(?,?,?,?,?,?,?,?)`,
+			inv.ID, i, li.Kind, li.Code, li.Label, li.Quantity, li.UnitPrice, li.Amount); err != nil {
+			rollback()
+			return err
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `COMMIT`); err != nil {
+		rollback()
+		return err
+	}
+	return nil
+}
+
+// List returns invoices newest first, optionally for one year.
+func (r *InvoiceRepository) List(year int) ([]models.Invoice, error) {
+	q := `SELECT ` + invoiceColumns + ` FROM invoices`
+	args := []interface{}{}
+	if year > 0 {
+		q += ` WHERE year=?`
+		args = append(args, year)
+	}
+	rows, err := r.DB.Query(q+` ORDER BY year DESC, sequence DESC`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Invoice{}
+	for rows.Next() {
+		inv, err := scanInvoice(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *inv)
+	}
+	return out, rows.Err()
+}
+
+func (r *InvoiceRepository) GetByID(id string) (*models.Invoice, error) {
+	return r.withLines(scanInvoice(r.DB.QueryRow(`SELECT `+invoiceColumns+` FROM invoices WHERE id=?`, id)))
+}
+
+func (r *InvoiceRepository) ForReservation(reservationID string) (*models.Invoice, error) {
+	return r.withLines(scanInvoice(r.DB.QueryRow(`SELECT `+invoiceColumns+` FROM invoices WHERE reservation_id=?`, reservationID)))
+}
+
+func (r *InvoiceRepository) withLines(inv *models.Invoice, err error) (*models.Invoice, error) {
+	if err != nil {
+		return nil, err
+	}
+	rows, err := r.DB.Query(`SELECT kind, code, label, quantity, unit_price_minor, amount_minor FROM invoice_lines WHERE invoice_id=? ORDER BY position`, inv.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	inv.Lines = []models.LineItem{}
+	for rows.Next() {
+		var li models.LineItem
+		if err := rows.Scan(&li.Kind, &li.Code, &li.Label, &li.Quantity, &li.UnitPrice, &li.Amount); err != nil {
+			return nil, err
+		}
+		inv.Lines = append(inv.Lines, li)
+	}
+	return inv, rows.Err()
+}
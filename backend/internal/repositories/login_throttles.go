@@ -0,0 +1,40 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+type LoginThrottleRepository struct{ DB *sql.DB }
+
+func (r *LoginThrottleRepository) Get(key string) (*models.LoginThrottle, error) {
+	var t models.LoginThrottle
+	var lockedUntil sql.NullTime
+	if err := r.DB.QueryRow(`SELECT key, failures, last_failure_at, locked_until FROM login_throttles WHERE key=?`, key).Scan(&t.Key, &t.Failures, &t.LastFailureAt, &lockedUntil); err != nil {
+		return nil, err
+	}
+	t.LockedUntil = nullTimePtr(lockedUntil)
+	return &t, nil
+}
+
+// RecordFailure bumps the failure counter, restarting it when the previous
+// failure is older than resetBefore, and returns the new count.
+func (r *LoginThrottleRepository) RecordFailure(key string, now, resetBefore time.Time) (int, error) {
+	var failures int
+	err := r.DB.QueryRow(`INSERT INTO login_throttles(key, failures, last_failure_at) VALUES(?, 1, ?)
+	ON CONFLICT(key) DO UPDATE SET
+	  failures = CASE WHEN last_failure_at < ? THEN 1 ELSE failures + 1 END,
+	  last_failure_at = excluded.last_failure_at
+	RETURNING failures`, key, now, resetBefore).Scan(&failures)
+	return failures, err
+}
+func (r *LoginThrottleRepository) Lock(key string, until time.Time) error {
+	_, err := r.DB.Exec(`UPDATE login_throttles SET locked_until=? WHERE key=?`, until, key)
+	return err
+}
+func (r *LoginThrottleRepository) Clear(key string) error {
+	_, err := r.DB.Exec(`DELETE FROM login_throttles WHERE key=?`, key)
+	return err
+}
@@ -0,0 +1,39 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type PasswordResetRepository struct{ DB *sql.DB }
+
+func (r *PasswordResetRepository) Create(userID, tokenHash string, expiresAt time.Time) error {
+	_, err := r.DB.Exec(`INSERT INTO password_reset_tokens(id, user_id, token_hash, expires_at) VALUES(?,?,?,?)`, uuid.NewString(), userID, tokenHash, expiresAt)
+	return err
+}
+func (r *PasswordResetRepository) FindByHash(tokenHash string) (*models.PasswordResetToken, error) {
+	var p models.PasswordResetToken
+	var usedAt sql.NullTime
+	if err := r.DB.QueryRow(`SELECT id, user_id, expires_at, used_at FROM password_reset_tokens WHERE token_hash=?`, tokenHash).Scan(&p.ID, &p.UserID, &p.ExpiresAt, &usedAt); err != nil {
+		return nil, err
+	}
+	p.UsedAt = nullTimePtr(usedAt)
+	return &p, nil
+}
+
+// MarkUsed consumes a token and reports false if it was already consumed.
+func (r *PasswordResetRepository) MarkUsed(id string) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE password_reset_tokens SET used_at=? WHERE id=? AND used_at IS NULL`, time.Now().UTC(), id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+func (r *PasswordResetRepository) InvalidateForUser(userID string) error {
+	_, err := r.DB.Exec(`UPDATE password_reset_tokens SET used_at=? WHERE user_id=? AND used_at IS NULL`, time.Now().UTC(), userID)
+	return err
+}
@@ -0,0 +1,111 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type PaymentRepository struct{ DB *sql.DB }
+
+const paymentColumns = `id, reservation_id, kind, method, provider, provider_ref, status, amount_minor, captured_minor, refunded_minor, currency, failure_reason, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...interface{}) error }) (*models.Payment, error) {
+	var p models.Payment
+	if err := row.Scan(&p.ID, &p.ReservationID, &p.Kind, &p.Method, &p.Provider, &p.ProviderRef, &p.Status, &p.Amount, &p.Captured, &p.Refunded, &p.Currency, &p.FailureReason, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PaymentRepository) Create(p *models.Payment) error {
+	p.ID = uuid.NewString()
+	return r.DB.QueryRow(`INSERT INTO payments(id, reservation_id, kind, method, provider, provider_ref, status, amount_minor, currency, failure_reason) VALUES(?,?,?,?,?,?,?,?,?,?) RETURNING created_at, updated_at`,
+		p.ID, p.ReservationID, p.Kind, p.Method, p.Provider, p.ProviderRef, p.Status, p.Amount, p.Currency, p.FailureReason).Scan(&p.CreatedAt, &p.UpdatedAt)
+}
+
+// Latest returns the most recent payment of a kind for a reservation.
+func (r *PaymentRepository) Latest(reservationID, kind string) (*models.Payment, error) {
+	return scanPayment(r.DB.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE reservation_id=? AND kind=? ORDER BY created_at DESC, rowid DESC LIMIT 1`, reservationID, kind))
+}
+
+func (r *PaymentRepository) GetByRef(provider, ref string) (*models.Payment, error) {
+	return scanPayment(r.DB.QueryRow(`SELECT `+paymentColumns+` FROM payments WHERE provider=? AND provider_ref=?`, provider, ref))
+}
+
+// Update saves a payment's status and amounts. It reports false when the
+// payment is no longer in status from, so a webhook and a staff action
+// racing on the same payment cannot both apply.
+func (r *PaymentRepository) Update(p *models.Payment, from string) (bool, error) {
+	p.UpdatedAt = time.Now().UTC()
+	res, err := r.DB.Exec(`UPDATE payments SET status=?, captured_minor=?, refunded_minor=?, failure_reason=?, updated_at=? WHERE id=? AND status=?`,
+		p.Status, p.Captured, p.Refunded, p.FailureReason, p.UpdatedAt, p.ID, from)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Captured totals what payments of a kind have taken, net of refunds, in
+// each currency.
+func (r *PaymentRepository) Captured(kind string) (map[string]models.Money, error) {
+	rows, err := r.DB.Query(`SELECT currency, SUM(captured_minor - refunded_minor) FROM payments WHERE kind=? AND captured_minor > 0 GROUP BY currency`, kind)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string]models.Money{}
+	for rows.Next() {
+		var currency string
+		var total models.Money
+		if err := rows.Scan(&currency, &total); err != nil {
+			return nil, err
+		}
+		out[currency] = total
+	}
+	return out, rows.Err()
+}
+
+// RecordEvent remembers a webhook delivery. It reports false when the event
+// was seen before.
+func (r *PaymentRepository) RecordEvent(provider, eventID, paymentID, status string) (bool, error) {
+	res, err := r.DB.Exec(`INSERT OR IGNORE INTO payment_events(provider, event_id, payment_id, status) VALUES(?,?,?,?)`, provider, eventID, paymentID, status)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ForgetEvent drops a recorded delivery whose processing failed, so the
+// gateway's retry is applied.
+func (r *PaymentRepository) ForgetEvent(provider, eventID string) error {
+	_, err := r.DB.Exec(`DELETE FROM payment_events WHERE provider=? AND event_id=?`, provider, eventID)
+	return err
+}
+
+// HeldForCancelled returns the latest rental payment of each of a user's
+// cancelled reservations that still holds or has taken money, leaving out
+// those that kept a cancellation fee.
+func (r *PaymentRepository) HeldForCancelled(userID string) ([]models.Payment, error) {
+	rows, err := r.DB.Query(`SELECT `+paymentColumns+` FROM payments p
+	WHERE p.kind='rental' AND p.status IN ('pending','authorized','captured','partially_refunded')
+	AND p.reservation_id IN (SELECT id FROM reservations WHERE user_id=? AND status='cancelled' AND COALESCE(cancellation_fee_minor, 0)=0)
+	AND p.rowid = (SELECT rowid FROM payments WHERE reservation_id=p.reservation_id AND kind='rental' ORDER BY created_at DESC, rowid DESC LIMIT 1)`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.Payment{}
+	for rows.Next() {
+		p, err := scanPayment(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
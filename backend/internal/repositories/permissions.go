@@ -0,0 +1,41 @@
+package repositories
+
+import "database/sql"
+
+type PermissionRepository struct{ DB *sql.DB }
+
+// All returns the stored permissions keyed by role.
+func (r *PermissionRepository) All() (map[string][]string, error) {
+	rows, err := r.DB.Query(`SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := map[string][]string{}
+	for rows.Next() {
+		var role, perm string
+		if err := rows.Scan(&role, &perm); err != nil {
+			return nil, err
+		}
+		out[role] = append(out[role], perm)
+	}
+	return out, rows.Err()
+}
+
+func (r *PermissionRepository) Replace(role string, perms []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role=?`, role); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, p := range perms {
+		if _, err := tx.Exec(`INSERT OR IGNORE INTO role_permissions(role, permission) VALUES(?,?)`, role, p); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
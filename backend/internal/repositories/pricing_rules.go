@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type PricingRuleRepository struct{ DB *sql.DB }
+
+const pricingRuleColumns = `id, name, kind, position, active, percent, amount_per_day_minor, currency, category, season_start, season_end, min_days, min_lead_days, max_lead_hours, max_driver_age, created_at`
+
+// List returns rules in evaluation order.
+func (r *PricingRuleRepository) List(activeOnly bool) ([]models.PricingRule, error) {
+	q := `SELECT ` + pricingRuleColumns + ` FROM pricing_rules`
+	if activeOnly {
+		q += ` WHERE active=1`
+	}
+	rows, err := r.DB.Query(q + ` ORDER BY position, created_at`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.PricingRule{}
+	for rows.Next() {
+		p, err := scanPricingRule(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+func scanPricingRule(row interface{ Scan(...interface{}) error }) (*models.PricingRule, error) {
+	var p models.PricingRule
+	if err := row.Scan(&p.ID, &p.Name, &p.Kind, &p.Position, &p.Active, &p.Percent, &p.AmountPerDay, &p.Currency, &p.Category, &p.SeasonStart, &p.SeasonEnd, &p.MinDays, &p.MinLeadDays, &p.MaxLeadHours, &p.MaxDriverAge, &p.CreatedAt); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (r *PricingRuleRepository) GetByID(id string) (*models.PricingRule, error) {
+	return scanPricingRule(r.DB.QueryRow(`SELECT `+pricingRuleColumns+` FROM pricing_rules WHERE id=?`, id))
+}
+func (r *PricingRuleRepository) Create(p *models.PricingRule) error {
+	p.ID = uuid.NewString()
+	_, err := r.DB.Exec(`INSERT INTO pricing_rules(id, name, kind, position, active, percent, amount_per_day_minor, currency, category, season_start, season_end, min_days, min_lead_days, max_lead_hours, max_driver_age)
+	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, p.ID, p.Name, p.Kind, p.Position, p.Active, p.Percent, p.AmountPerDay, p.Currency, p.Category, p.SeasonStart, p.SeasonEnd, p.MinDays, p.MinLeadDays, p.MaxLeadHours, p.MaxDriverAge)
+	return err
+}
+func (r *PricingRuleRepository) Update(id string, p *models.PricingRule) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE pricing_rules SET name=?, kind=?, position=?, active=?, percent=?, amount_per_day_minor=?, currency=?, category=?, season_start=?, season_end=?, min_days=?, min_lead_days=?, max_lead_hours=?, max_driver_age=? WHERE id=?`,
+		p.Name, p.Kind, p.Position, p.Active, p.Percent, p.AmountPerDay, p.Currency, p.Category, p.SeasonStart, p.SeasonEnd, p.MinDays, p.MinLeadDays, p.MaxLeadHours, p.MaxDriverAge, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+func (r *PricingRuleRepository) Delete(id string) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM pricing_rules WHERE id=?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
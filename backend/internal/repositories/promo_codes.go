@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+var ErrPromoCodeUsedUp = errors.New("promo code has been used up")
+
+type PromoCodeRepository struct{ DB *sql.DB }
+
+// Uses count bookings that still stand; cancelled and denied ones give
+// their use back.
+const promoCodeColumns = `p.id, p.code, p.kind, p.percent, p.amount_minor, p.currency, p.min_days, p.valid_from, p.valid_to, p.max_uses, p.max_uses_per_user, p.categories, p.active, p.created_at,
+	(SELECT COUNT(*) FROM reservations WHERE promo_code_id=p.id AND status NOT IN ('cancelled','denied')),
+	(SELECT COALESCE(SUM(discount_minor),0) FROM reservations WHERE promo_code_id=p.id AND status NOT IN ('cancelled','denied'))`
+
+func scanPromoCode(row interface{ Scan(...interface{}) error }) (*models.PromoCode, error) {
+	var p models.PromoCode
+	var from, to sql.NullTime
+	var categories string
+	if err := row.Scan(&p.ID, &p.Code, &p.Kind, &p.Percent, &p.Amount, &p.Currency, &p.MinDays, &from, &to, &p.MaxUses, &p.MaxUsesPerUser, &categories, &p.Active, &p.CreatedAt, &p.Uses, &p.Discounted); err != nil {
+		return nil, err
+	}
+	p.ValidFrom, p.ValidTo = nullTimePtr(from), nullTimePtr(to)
+	p.Categories = []string{}
+	_ = json.Unmarshal([]byte(categories), &p.Categories)
+	return &p, nil
+}
+
+func (r *PromoCodeRepository) List() ([]models.PromoCode, error) {
+	rows, err := r.DB.Query(`SELECT ` + promoCodeColumns + ` FROM promo_codes p ORDER BY p.created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.PromoCode{}
+	for rows.Next() {
+		p, err := scanPromoCode(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, *p)
+	}
+	return out, rows.Err()
+}
+
+// GetByCode looks a code up as customers type it; codes are stored in
+// upper case.
+func (r *PromoCodeRepository) GetByCode(code string) (*models.PromoCode, error) {
+	return scanPromoCode(r.DB.QueryRow(`SELECT `+promoCodeColumns+` FROM promo_codes p WHERE p.code=?`, code))
+}
+
+func categoriesJSON(categories []string) string {
+	if categories == nil {
+		categories = []string{}
+	}
+	b, _ := json.Marshal(categories)
+	return string(b)
+}
+
+func utcPtr(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC()
+}
+
+func (r *PromoCodeRepository) Create(p *models.PromoCode) error {
+	p.ID = uuid.NewString()
+	return r.DB.QueryRow(`INSERT INTO promo_codes(id, code, kind, percent, amount_minor, currency, min_days, valid_from, valid_to, max_uses, max_uses_per_user, categories, active)
+	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?) RETURNING created_at`, p.ID, p.Code, p.Kind, p.Percent, p.Amount, p.Currency, p.MinDays, utcPtr(p.ValidFrom), utcPtr(p.ValidTo), p.MaxUses, p.MaxUsesPerUser, categoriesJSON(p.Categories), p.Active).Scan(&p.CreatedAt)
+}
+
+func (r *PromoCodeRepository) Update(id string, p *models.PromoCode) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE promo_codes SET code=?, kind=?, percent=?, amount_minor=?, currency=?, min_days=?, valid_from=?, valid_to=?, max_uses=?, max_uses_per_user=?, categories=?, active=? WHERE id=?`,
+		p.Code, p.Kind, p.Percent, p.Amount, p.Currency, p.MinDays, utcPtr(p.ValidFrom), utcPtr(p.ValidTo), p.MaxUses, p.MaxUsesPerUser, categoriesJSON(p.Categories), p.Active, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *PromoCodeRepository) Delete(id string) (bool, error) {
+	res, err := r.DB.Exec(`DELETE FROM promo_codes WHERE id=?`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// checkPromoUses runs inside the booking transaction so two bookings cannot
+// both take a code's last use.
+func checkPromoUses(ctx context.Context, conn *sql.Conn, promoID, userID string) error {
+	var maxUses, maxPerUser, uses, byUser int
+	err := conn.QueryRowContext(ctx, `SELECT max_uses, max_uses_per_user,
+	(SELECT COUNT(*) FROM reservations WHERE promo_code_id=p.id AND status NOT IN ('cancelled','denied')),
+	(SELECT COUNT(*) FROM reservations WHERE promo_code_id=p.id AND user_id=? AND status NOT IN ('cancelled','denied'))
+	FROM promo_codes p WHERE p.id=?`, userID, promoID).Scan(&maxUses, &maxPerUser, &uses, &byUser)
+	if err != nil {
+		return err
+	}
+	if (maxUses > 0 && uses >= maxUses) || (maxPerUser > 0 && byUser >= maxPerUser) {
+		return ErrPromoCodeUsedUp
+	}
+	return nil
+}
+
+// UsesBy counts a user's bookings with a promo code that still stand.
+func (r *PromoCodeRepository) UsesBy(promoID, userID string) (int, error) {
+	var n int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM reservations WHERE promo_code_id=? AND user_id=? AND status NOT IN ('cancelled','denied')`, promoID, userID).Scan(&n)
+	return n, err
+}
@@ -1,9 +1,11 @@
 package repositories
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,43 +28,220 @@ func (r *UserRepository) Create(username, password, role string) (*models.User,
 	}
 	return &models.User{ID: id, Username: username, Role: role, CreatedAt: time.Now().UTC()}, nil
 }
-func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
-	row := r.DB.QueryRow(`SELECT id, username, password_hash, role, created_at FROM users WHERE username=?`, username)
+
+const userColumns = `id, username, password_hash, role, full_name, email, phone, date_of_birth, license_number, license_expiry, disabled_at, deleted_at, password_reset_required, created_at`
+
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+func scanUser(row interface{ Scan(...interface{}) error }) (*models.User, error) {
 	u := models.User{}
-	if err := row.Scan(&u.ID, &u.Username, &u.Password, &u.Role, &u.CreatedAt); err != nil {
+	var email sql.NullString
+	var dob, licenseExpiry, disabledAt, deletedAt sql.NullTime
+	if err := row.Scan(&u.ID, &u.Username, &u.Password, &u.Role, &u.FullName, &email, &u.Phone, &dob, &u.LicenseNumber, &licenseExpiry, &disabledAt, &deletedAt, &u.PasswordResetRequired, &u.CreatedAt); err != nil {
 		return nil, err
 	}
+	u.Email = email.String
+	u.DateOfBirth = nullTimePtr(dob)
+	u.LicenseExpiry = nullTimePtr(licenseExpiry)
+	u.DisabledAt = nullTimePtr(disabledAt)
+	u.DeletedAt = nullTimePtr(deletedAt)
 	return &u, nil
 }
+func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
+	return scanUser(r.DB.QueryRow(`SELECT `+userColumns+` FROM users WHERE username=?`, username))
+}
+func (r *UserRepository) FindByID(id string) (*models.User, error) {
+	return scanUser(r.DB.QueryRow(`SELECT `+userColumns+` FROM users WHERE id=?`, id))
+}
+func (r *UserRepository) FindByEmail(email string) (*models.User, error) {
+	return scanUser(r.DB.QueryRow(`SELECT `+userColumns+` FROM users WHERE email=?`, strings.ToLower(email)))
+}
+func (r *UserRepository) List(filters map[string]string, limit, offset int) ([]models.User, int, error) {
+	where := []string{"1=1"}
+	args := []interface{}{}
+	if v := filters["q"]; v != "" {
+		where = append(where, "LOWER(username) LIKE LOWER(?)")
+		args = append(args, "%"+v+"%")
+	}
+	if v := filters["role"]; v != "" {
+		where = append(where, "role=?")
+		args = append(args, v)
+	}
+	switch filters["status"] {
+	case "active":
+		where = append(where, "disabled_at IS NULL")
+	case "disabled":
+		where = append(where, "disabled_at IS NOT NULL")
+	}
+	w := strings.Join(where, " AND ")
+	var total int
+	if err := r.DB.QueryRow("SELECT COUNT(*) FROM users WHERE "+w, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+	rows, err := r.DB.Query("SELECT "+userColumns+" FROM users WHERE "+w+" ORDER BY created_at DESC LIMIT ? OFFSET ?", append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	out := []models.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		out = append(out, *u)
+	}
+	return out, total, nil
+}
+func (r *UserRepository) UpdateProfile(u *models.User) error {
+	var email interface{}
+	if u.Email != "" {
+		email = u.Email
+	}
+	_, err := r.DB.Exec(`UPDATE users SET full_name=?, email=?, phone=?, date_of_birth=?, license_number=?, license_expiry=? WHERE id=?`,
+		u.FullName, email, u.Phone, u.DateOfBirth, u.LicenseNumber, u.LicenseExpiry, u.ID)
+	return err
+}
+func (r *UserRepository) UpdatePassword(id, hash string) error {
+	_, err := r.DB.Exec(`UPDATE users SET password_hash=?, password_reset_required=0 WHERE id=?`, hash, id)
+	return err
+}
+
+// Anonymize scrubs personal data while keeping the row, so reservations and
+// reviews keep a valid user_id. Upcoming bookings must have been cancelled
+// first.
+func (r *UserRepository) Anonymize(id string) error {
+	now := time.Now().UTC()
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	stmts := []struct {
+		q    string
+		args []interface{}
+	}{
+		{`UPDATE users SET username='deleted-' || id, password_hash='', full_name='', email=NULL, phone='', date_of_birth=NULL, license_number='', license_expiry=NULL, disabled_at=COALESCE(disabled_at, ?), deleted_at=? WHERE id=?`, []interface{}{now, now, id}},
+		{`UPDATE reservations SET notes='' WHERE user_id=?`, []interface{}{id}},
+		{`UPDATE reviews SET comment='' WHERE user_id=?`, []interface{}{id}},
+		{`UPDATE sessions SET revoked_at=? WHERE user_id=? AND revoked_at IS NULL`, []interface{}{now, id}},
+		{`DELETE FROM totp_recovery_codes WHERE user_id=?`, []interface{}{id}},
+		{`DELETE FROM user_totp WHERE user_id=?`, []interface{}{id}},
+	}
+	for _, st := range stmts {
+		if _, err := tx.Exec(st.q, st.args...); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+func (r *UserRepository) UpdateRole(id, role string) error {
+	_, err := r.DB.Exec(`UPDATE users SET role=? WHERE id=?`, role, id)
+	return err
+}
+func (r *UserRepository) SetDisabled(id string, disabled bool) error {
+	var at interface{}
+	if disabled {
+		at = time.Now().UTC()
+	}
+	_, err := r.DB.Exec(`UPDATE users SET disabled_at=? WHERE id=?`, at, id)
+	return err
+}
+func (r *UserRepository) SetPasswordResetRequired(id string, required bool) error {
+	_, err := r.DB.Exec(`UPDATE users SET password_reset_required=? WHERE id=?`, required, id)
+	return err
+}
+func (r *UserRepository) CountActiveAdmins() (int, error) {
+	var c int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM users WHERE role='admin' AND disabled_at IS NULL`).Scan(&c)
+	return c, err
+}
+func (r *UserRepository) HasActiveRental(id string) (bool, error) {
+	var c int
+	err := r.DB.QueryRow(`SELECT COUNT(*) FROM reservations WHERE user_id=? AND status='active'`, id).Scan(&c)
+	return c > 0, err
+}
+func (r *UserRepository) HasHistory(id string) (bool, error) {
+	var c int
+	err := r.DB.QueryRow(`SELECT (SELECT COUNT(*) FROM reservations WHERE user_id=?) + (SELECT COUNT(*) FROM reviews WHERE user_id=?)`, id, id).Scan(&c)
+	return c > 0, err
+}
+func (r *UserRepository) Delete(id string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	for _, q := range []string{
+		`DELETE FROM session_spent_tokens WHERE session_id IN (SELECT id FROM sessions WHERE user_id=?)`,
+		`DELETE FROM sessions WHERE user_id=?`,
+		`DELETE FROM totp_recovery_codes WHERE user_id=?`,
+		`DELETE FROM user_totp WHERE user_id=?`,
+		`DELETE FROM users WHERE id=?`,
+	} {
+		if _, err := tx.Exec(q, id); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
 
 func (r *CarRepository) Create(car *models.Car) error {
 	car.ID = uuid.NewString()
 	img, _ := json.Marshal(car.Images)
-	_, err := r.DB.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price, status, mileage, description, images)
-	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`, car.ID, car.Brand, car.Model, car.Year, car.Category, car.Transmission, car.Fuel, car.Seats, car.DailyPrice, car.Status, car.Mileage, car.Description, string(img))
+	_, err := r.DB.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price_minor, currency, status, mileage, description, images, hourly_rates)
+	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, car.ID, car.Brand, car.Model, car.Year, car.Category, car.Transmission, car.Fuel, car.Seats, car.DailyPrice, car.Currency, car.Status, car.Mileage, car.Description, string(img), hourlyRatesJSON(car.HourlyRates))
 	return err
 }
 func (r *CarRepository) Update(id string, car *models.Car) error {
 	img, _ := json.Marshal(car.Images)
-	_, err := r.DB.Exec(`UPDATE cars SET brand=?, model=?, year=?, category=?, transmission=?, fuel=?, seats=?, daily_price=?, status=?, mileage=?, description=?, images=? WHERE id=?`,
-		car.Brand, car.Model, car.Year, car.Category, car.Transmission, car.Fuel, car.Seats, car.DailyPrice, car.Status, car.Mileage, car.Description, string(img), id)
+	_, err := r.DB.Exec(`UPDATE cars SET brand=?, model=?, year=?, category=?, transmission=?, fuel=?, seats=?, daily_price_minor=?, currency=?, status=?, mileage=?, description=?, images=?, hourly_rates=? WHERE id=?`,
+		car.Brand, car.Model, car.Year, car.Category, car.Transmission, car.Fuel, car.Seats, car.DailyPrice, car.Currency, car.Status, car.Mileage, car.Description, string(img), hourlyRatesJSON(car.HourlyRates), id)
 	return err
 }
 func (r *CarRepository) Delete(id string) error {
 	_, err := r.DB.Exec(`DELETE FROM cars WHERE id=?`, id)
 	return err
 }
-func (r *CarRepository) UpdateStatus(id, status string) error {
-	_, err := r.DB.Exec(`UPDATE cars SET status=? WHERE id=?`, status, id)
-	return err
+
+// storedHourlyRate keeps the hourly price in minor units inside the JSON
+// column, like every other stored amount.
+type storedHourlyRate struct {
+	MaxHours     int   `json:"maxHours"`
+	PricePerHour int64 `json:"pricePerHour"`
+}
+
+func hourlyRatesJSON(rates []models.HourlyRate) string {
+	if len(rates) == 0 {
+		return "[]"
+	}
+	stored := make([]storedHourlyRate, len(rates))
+	for i, t := range rates {
+		stored[i] = storedHourlyRate{MaxHours: t.MaxHours, PricePerHour: int64(t.PricePerHour)}
+	}
+	b, _ := json.Marshal(stored)
+	return string(b)
 }
 func scanCar(rows *sql.Rows) (models.Car, error) {
 	var c models.Car
-	var images string
-	err := rows.Scan(&c.ID, &c.Brand, &c.Model, &c.Year, &c.Category, &c.Transmission, &c.Fuel, &c.Seats, &c.DailyPrice, &c.Status, &c.Mileage, &c.Description, &images, &c.CreatedAt)
+	var images, rates string
+	err := rows.Scan(&c.ID, &c.Brand, &c.Model, &c.Year, &c.Category, &c.Transmission, &c.Fuel, &c.Seats, &c.DailyPrice, &c.Currency, &c.Status, &c.Mileage, &c.Description, &images, &rates, &c.CreatedAt)
 	if err == nil && images != "" {
 		_ = json.Unmarshal([]byte(images), &c.Images)
 	}
+	c.HourlyRates = []models.HourlyRate{}
+	var stored []storedHourlyRate
+	if err == nil && rates != "" {
+		_ = json.Unmarshal([]byte(rates), &stored)
+	}
+	for _, t := range stored {
+		c.HourlyRates = append(c.HourlyRates, models.HourlyRate{MaxHours: t.MaxHours, PricePerHour: models.Money(t.PricePerHour)})
+	}
 	return c, err
 }
 func (r *CarRepository) List(filters map[string]string, limit, offset int, sort string) ([]models.Car, int, error) {
@@ -90,13 +269,13 @@ func (r *CarRepository) List(filters map[string]string, limit, offset int, sort
 			args = append(args, term+"%", "% "+term+"%")
 		}
 	}
-	if v := filters["minPrice"]; v != "" {
-		where = append(where, "daily_price>=?")
-		args = append(args, v)
+	if v, err := strconv.ParseFloat(filters["minPrice"], 64); err == nil {
+		where = append(where, "daily_price_minor>=?")
+		args = append(args, models.MoneyFromFloat(v))
 	}
-	if v := filters["maxPrice"]; v != "" {
-		where = append(where, "daily_price<=?")
-		args = append(args, v)
+	if v, err := strconv.ParseFloat(filters["maxPrice"], 64); err == nil {
+		where = append(where, "daily_price_minor<=?")
+		args = append(args, models.MoneyFromFloat(v))
 	}
 	if v := filters["minYear"]; v != "" {
 		where = append(where, "year>=?")
@@ -118,11 +297,20 @@ func (r *CarRepository) List(filters map[string]string, limit, offset int, sort
 		where = append(where, "seats>=?")
 		args = append(args, v)
 	}
+	// from/to (RFC 3339) keep only cars that can be booked for the whole
+	// window; callers widen it by the turnaround buffer.
+	from, errFrom := time.Parse(time.RFC3339, filters["from"])
+	to, errTo := time.Parse(time.RFC3339, filters["to"])
+	if errFrom == nil && errTo == nil {
+		from, to = from.UTC(), to.UTC()
+		where = append(where, "status='available'", "NOT EXISTS (SELECT 1 FROM reservations r WHERE r.car_id=cars.id AND (r.status IN ('pending','approved','active') OR (r.status='awaiting_payment' AND r.hold_expires_at > ?)) AND r.start_date < ? AND r.end_date > ?)")
+		args = append(args, time.Now().UTC(), to, from)
+	}
 	order := "created_at DESC"
 	if sort == "price_asc" {
-		order = "daily_price ASC"
+		order = "daily_price_minor ASC"
 	} else if sort == "price_desc" {
-		order = "daily_price DESC"
+		order = "daily_price_minor DESC"
 	} else if sort == "year" {
 		order = "year DESC"
 	}
@@ -131,7 +319,7 @@ func (r *CarRepository) List(filters map[string]string, limit, offset int, sort
 	var total int
 	_ = countRow.Scan(&total)
 	args2 := append(args, limit, offset)
-	rows, err := r.DB.Query("SELECT id, brand, model, year, category, transmission, fuel, seats, daily_price, status, mileage, description, images, created_at FROM cars WHERE "+w+" ORDER BY "+order+" LIMIT ? OFFSET ?", args2...)
+	rows, err := r.DB.Query("SELECT id, brand, model, year, category, transmission, fuel, seats, daily_price_minor, currency, status, mileage, description, images, hourly_rates, created_at FROM cars WHERE "+w+" ORDER BY "+order+" LIMIT ? OFFSET ?", args2...)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -147,7 +335,7 @@ func (r *CarRepository) List(filters map[string]string, limit, offset int, sort
 	return out, total, nil
 }
 func (r *CarRepository) GetByID(id string) (*models.Car, error) {
-	rows, err := r.DB.Query("SELECT id, brand, model, year, category, transmission, fuel, seats, daily_price, status, mileage, description, images, created_at FROM cars WHERE id=?", id)
+	rows, err := r.DB.Query("SELECT id, brand, model, year, category, transmission, fuel, seats, daily_price_minor, currency, status, mileage, description, images, hourly_rates, created_at FROM cars WHERE id=?", id)
 	if err != nil {
 		return nil, err
 	}
@@ -163,7 +351,7 @@ func (r *CarRepository) GetByID(id string) (*models.Car, error) {
 }
 
 func (r *ExtraRepository) List() ([]models.Extra, error) {
-	rows, err := r.DB.Query(`SELECT id, name, price_per_day FROM extras ORDER BY name`)
+	rows, err := r.DB.Query(`SELECT id, name, price_per_day_minor, currency FROM extras ORDER BY name`)
 	if err != nil {
 		return nil, err
 	}
@@ -171,7 +359,7 @@ func (r *ExtraRepository) List() ([]models.Extra, error) {
 	out := []models.Extra{}
 	for rows.Next() {
 		var e models.Extra
-		if err := rows.Scan(&e.ID, &e.Name, &e.PricePerDay); err != nil {
+		if err := rows.Scan(&e.ID, &e.Name, &e.PricePerDay, &e.Currency); err != nil {
 			return nil, err
 		}
 		out = append(out, e)
@@ -188,7 +376,7 @@ func (r *ExtraRepository) ByIDs(ids []string) ([]models.Extra, error) {
 	for i, v := range ids {
 		args[i] = v
 	}
-	rows, err := r.DB.Query("SELECT id,name,price_per_day FROM extras WHERE id IN ("+ph+")", args...)
+	rows, err := r.DB.Query("SELECT id,name,price_per_day_minor,currency FROM extras WHERE id IN ("+ph+")", args...)
 	if err != nil {
 		return nil, err
 	}
@@ -196,48 +384,171 @@ func (r *ExtraRepository) ByIDs(ids []string) ([]models.Extra, error) {
 	out := []models.Extra{}
 	for rows.Next() {
 		var e models.Extra
-		_ = rows.Scan(&e.ID, &e.Name, &e.PricePerDay)
+		_ = rows.Scan(&e.ID, &e.Name, &e.PricePerDay, &e.Currency)
 		out = append(out, e)
 	}
 	return out, nil
 }
 
-func (r *ReservationRepository) Create(res *models.Reservation, extraIDs []string) error {
+func insertReservation(ctx context.Context, conn *sql.Conn, res *models.Reservation, extraIDs []string) error {
 	res.ID = uuid.NewString()
-	tx, _ := r.DB.Begin()
-	_, err := tx.Exec(`INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price)
-	VALUES(?,?,?,?,?,?,?,?,?,?)`, res.ID, res.CarID, res.UserID, res.StartDate, res.EndDate, res.PickupLocation, res.DropoffLocation, res.Notes, res.Status, res.TotalPrice)
-	if err != nil {
-		_ = tx.Rollback()
+	if res.Timezone == "" {
+		res.Timezone = "UTC"
+	}
+	var promoID interface{}
+	if res.PromoCodeID != "" {
+		promoID = res.PromoCodeID
+	}
+	if _, err := conn.ExecContext(ctx, `INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price_minor, currency, timezone, cancellation_policy, promo_code_id, promo_code, discount_minor, hold_expires_at)
+	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?,?)`, res.ID, res.CarID, res.UserID, res.StartDate.UTC(), res.EndDate.UTC(), res.PickupLocation, res.DropoffLocation, res.Notes, res.Status, res.TotalPrice, res.Currency, res.Timezone, cancellationPolicyJSON(res.CancellationPolicy), promoID, res.PromoCode, res.Discount, utcPtr(res.HoldExpiresAt)); err != nil {
 		return err
 	}
 	for _, e := range extraIDs {
-		_, err = tx.Exec(`INSERT INTO reservation_extras(reservation_id, extra_id) VALUES(?,?)`, res.ID, e)
-		if err != nil {
-			_ = tx.Rollback()
+		if _, err := conn.ExecContext(ctx, `INSERT INTO reservation_extras(reservation_id, extra_id) VALUES(?,?)`, res.ID, e); err != nil {
 			return err
 		}
 	}
-	return tx.Commit()
+	for i, li := range res.LineItems {
+		if _, err := conn.ExecContext(ctx, `INSERT INTO reservation_line_items(id, reservation_id, position, kind, code, label, quantity, unit_price_minor, amount_minor) VALUES(?,?,?,?,?,?,?,?,?)`,
+			uuid.NewString(), res.ID, i, li.Kind, li.Code, li.Label, li.Quantity, li.UnitPrice, li.Amount); err != nil {
+			return err
+		}
+	}
+	return nil
 }
-func (r *ReservationRepository) HasOverlap(carID string, start, end time.Time) (bool, error) {
-	row := r.DB.QueryRow(`SELECT COUNT(*) FROM reservations WHERE car_id=? AND status IN ('pending','approved','active') AND NOT (end_date <= ? OR start_date >= ?)`, carID, start, end)
-	var c int
-	err := row.Scan(&c)
-	return c > 0, err
+
+// CreateIfAvailable runs the overlap check and the insert in one
+// BEGIN IMMEDIATE transaction. SQLite hands the write lock out at BEGIN, so
+// concurrent bookings are serialised and the check cannot go stale before
+// the insert. Existing bookings must end at least buffer before the new one
+// starts and start at least buffer after it ends. It reports false when the
+// car is already taken, and returns ErrPromoCodeUsedUp when the booking's
+// promo code has no uses left.
+func (r *ReservationRepository) CreateIfAvailable(res *models.Reservation, extraIDs []string, buffer time.Duration) (bool, error) {
+	ctx := context.Background()
+	conn, err := r.DB.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, `BEGIN IMMEDIATE`); err != nil {
+		return false, err
+	}
+	rollback := func() { _, _ = conn.ExecContext(ctx, `ROLLBACK`) }
+	var n int
+	if err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM reservations WHERE car_id=? AND `+blockingStatus+` AND NOT (end_date <= ? OR start_date >= ?)`, res.CarID, time.Now().UTC(), res.StartDate.Add(-buffer).UTC(), res.EndDate.Add(buffer).UTC()).Scan(&n); err != nil {
+		rollback()
+		return false, err
+	}
+	if n > 0 {
+		rollback()
+		return false, nil
+	}
+	if res.PromoCodeID != "" {
+		if err := checkPromoUses(ctx, conn, res.PromoCodeID, res.UserID); err != nil {
+			rollback()
+			return false, err
+		}
+	}
+	if err := insertReservation(ctx, conn, res, extraIDs); err != nil {
+		rollback()
+		return false, err
+	}
+	if _, err := conn.ExecContext(ctx, `COMMIT`); err != nil {
+		rollback()
+		return false, err
+	}
+	return true, nil
 }
-func (r *ReservationRepository) UpdateStatus(id, status string) error {
-	_, err := r.DB.Exec(`UPDATE reservations SET status=? WHERE id=?`, status, id)
-	return err
+
+// blockingStatus matches reservations that keep a car from being booked:
+// everything still going ahead, and bookings awaiting payment until their
+// hold expires. It takes the current time as its argument.
+const blockingStatus = `(status IN ('pending','approved','active') OR (status='awaiting_payment' AND hold_expires_at > ?))`
+
+// ExpiredHolds lists bookings still awaiting payment after their hold ran
+// out.
+func (r *ReservationRepository) ExpiredHolds(now time.Time) ([]string, error) {
+	return r.ids(`SELECT id FROM reservations WHERE status='awaiting_payment' AND (hold_expires_at IS NULL OR hold_expires_at <= ?)`, now.UTC())
 }
-func (r *ReservationRepository) HasBlockingForCar(carID string) (bool, error) {
-	row := r.DB.QueryRow(`SELECT COUNT(*) FROM reservations WHERE car_id=? AND status IN ('pending','approved','active')`, carID)
-	var c int
-	err := row.Scan(&c)
-	return c > 0, err
+
+// UpcomingIDs lists a user's bookings that have not been picked up yet.
+func (r *ReservationRepository) UpcomingIDs(userID string) ([]string, error) {
+	return r.ids(`SELECT id FROM reservations WHERE user_id=? AND status IN ('awaiting_payment','pending','approved')`, userID)
+}
+
+func (r *ReservationRepository) ids(q string, args ...interface{}) ([]string, error) {
+	rows, err := r.DB.Query(q, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out = append(out, id)
+	}
+	return out, rows.Err()
+}
+
+// statusTimestamps names the column stamped when a reservation enters a status.
+var statusTimestamps = map[string]string{
+	"approved":  "approved_at",
+	"active":    "picked_up_at",
+	"completed": "returned_at",
+	"cancelled": "cancelled_at",
+}
+
+// Transition moves a reservation from one status to another and stamps the
+// matching timestamp. It reports false when the reservation is no longer in
+// the expected status, so two concurrent updates cannot both apply.
+func (r *ReservationRepository) Transition(id, from, to string, at time.Time) (bool, error) {
+	q := `UPDATE reservations SET status=?`
+	args := []interface{}{to}
+	if col, ok := statusTimestamps[to]; ok {
+		q += `, ` + col + `=?`
+		args = append(args, at)
+	}
+	q += ` WHERE id=? AND status=?`
+	args = append(args, id, from)
+	res, err := r.DB.Exec(q, args...)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// OccupiedCarIDs returns which of the given cars have a blocking
+// reservation overlapping [from, to).
+func (r *ReservationRepository) OccupiedCarIDs(carIDs []string, from, to time.Time) (map[string]bool, error) {
+	out := map[string]bool{}
+	if len(carIDs) == 0 {
+		return out, nil
+	}
+	args := []interface{}{time.Now().UTC(), to.UTC(), from.UTC()}
+	for _, id := range carIDs {
+		args = append(args, id)
+	}
+	rows, err := r.DB.Query(`SELECT DISTINCT car_id FROM reservations WHERE `+blockingStatus+` AND start_date < ? AND end_date > ? AND car_id IN (?`+strings.Repeat(",?", len(carIDs)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		out[id] = true
+	}
+	return out, rows.Err()
 }
 func (r *ReservationRepository) ListBlockedRangesByCar(carID string) ([]models.Reservation, error) {
-	rows, err := r.DB.Query(`SELECT id,car_id,user_id,start_date,end_date,pickup_location,dropoff_location,notes,status,total_price,created_at FROM reservations WHERE car_id=? AND status IN ('pending','approved','active') ORDER BY start_date ASC`, carID)
+	rows, err := r.DB.Query(`SELECT id,car_id,user_id,start_date,end_date,pickup_location,dropoff_location,notes,status,total_price_minor,currency,timezone,created_at FROM reservations WHERE car_id=? AND `+blockingStatus+` ORDER BY start_date ASC`, carID, time.Now().UTC())
 	if err != nil {
 		return nil, err
 	}
@@ -245,15 +556,69 @@ func (r *ReservationRepository) ListBlockedRangesByCar(carID string) ([]models.R
 	out := []models.Reservation{}
 	for rows.Next() {
 		var re models.Reservation
-		if err := rows.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.CreatedAt); err != nil {
+		if err := rows.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.Currency, &re.Timezone, &re.CreatedAt); err != nil {
 			return nil, err
 		}
+		inZone(&re)
 		out = append(out, re)
 	}
 	return out, nil
 }
+
+type reservationStamps struct {
+	approved, pickedUp, returned, cancelled sql.NullTime
+	hold                                    sql.NullTime
+	policy                                  sql.NullString
+	fee, refund                             sql.NullInt64
+}
+
+// inZone renders the rental period in the pickup branch's time zone. Times
+// are stored in UTC and only converted for display.
+func inZone(re *models.Reservation) {
+	loc, err := time.LoadLocation(re.Timezone)
+	if err != nil {
+		return
+	}
+	re.StartDate = re.StartDate.In(loc)
+	re.EndDate = re.EndDate.In(loc)
+}
+
+func (s reservationStamps) apply(re *models.Reservation) {
+	re.ApprovedAt = nullTimePtr(s.approved)
+	re.PickedUpAt = nullTimePtr(s.pickedUp)
+	re.ReturnedAt = nullTimePtr(s.returned)
+	re.CancelledAt = nullTimePtr(s.cancelled)
+	re.HoldExpiresAt = nullTimePtr(s.hold)
+	if s.policy.Valid {
+		var p models.CancellationPolicy
+		if json.Unmarshal([]byte(s.policy.String), &p) == nil {
+			re.CancellationPolicy = &p
+		}
+	}
+	if s.fee.Valid {
+		fee, refund := models.Money(s.fee.Int64), models.Money(s.refund.Int64)
+		re.CancellationFee, re.Refund = &fee, &refund
+	}
+}
+
+// cancellationPolicyJSON copies the terms a booking is made under; which
+// categories the policy covered does not matter to it.
+func cancellationPolicyJSON(p *models.CancellationPolicy) interface{} {
+	if p == nil {
+		return nil
+	}
+	b, _ := json.Marshal(models.CancellationPolicy{ID: p.ID, Name: p.Name, Tiers: p.Tiers, IsDefault: p.IsDefault, CreatedAt: p.CreatedAt})
+	return string(b)
+}
+
+// SetCancellation records what cancelling a reservation cost.
+func (r *ReservationRepository) SetCancellation(id string, fee, refund models.Money) error {
+	_, err := r.DB.Exec(`UPDATE reservations SET cancellation_fee_minor=?, refund_minor=? WHERE id=?`, fee, refund, id)
+	return err
+}
+
 func (r *ReservationRepository) List(userID string, all bool) ([]models.Reservation, error) {
-	q := `SELECT r.id,r.car_id,r.user_id,r.start_date,r.end_date,r.pickup_location,r.dropoff_location,r.notes,r.status,r.total_price,r.created_at,u.username,c.brand,c.model,c.daily_price
+	q := `SELECT r.id,r.car_id,r.user_id,r.start_date,r.end_date,r.pickup_location,r.dropoff_location,r.notes,r.status,r.total_price_minor,r.currency,r.timezone,r.approved_at,r.picked_up_at,r.returned_at,r.cancelled_at,r.cancellation_policy,r.cancellation_fee_minor,r.refund_minor,r.promo_code,r.discount_minor,r.hold_expires_at,r.created_at,u.username,c.brand,c.model,c.daily_price_minor,c.currency
 	FROM reservations r JOIN users u ON u.id=r.user_id JOIN cars c ON c.id=r.car_id`
 	args := []interface{}{}
 	if !all {
@@ -270,25 +635,34 @@ func (r *ReservationRepository) List(userID string, all bool) ([]models.Reservat
 	for rows.Next() {
 		var re models.Reservation
 		var car models.Car
-		if err := rows.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.CreatedAt, &re.Username, &car.Brand, &car.Model, &car.DailyPrice); err != nil {
+		var stamps reservationStamps
+		if err := rows.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.Currency, &re.Timezone, &stamps.approved, &stamps.pickedUp, &stamps.returned, &stamps.cancelled, &stamps.policy, &stamps.fee, &stamps.refund, &re.PromoCode, &re.Discount, &stamps.hold, &re.CreatedAt, &re.Username, &car.Brand, &car.Model, &car.DailyPrice, &car.Currency); err != nil {
 			return nil, err
 		}
+		stamps.apply(&re)
+		inZone(&re)
 		re.Car = &car
 		out = append(out, re)
 	}
 	return out, nil
 }
 func (r *ReservationRepository) GetByID(id string) (*models.Reservation, error) {
-	row := r.DB.QueryRow(`SELECT id,car_id,user_id,start_date,end_date,pickup_location,dropoff_location,notes,status,total_price,created_at FROM reservations WHERE id=?`, id)
+	row := r.DB.QueryRow(`SELECT id,car_id,user_id,start_date,end_date,pickup_location,dropoff_location,notes,status,total_price_minor,currency,timezone,approved_at,picked_up_at,returned_at,cancelled_at,cancellation_policy,cancellation_fee_minor,refund_minor,promo_code,discount_minor,hold_expires_at,created_at FROM reservations WHERE id=?`, id)
 	var re models.Reservation
-	if err := row.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.CreatedAt); err != nil {
+	var stamps reservationStamps
+	if err := row.Scan(&re.ID, &re.CarID, &re.UserID, &re.StartDate, &re.EndDate, &re.PickupLocation, &re.DropoffLocation, &re.Notes, &re.Status, &re.TotalPrice, &re.Currency, &re.Timezone, &stamps.approved, &stamps.pickedUp, &stamps.returned, &stamps.cancelled, &stamps.policy, &stamps.fee, &stamps.refund, &re.PromoCode, &re.Discount, &stamps.hold, &re.CreatedAt); err != nil {
 		return nil, err
 	}
+	stamps.apply(&re)
+	inZone(&re)
 	return &re, nil
 }
+
+// Metrics summarises the fleet and bookings. Revenue is the money actually
+// captured through payments, net of refunds.
 func (r *ReservationRepository) Metrics() (map[string]float64, error) {
 	m := map[string]float64{}
-	queries := map[string]string{"totalCars": "SELECT COUNT(*) FROM cars", "availableCars": "SELECT COUNT(*) FROM cars WHERE status='available'", "activeRentals": "SELECT COUNT(*) FROM reservations WHERE status='active'", "pendingReservations": "SELECT COUNT(*) FROM reservations WHERE status='pending'", "revenue": "SELECT COALESCE(SUM(total_price),0) FROM reservations WHERE status IN ('completed','active','approved')"}
+	queries := map[string]string{"totalCars": "SELECT COUNT(*) FROM cars", "availableCars": "SELECT COUNT(*) FROM cars WHERE status='available'", "activeRentals": "SELECT COUNT(*) FROM reservations WHERE status='active'", "pendingReservations": "SELECT COUNT(*) FROM reservations WHERE status='pending'"}
 	for k, q := range queries {
 		var v float64
 		if err := r.DB.QueryRow(q).Scan(&v); err != nil {
@@ -298,8 +672,14 @@ func (r *ReservationRepository) Metrics() (map[string]float64, error) {
 	}
 	return m, nil
 }
+
+// ExtrasForReservation returns the extras with the name and daily price
+// they were booked at, falling back to the current ones for reservations
+// made before line items were kept.
 func (r *ReservationRepository) ExtrasForReservation(resID string) ([]models.Extra, error) {
-	rows, err := r.DB.Query(`SELECT e.id,e.name,e.price_per_day FROM reservation_extras re JOIN extras e ON e.id=re.extra_id WHERE re.reservation_id=?`, resID)
+	rows, err := r.DB.Query(`SELECT e.id, COALESCE(li.label, e.name), COALESCE(li.unit_price_minor, e.price_per_day_minor), CASE WHEN li.id IS NULL THEN e.currency ELSE r.currency END
+	FROM reservation_extras re JOIN extras e ON e.id=re.extra_id JOIN reservations r ON r.id=re.reservation_id
+	LEFT JOIN reservation_line_items li ON li.reservation_id=re.reservation_id AND li.kind='extra' AND li.code=re.extra_id WHERE re.reservation_id=?`, resID)
 	if err != nil {
 		return nil, err
 	}
@@ -307,12 +687,30 @@ func (r *ReservationRepository) ExtrasForReservation(resID string) ([]models.Ext
 	out := []models.Extra{}
 	for rows.Next() {
 		var e models.Extra
-		_ = rows.Scan(&e.ID, &e.Name, &e.PricePerDay)
+		_ = rows.Scan(&e.ID, &e.Name, &e.PricePerDay, &e.Currency)
 		out = append(out, e)
 	}
 	return out, nil
 }
 
+// LineItems returns what a reservation was charged, in booking order.
+func (r *ReservationRepository) LineItems(resID string) ([]models.LineItem, error) {
+	rows, err := r.DB.Query(`SELECT kind, code, label, quantity, unit_price_minor, amount_minor FROM reservation_line_items WHERE reservation_id=? ORDER BY position`, resID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []models.LineItem{}
+	for rows.Next() {
+		var li models.LineItem
+		if err := rows.Scan(&li.Kind, &li.Code, &li.Label, &li.Quantity, &li.UnitPrice, &li.Amount); err != nil {
+			return nil, err
+		}
+		out = append(out, li)
+	}
+	return out, rows.Err()
+}
+
 func (r *AuditLogRepository) Create(actorID, actorName, action, entity, entityID, details string) error {
 	_, err := r.DB.Exec(`INSERT INTO audit_logs(id, actor_id, actor_name, action, entity, entity_id, details) VALUES(?,?,?,?,?,?,?)`,
 		uuid.NewString(), actorID, actorName, action, entity, entityID, details)
@@ -371,6 +769,28 @@ func (r *ReviewRepository) ListByCar(carID string) ([]models.Review, float64, in
 	return items, avgRating, total, nil
 }
 
+func (r *ReviewRepository) ListByUser(userID string) ([]models.Review, error) {
+	rows, err := r.DB.Query(`
+		SELECT rv.id, rv.car_id, rv.user_id, u.username, rv.rating, COALESCE(rv.comment, ''), rv.created_at
+		FROM reviews rv
+		JOIN users u ON u.id = rv.user_id
+		WHERE rv.user_id=?
+		ORDER BY rv.created_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []models.Review{}
+	for rows.Next() {
+		var it models.Review
+		if err := rows.Scan(&it.ID, &it.CarID, &it.UserID, &it.Username, &it.Rating, &it.Comment, &it.CreatedAt); err != nil {
+			return nil, err
+		}
+		items = append(items, it)
+	}
+	return items, nil
+}
+
 func (r *ReviewRepository) Create(carID, userID string, rating int, comment string) error {
 	_, err := r.DB.Exec(`INSERT INTO reviews(id, car_id, user_id, rating, comment) VALUES(?,?,?,?,?)`,
 		uuid.NewString(), carID, userID, rating, comment)
@@ -0,0 +1,83 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type SessionRepository struct{ DB *sql.DB }
+
+const sessionColumns = `id, user_id, refresh_token_hash, user_agent, ip, expires_at, revoked_at, last_used_at, mfa_verified, created_at`
+
+func scanSession(row interface{ Scan(...interface{}) error }) (*models.Session, error) {
+	var s models.Session
+	var revokedAt, lastUsedAt sql.NullTime
+	if err := row.Scan(&s.ID, &s.UserID, &s.TokenHash, &s.UserAgent, &s.IP, &s.ExpiresAt, &revokedAt, &lastUsedAt, &s.MFAVerified, &s.CreatedAt); err != nil {
+		return nil, err
+	}
+	if revokedAt.Valid {
+		s.RevokedAt = &revokedAt.Time
+	}
+	if lastUsedAt.Valid {
+		s.LastUsedAt = &lastUsedAt.Time
+	}
+	return &s, nil
+}
+
+func (r *SessionRepository) Create(s *models.Session) error {
+	s.ID = uuid.NewString()
+	s.CreatedAt = time.Now().UTC()
+	_, err := r.DB.Exec(`INSERT INTO sessions(id, user_id, refresh_token_hash, user_agent, ip, expires_at, mfa_verified, created_at) VALUES(?,?,?,?,?,?,?,?)`,
+		s.ID, s.UserID, s.TokenHash, s.UserAgent, s.IP, s.ExpiresAt, s.MFAVerified, s.CreatedAt)
+	return err
+}
+func (r *SessionRepository) GetByID(id string) (*models.Session, error) {
+	return scanSession(r.DB.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id=?`, id))
+}
+func (r *SessionRepository) FindByTokenHash(hash string) (*models.Session, error) {
+	return scanSession(r.DB.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE refresh_token_hash=?`, hash))
+}
+
+// FindBySpentTokenHash finds the session a refresh token was rotated away
+// from, however many rotations ago.
+func (r *SessionRepository) FindBySpentTokenHash(hash string) (*models.Session, error) {
+	return scanSession(r.DB.QueryRow(`SELECT `+sessionColumns+` FROM sessions WHERE id=(SELECT session_id FROM session_spent_tokens WHERE token_hash=?)`, hash))
+}
+
+// Rotate swaps the refresh token of an active session and remembers the old
+// one as spent. It reports false when the session was revoked or rotated
+// concurrently.
+func (r *SessionRepository) Rotate(id, oldHash, newHash string, expiresAt time.Time) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.Exec(`UPDATE sessions SET refresh_token_hash=?, expires_at=?, last_used_at=? WHERE id=? AND refresh_token_hash=? AND revoked_at IS NULL`,
+		newHash, expiresAt, time.Now().UTC(), id, oldHash)
+	if err != nil {
+		return false, err
+	}
+	if n, err := res.RowsAffected(); err != nil || n != 1 {
+		return false, err
+	}
+	if _, err := tx.Exec(`INSERT INTO session_spent_tokens(token_hash, session_id) VALUES(?,?)`, oldHash, id); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
+func (r *SessionRepository) Revoke(id string) error {
+	_, err := r.DB.Exec(`UPDATE sessions SET revoked_at=? WHERE id=? AND revoked_at IS NULL`, time.Now().UTC(), id)
+	return err
+}
+func (r *SessionRepository) RevokeAllForUser(userID string) error {
+	_, err := r.DB.Exec(`UPDATE sessions SET revoked_at=? WHERE user_id=? AND revoked_at IS NULL`, time.Now().UTC(), userID)
+	return err
+}
+func (r *SessionRepository) RevokeAllForUserExcept(userID, keepID string) error {
+	_, err := r.DB.Exec(`UPDATE sessions SET revoked_at=? WHERE user_id=? AND id<>? AND revoked_at IS NULL`, time.Now().UTC(), userID, keepID)
+	return err
+}
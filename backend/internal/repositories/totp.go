@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/models"
+)
+
+type TOTPRepository struct{ DB *sql.DB }
+
+func (r *TOTPRepository) Get(userID string) (*models.TOTP, error) {
+	var t models.TOTP
+	var enabledAt sql.NullTime
+	if err := r.DB.QueryRow(`SELECT user_id, secret, enabled_at, last_used_step, created_at FROM user_totp WHERE user_id=?`, userID).Scan(&t.UserID, &t.Secret, &enabledAt, &t.LastUsedStep, &t.CreatedAt); err != nil {
+		return nil, err
+	}
+	t.EnabledAt = nullTimePtr(enabledAt)
+	return &t, nil
+}
+
+// SetPending stores a new, not yet confirmed secret. An enabled secret is
+// never overwritten; it has to be disabled first.
+func (r *TOTPRepository) SetPending(userID, secret string) error {
+	_, err := r.DB.Exec(`INSERT INTO user_totp(user_id, secret) VALUES(?, ?)
+	ON CONFLICT(user_id) DO UPDATE SET secret=excluded.secret, last_used_step=0, created_at=CURRENT_TIMESTAMP
+	WHERE user_totp.enabled_at IS NULL`, userID, secret)
+	return err
+}
+
+// Enable confirms the pending secret and stores the first set of recovery
+// codes in one transaction.
+func (r *TOTPRepository) Enable(userID string, step int64, codeHashes []string) (bool, error) {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return false, err
+	}
+	res, err := tx.Exec(`UPDATE user_totp SET enabled_at=?, last_used_step=? WHERE user_id=? AND enabled_at IS NULL`, time.Now().UTC(), step, userID)
+	if err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		_ = tx.Rollback()
+		return false, nil
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		_ = tx.Rollback()
+		return false, err
+	}
+	return true, tx.Commit()
+}
+
+// UseStep records a verified time step. It fails when the step (or a later
+// one) was already used, so a code cannot be replayed within its window.
+func (r *TOTPRepository) UseStep(userID string, step int64) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE user_totp SET last_used_step=? WHERE user_id=? AND last_used_step < ?`, step, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+func (r *TOTPRepository) Delete(userID string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	for _, q := range []string{`DELETE FROM totp_recovery_codes WHERE user_id=?`, `DELETE FROM user_totp WHERE user_id=?`} {
+		if _, err := tx.Exec(q, userID); err != nil {
+			_ = tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *TOTPRepository) ReplaceRecoveryCodes(userID string, codeHashes []string) error {
+	tx, err := r.DB.Begin()
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(tx, userID, codeHashes); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceRecoveryCodes(tx *sql.Tx, userID string, codeHashes []string) error {
+	if _, err := tx.Exec(`DELETE FROM totp_recovery_codes WHERE user_id=?`, userID); err != nil {
+		return err
+	}
+	for _, h := range codeHashes {
+		if _, err := tx.Exec(`INSERT INTO totp_recovery_codes(id, user_id, code_hash) VALUES(?,?,?)`, uuid.NewString(), userID, h); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode burns a matching unused code and reports whether one was found.
+func (r *TOTPRepository) UseRecoveryCode(userID, codeHash string) (bool, error) {
+	res, err := r.DB.Exec(`UPDATE totp_recovery_codes SET used_at=? WHERE user_id=? AND code_hash=? AND used_at IS NULL`, time.Now().UTC(), userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (r *TOTPRepository) CountRecoveryCodes(userID string) (int, error) {
+	var n int
+	err := r.DB.QueryRow(`SELECT COUNT(1) FROM totp_recovery_codes WHERE user_id=? AND used_at IS NULL`, userID).Scan(&n)
+	return n, err
+}
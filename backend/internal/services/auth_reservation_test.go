@@ -3,7 +3,6 @@ package services
 import (
 	"database/sql"
 	"errors"
-	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -13,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	_ "github.com/mattn/go-sqlite3"
 	"golang.org/x/crypto/bcrypt"
+	"rentacar/backend/internal/migrations"
 	"rentacar/backend/internal/models"
 	"rentacar/backend/internal/repositories"
 )
@@ -39,28 +39,18 @@ func runMigrationsForTest(t *testing.T, db *sql.DB) {
 		t.Fatal("runtime.Caller failed")
 	}
 	root := filepath.Join(filepath.Dir(thisFile), "..", "..")
-	files := []string{
-		filepath.Join(root, "migrations", "001_init.sql"),
-		filepath.Join(root, "migrations", "002_audit_logs.sql"),
-		filepath.Join(root, "migrations", "003_reviews.sql"),
-	}
-	for _, f := range files {
-		sqlBytes, err := os.ReadFile(f)
-		if err != nil {
-			t.Fatalf("read migration %s: %v", f, err)
-		}
-		if _, err := db.Exec(string(sqlBytes)); err != nil {
-			t.Fatalf("exec migration %s: %v", f, err)
-		}
+	m := &migrations.Migrator{DB: db, Dir: filepath.Join(root, "migrations")}
+	if _, err := m.Up(); err != nil {
+		t.Fatalf("apply migrations: %v", err)
 	}
 }
 
 func insertTestCar(t *testing.T, db *sql.DB) string {
 	t.Helper()
 	carID := uuid.NewString()
-	_, err := db.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price, status, mileage, description, images)
+	_, err := db.Exec(`INSERT INTO cars(id, brand, model, year, category, transmission, fuel, seats, daily_price_minor, status, mileage, description, images)
 	VALUES(?,?,?,?,?,?,?,?,?,?,?,?,?)`,
-		carID, "Toyota", "Corolla", 2022, "sedan", "automatic", "gasoline", 5, 50.0, "available", 10000, "test car", "[]")
+		carID, "Toyota", "Corolla", 2022, "sedan", "automatic", "gasoline", 5, 5000, "available", 10000, "test car", "[]")
 	if err != nil {
 		t.Fatalf("insert car: %v", err)
 	}
@@ -83,9 +73,14 @@ func insertTestUser(t *testing.T, db *sql.DB) string {
 
 func insertReservationWithStatus(t *testing.T, db *sql.DB, carID, userID, status string, start, end time.Time) {
 	t.Helper()
-	_, err := db.Exec(`INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price)
-	VALUES(?,?,?,?,?,?,?,?,?,?)`,
-		uuid.NewString(), carID, userID, start, end, "A", "B", "", status, 100.0)
+	// Bookings awaiting payment only hold the car while their hold lasts.
+	var hold interface{}
+	if status == "awaiting_payment" {
+		hold = time.Now().UTC().Add(time.Hour)
+	}
+	_, err := db.Exec(`INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price_minor, hold_expires_at)
+	VALUES(?,?,?,?,?,?,?,?,?,?,?)`,
+		uuid.NewString(), carID, userID, start, end, "A", "B", "", status, 10000, hold)
 	if err != nil {
 		t.Fatalf("insert reservation (%s): %v", status, err)
 	}
@@ -95,6 +90,7 @@ func TestAuthRegisterStoresBcryptHash(t *testing.T) {
 	db := newTestDB(t)
 	svc := &AuthService{
 		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
 		JWTSecret: "test-secret",
 	}
 
@@ -121,6 +117,7 @@ func TestAuthLoginSuccessAndWrongPassword(t *testing.T) {
 	db := newTestDB(t)
 	svc := &AuthService{
 		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
 		JWTSecret: "test-secret",
 	}
 
@@ -130,18 +127,18 @@ func TestAuthLoginSuccessAndWrongPassword(t *testing.T) {
 		t.Fatalf("register failed: %v", err)
 	}
 
-	token, user, err := svc.Login(username, password)
+	tokens, user, err := svc.Login(username, password, ClientInfo{})
 	if err != nil {
 		t.Fatalf("login with correct credentials failed: %v", err)
 	}
-	if token == "" {
+	if tokens.AccessToken == "" || tokens.RefreshToken == "" {
 		t.Fatalf("expected non-empty token")
 	}
 	if user == nil || user.Username != username {
 		t.Fatalf("unexpected user from login: %#v", user)
 	}
 
-	_, _, err = svc.Login(username, "wrong-password")
+	_, _, err = svc.Login(username, "wrong-password", ClientInfo{})
 	if err == nil {
 		t.Fatalf("expected error for wrong password")
 	}
@@ -151,7 +148,7 @@ func TestAuthLoginSuccessAndWrongPassword(t *testing.T) {
 }
 
 func TestReservationCreateRejectsOverlapForBlockingStatuses(t *testing.T) {
-	blocking := []string{"pending", "approved", "active"}
+	blocking := []string{"awaiting_payment", "pending", "approved", "active"}
 	for _, status := range blocking {
 		t.Run(status, func(t *testing.T) {
 			db := newTestDB(t)
@@ -254,6 +251,7 @@ func TestAuthRegisterDuplicateUsername(t *testing.T) {
 	db := newTestDB(t)
 	svc := &AuthService{
 		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
 		JWTSecret: "test-secret",
 	}
 
@@ -266,3 +264,148 @@ func TestAuthRegisterDuplicateUsername(t *testing.T) {
 	}
 }
 
+func TestAuthRefreshRotatesAndDetectsReuse(t *testing.T) {
+	db := newTestDB(t)
+	svc := &AuthService{
+		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
+		JWTSecret: "test-secret",
+	}
+	if err := svc.Register("carol", "secret123"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	first, _, err := svc.Login("carol", "secret123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+
+	second, _, err := svc.Refresh(first.RefreshToken)
+	if err != nil {
+		t.Fatalf("refresh failed: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Fatalf("expected refresh token to rotate")
+	}
+	if _, _, err := svc.Authenticate(second.AccessToken); err != nil {
+		t.Fatalf("new access token rejected: %v", err)
+	}
+
+	// Replaying the rotated token revokes the session for every holder.
+	if _, _, err := svc.Refresh(first.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken on reuse, got %v", err)
+	}
+	if _, _, err := svc.Authenticate(second.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("expected session to be revoked after reuse, got %v", err)
+	}
+	if _, _, err := svc.Refresh(second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected revoked session refresh to fail, got %v", err)
+	}
+}
+
+func TestAuthRefreshDetectsReuseOfAnOlderToken(t *testing.T) {
+	db := newTestDB(t)
+	svc := &AuthService{
+		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
+		JWTSecret: "test-secret",
+	}
+	if err := svc.Register("carol", "secret123"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	first, _, err := svc.Login("carol", "secret123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	latest := first
+	for i := 0; i < 3; i++ {
+		if latest, _, err = svc.Refresh(latest.RefreshToken); err != nil {
+			t.Fatalf("refresh %d failed: %v", i+1, err)
+		}
+	}
+
+	// The first token is three rotations old and must still be recognised.
+	if _, _, err := svc.Refresh(first.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected ErrInvalidRefreshToken on reuse, got %v", err)
+	}
+	if _, _, err := svc.Authenticate(latest.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("expected session to be revoked after reuse, got %v", err)
+	}
+	if _, _, err := svc.Refresh(latest.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected revoked session refresh to fail, got %v", err)
+	}
+}
+
+func TestAuthLogoutRevokesAccessToken(t *testing.T) {
+	db := newTestDB(t)
+	svc := &AuthService{
+		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
+		JWTSecret: "test-secret",
+	}
+	if err := svc.Register("dave", "secret123"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	a, u, _ := svc.Login("dave", "secret123", ClientInfo{})
+	b, _, _ := svc.Login("dave", "secret123", ClientInfo{})
+
+	if err := svc.Logout(a.RefreshToken); err != nil {
+		t.Fatalf("logout failed: %v", err)
+	}
+	if _, _, err := svc.Authenticate(a.AccessToken); err == nil {
+		t.Fatalf("expected logged out access token to be rejected")
+	}
+	if _, _, err := svc.Authenticate(b.AccessToken); err != nil {
+		t.Fatalf("other device should stay logged in: %v", err)
+	}
+
+	if err := svc.LogoutAll(u.ID); err != nil {
+		t.Fatalf("logout all failed: %v", err)
+	}
+	if _, _, err := svc.Authenticate(b.AccessToken); err == nil {
+		t.Fatalf("expected all sessions to be revoked")
+	}
+}
+
+func TestAuthenticateUsesCurrentRoleAndAccountState(t *testing.T) {
+	db := newTestDB(t)
+	users := &repositories.UserRepository{DB: db}
+	cache := &UserCache{Users: users, TTL: time.Hour}
+	svc := &AuthService{
+		Users:     users,
+		Sessions:  &repositories.SessionRepository{DB: db},
+		UserCache: cache,
+		JWTSecret: "test-secret",
+	}
+	if err := svc.Register("erin", "secret123"); err != nil {
+		t.Fatalf("register failed: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE users SET role='admin' WHERE username='erin'`); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	tokens, u, err := svc.Login("erin", "secret123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("login failed: %v", err)
+	}
+	if _, got, err := svc.Authenticate(tokens.AccessToken); err != nil || got.Role != "admin" {
+		t.Fatalf("expected admin role, got %#v (err=%v)", got, err)
+	}
+
+	if _, err := db.Exec(`UPDATE users SET role='user' WHERE id=?`, u.ID); err != nil {
+		t.Fatalf("demote: %v", err)
+	}
+	cache.Invalidate(u.ID)
+	if _, got, err := svc.Authenticate(tokens.AccessToken); err != nil || got.Role != "user" {
+		t.Fatalf("expected demotion to apply to the existing token, got %#v (err=%v)", got, err)
+	}
+
+	if _, err := db.Exec(`UPDATE users SET disabled_at=? WHERE id=?`, time.Now().UTC(), u.ID); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	cache.Invalidate(u.ID)
+	if _, _, err := svc.Authenticate(tokens.AccessToken); !errors.Is(err, ErrAccountDisabled) {
+		t.Fatalf("expected ErrAccountDisabled, got %v", err)
+	}
+	if _, _, err := svc.Login("erin", "secret123", ClientInfo{}); !errors.Is(err, ErrAccountDisabled) {
+		t.Fatalf("expected disabled login to fail, got %v", err)
+	}
+}
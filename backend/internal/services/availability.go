@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+// Operational car statuses. Whether a car is booked is not a status; it is
+// computed from reservations for the dates someone asks about.
+const (
+	CarAvailable   = "available"
+	CarMaintenance = "maintenance"
+	CarRetired     = "retired"
+)
+
+var CarStatuses = []string{CarAvailable, CarMaintenance, CarRetired}
+
+var ErrCarNotBookable = errors.New("car is not available for booking")
+
+func IsValidCarStatus(status string) bool {
+	for _, s := range CarStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TodayRange is the default occupancy window: the current day in loc.
+func TodayRange(now time.Time, loc *time.Location) (time.Time, time.Time) {
+	y, m, d := now.In(loc).Date()
+	from := time.Date(y, m, d, 0, 0, 0, 0, loc)
+	return from, from.AddDate(0, 0, 1)
+}
+
+// AttachOccupancy fills in Occupancy on every car for [from, to). A car
+// also counts as occupied when a booking falls inside the turnaround buffer.
+func (s *ReservationService) AttachOccupancy(cars []models.Car, from, to time.Time) error {
+	ids := make([]string, len(cars))
+	for i := range cars {
+		ids[i] = cars[i].ID
+	}
+	lo, hi := s.Policy.BlockingWindow(from, to)
+	occupied, err := s.Reservations.OccupiedCarIDs(ids, lo, hi)
+	if err != nil {
+		return err
+	}
+	for i := range cars {
+		busy := occupied[cars[i].ID]
+		cars[i].Occupancy = &models.CarOccupancy{From: from, To: to, Occupied: busy, Bookable: !busy && cars[i].Status == CarAvailable}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestAttachOccupancyIsDateAware(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	insertReservationWithStatus(t, db, carID, userID, "pending", time.Date(2026, 5, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC))
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}}
+
+	occupancy := func(from, to time.Time) *models.CarOccupancy {
+		t.Helper()
+		car, err := svc.Cars.GetByID(carID)
+		if err != nil {
+			t.Fatalf("get car: %v", err)
+		}
+		cars := []models.Car{*car}
+		if err := svc.AttachOccupancy(cars, from, to); err != nil {
+			t.Fatalf("attach occupancy: %v", err)
+		}
+		return cars[0].Occupancy
+	}
+
+	if o := occupancy(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC)); o.Occupied || !o.Bookable {
+		t.Fatalf("car booked next month should be free today, got %+v", o)
+	}
+	if o := occupancy(time.Date(2026, 5, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 14, 0, 0, 0, 0, time.UTC)); !o.Occupied || o.Bookable {
+		t.Fatalf("expected overlap to be occupied, got %+v", o)
+	}
+	if o := occupancy(time.Date(2026, 5, 13, 0, 0, 0, 0, time.UTC), time.Date(2026, 5, 14, 0, 0, 0, 0, time.UTC)); o.Occupied {
+		t.Fatalf("return day should be free for the next booking, got %+v", o)
+	}
+
+	if _, err := db.Exec(`UPDATE cars SET status='maintenance' WHERE id=?`, carID); err != nil {
+		t.Fatalf("set maintenance: %v", err)
+	}
+	if o := occupancy(time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 2, 0, 0, 0, 0, time.UTC)); o.Occupied || o.Bookable {
+		t.Fatalf("car in maintenance should not be bookable, got %+v", o)
+	}
+	err := svc.Create(&models.Reservation{CarID: carID, UserID: userID, StartDate: time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), EndDate: time.Date(2026, 4, 3, 0, 0, 0, 0, time.UTC)}, nil)
+	if !errors.Is(err, ErrCarNotBookable) {
+		t.Fatalf("expected ErrCarNotBookable, got %v", err)
+	}
+}
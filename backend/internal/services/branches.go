@@ -0,0 +1,92 @@
+package services
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+var (
+	ErrUnknownBranch    = errors.New("unknown pickup location")
+	ErrInvalidTimezone  = errors.New("timezone must be an IANA name such as Europe/Sarajevo")
+	ErrInvalidLocalTime = errors.New("time does not exist in the pickup location's time zone")
+	ErrStartInPast      = errors.New("startDate is in the past")
+)
+
+// ValidateBranch trims the name and checks that the time zone is known and
+// the tax rate, when set, is a percentage.
+func ValidateBranch(b *models.Branch) error {
+	b.Name = strings.TrimSpace(b.Name)
+	b.Timezone = strings.TrimSpace(b.Timezone)
+	b.TaxName = strings.TrimSpace(b.TaxName)
+	if b.TaxName == "" {
+		b.TaxName = DefaultTaxName
+	}
+	if b.Name == "" {
+		return errors.New("name is required")
+	}
+	if b.TaxRate != nil && (*b.TaxRate < 0 || *b.TaxRate > 100) {
+		return ErrInvalidTaxRate
+	}
+	if b.Timezone == "" || b.Timezone == "Local" {
+		return ErrInvalidTimezone
+	}
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		return ErrInvalidTimezone
+	}
+	return nil
+}
+
+// Branch resolves a pickup location by name together with its zone.
+func (s *ReservationService) Branch(name string) (*models.Branch, *time.Location, error) {
+	b, err := s.Branches.GetByName(strings.TrimSpace(name))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil, nil, ErrUnknownBranch
+		}
+		return nil, nil, err
+	}
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return nil, nil, ErrInvalidTimezone
+	}
+	return b, loc, nil
+}
+
+// Zone is the time zone availability queries are read and shown in: the
+// named branch's, or UTC when no branch is given.
+func (s *ReservationService) Zone(branch string) (*time.Location, error) {
+	if strings.TrimSpace(branch) == "" {
+		return time.UTC, nil
+	}
+	_, loc, err := s.Branch(branch)
+	return loc, err
+}
+
+// PickupTolerance is how far in the past a pickup may be, so a booking for
+// the current hour can still be made a few minutes into it.
+const PickupTolerance = 15 * time.Minute
+
+// ResolveBooking interprets the requested pickup and return times in the
+// pickup branch's zone and stores that zone on the reservation. A pickup
+// more than PickupTolerance before now is rejected.
+func (s *ReservationService) ResolveBooking(res *models.Reservation, start, end string, now time.Time) error {
+	b, loc, err := s.Branch(res.PickupLocation)
+	if err != nil {
+		return err
+	}
+	if res.StartDate, err = ParseRentalTime(start, loc); err != nil {
+		return err
+	}
+	if res.EndDate, err = ParseRentalTime(end, loc); err != nil {
+		return err
+	}
+	if res.StartDate.Before(now.Add(-PickupTolerance)) {
+		return ErrStartInPast
+	}
+	res.PickupLocation = b.Name
+	res.Timezone = b.Timezone
+	return nil
+}
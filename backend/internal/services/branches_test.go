@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestResolveBookingUsesBranchTimezone(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	branches := &repositories.BranchRepository{DB: db}
+	if err := branches.Create(&models.Branch{Name: "New York JFK", Timezone: "America/New_York"}); err != nil {
+		t.Fatalf("create branch: %v", err)
+	}
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}, Branches: branches}
+	// 22:00 on 1 November in New York, already 2 November in UTC.
+	now := time.Date(2026, 11, 2, 3, 0, 0, 0, time.UTC)
+
+	cases := []struct {
+		name, start, end string
+		wantStart        time.Time
+		wantErr          error
+	}{
+		{"bare date is local midnight", "2026-11-05", "2026-11-07", time.Date(2026, 11, 5, 5, 0, 0, 0, time.UTC), nil},
+		{"wall clock pickup", "2026-11-05T10:00", "2026-11-05T18:00", time.Date(2026, 11, 5, 15, 0, 0, 0, time.UTC), nil},
+		{"offset wins over branch zone", "2026-11-05T10:00:00Z", "2026-11-05T18:00:00Z", time.Date(2026, 11, 5, 10, 0, 0, 0, time.UTC), nil},
+		{"still today at the branch", "2026-11-01T23:00", "2026-11-02T10:00", time.Date(2026, 11, 2, 4, 0, 0, 0, time.UTC), nil},
+		{"this hour at the branch", "2026-11-01T22:00", "2026-11-02T10:00", time.Date(2026, 11, 2, 3, 0, 0, 0, time.UTC), nil},
+		{"earlier today at the branch", "2026-11-01T08:00", "2026-11-02T10:00", time.Time{}, ErrStartInPast},
+		{"yesterday at the branch", "2026-10-31", "2026-11-03", time.Time{}, ErrStartInPast},
+		{"skipped by DST", "2027-03-14T02:30", "2027-03-15T10:00", time.Time{}, ErrInvalidLocalTime},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			res := &models.Reservation{PickupLocation: "new york jfk"}
+			err := svc.ResolveBooking(res, tc.start, tc.end, now)
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if !res.StartDate.Equal(tc.wantStart) || res.Timezone != "America/New_York" || res.PickupLocation != "New York JFK" {
+				t.Fatalf("got start=%v tz=%q pickup=%q", res.StartDate, res.Timezone, res.PickupLocation)
+			}
+		})
+	}
+
+	if err := svc.ResolveBooking(&models.Reservation{PickupLocation: "Nowhere"}, "2026-11-05", "2026-11-07", now); !errors.Is(err, ErrUnknownBranch) {
+		t.Fatalf("expected ErrUnknownBranch, got %v", err)
+	}
+
+	res := &models.Reservation{CarID: carID, UserID: userID, PickupLocation: "New York JFK"}
+	if err := svc.ResolveBooking(res, "2026-11-05T10:00", "2026-11-06T10:00", now); err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	var stored string
+	_ = db.QueryRow(`SELECT start_date FROM reservations WHERE id=?`, res.ID).Scan(&stored)
+	if stored[:19] != "2026-11-05 15:00:00" && stored[:19] != "2026-11-05T15:00:00" {
+		t.Fatalf("expected start stored in UTC, got %q", stored)
+	}
+	got, err := svc.Reservations.GetByID(res.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.StartDate.Format("2006-01-02T15:04Z07:00") != "2026-11-05T10:00-05:00" {
+		t.Fatalf("expected start rendered in branch zone, got %v", got.StartDate)
+	}
+}
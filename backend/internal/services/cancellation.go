@@ -0,0 +1,200 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+)
+
+var ErrInvalidCancellationPolicy = errors.New("invalid cancellation policy")
+
+// CancellationQuote is what cancelling a reservation would cost right now.
+// Fee is FeePercent of Paid, the money the booking holds; the rest is
+// refunded.
+type CancellationQuote struct {
+	ReservationID string                     `json:"reservationId"`
+	Allowed       bool                       `json:"allowed"`
+	Policy        *models.CancellationPolicy `json:"policy,omitempty"`
+	HoursBefore   float64                    `json:"hoursBefore"`
+	FeePercent    float64                    `json:"feePercent"`
+	Paid          models.Money               `json:"paid"`
+	Fee           models.Money               `json:"fee"`
+	Refund        models.Money               `json:"refund"`
+	Currency      string                     `json:"currency"`
+}
+
+// ValidateCancellationPolicy sorts tiers from the shortest notice up. Each
+// tier needs its own number of hours and a fee between 0 and 100 percent.
+func ValidateCancellationPolicy(p *models.CancellationPolicy) error {
+	p.Name = strings.TrimSpace(p.Name)
+	invalid := func(msg string) error { return fmt.Errorf("%w: %s", ErrInvalidCancellationPolicy, msg) }
+	if p.Name == "" {
+		return invalid("name is required")
+	}
+	if len(p.Tiers) == 0 {
+		return invalid("at least one tier is required")
+	}
+	sort.Slice(p.Tiers, func(i, j int) bool { return p.Tiers[i].HoursBefore < p.Tiers[j].HoursBefore })
+	for i, t := range p.Tiers {
+		if t.HoursBefore < 1 || t.FeePercent < 0 || t.FeePercent > 100 {
+			return invalid("hoursBefore must be at least 1 and feePercent between 0 and 100")
+		}
+		if i > 0 && t.HoursBefore == p.Tiers[i-1].HoursBefore {
+			return invalid("hoursBefore must differ between tiers")
+		}
+	}
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, c := range p.Categories {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" && !seen[c] {
+			seen[c] = true
+			categories = append(categories, c)
+		}
+	}
+	p.Categories = categories
+	return nil
+}
+
+// FeePercent is the fee for cancelling with notice left before pickup: the
+// tier with the shortest notice that still covers it. Cancelling earlier
+// than every tier is free.
+func FeePercent(p *models.CancellationPolicy, notice time.Duration) float64 {
+	if p == nil {
+		return 0
+	}
+	for _, t := range p.Tiers {
+		if notice < time.Duration(t.HoursBefore)*time.Hour {
+			return t.FeePercent
+		}
+	}
+	return 0
+}
+
+// CancellationPolicyFor is the policy new bookings of car are made under.
+// Without one cancelling stays free.
+func (s *ReservationService) CancellationPolicyFor(car *models.Car) (*models.CancellationPolicy, error) {
+	if s.CancellationPolicies == nil {
+		return nil, nil
+	}
+	p, err := s.CancellationPolicies.ForCategory(strings.ToLower(car.Category))
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return p, err
+}
+
+// QuoteCancellation previews what the actor would pay to cancel a
+// reservation now.
+func (s *ReservationService) QuoteCancellation(id string, actor Actor) (*CancellationQuote, error) {
+	re, err := s.Reservations.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Staff && re.UserID != actor.UserID {
+		return nil, sql.ErrNoRows
+	}
+	return s.quoteCancellation(re, actor, time.Now().UTC())
+}
+
+// quoteCancellation applies the policy the reservation was booked under, or
+// the car's current one for bookings made before policies were kept. Staff
+// cancel free of charge, and a booking that holds no money costs nothing.
+func (s *ReservationService) quoteCancellation(re *models.Reservation, actor Actor, now time.Time) (*CancellationQuote, error) {
+	q := &CancellationQuote{ReservationID: re.ID, Currency: re.Currency, HoursBefore: re.StartDate.Sub(now).Hours()}
+	_, allowed := findTransition(re.Status, "cancelled")
+	q.Allowed = allowed && (actor.Staff || CanCancel(re))
+	q.Policy = re.CancellationPolicy
+	if q.Policy == nil {
+		car, err := s.Cars.GetByID(re.CarID)
+		if err != nil {
+			return nil, err
+		}
+		if q.Policy, err = s.CancellationPolicyFor(car); err != nil {
+			return nil, err
+		}
+	}
+	if s.Payments != nil {
+		paid, err := s.Payments.Held(re)
+		if err != nil {
+			return nil, err
+		}
+		q.Paid = paid
+	}
+	if !actor.Staff {
+		q.FeePercent = FeePercent(q.Policy, re.StartDate.Sub(now))
+	}
+	q.Fee = q.Paid.Percent(q.FeePercent)
+	q.Refund = q.Paid - q.Fee
+	return q, nil
+}
+
+// Held is the money a reservation's payment holds or has taken and not
+// given back.
+func (s *PaymentService) Held(re *models.Reservation) (models.Money, error) {
+	p, err := s.Payments.Latest(re.ID, PaymentRental)
+	if IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	switch p.Status {
+	case payments.StatusAuthorized:
+		return p.Amount, nil
+	case payments.StatusCaptured, payments.StatusPartiallyRefunded:
+		return p.Captured - p.Refunded, nil
+	}
+	return 0, nil
+}
+
+// Forfeit ends the payment of a cancelled booking keeping fee: an
+// authorization is captured for the fee only, which releases the rest, and
+// a capture is refunded less the fee. Without a fee it is Release. It
+// returns the fee actually kept: when the gateway will not capture the fee
+// the whole authorization is voided instead, so the customer's funds are
+// not held for a booking that no longer exists.
+func (s *PaymentService) Forfeit(re *models.Reservation, fee models.Money) (models.Money, error) {
+	if fee == 0 {
+		return 0, s.Release(re)
+	}
+	p, err := s.Payments.Latest(re.ID, PaymentRental)
+	if IsNotFound(err) {
+		return fee, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	from, before := p.Status, *p
+	switch p.Status {
+	case payments.StatusAuthorized:
+		if err := s.Provider.Capture(p.ProviderRef, fee); err != nil {
+			s.audit("payment_forfeit_failed", p.ID, fmt.Sprintf("fee %s %s not taken: %v", fee, p.Currency, err))
+			return 0, s.release(p)
+		}
+		p.Status, p.Captured = payments.StatusCaptured, fee
+	case payments.StatusCaptured, payments.StatusPartiallyRefunded:
+		refund := p.Captured - p.Refunded - fee
+		if refund <= 0 {
+			return fee, nil
+		}
+		if err := s.Provider.Refund(p.ProviderRef, refund); err != nil {
+			// Keep the payment as it was and leave the reason on it for staff.
+			*p = before
+			p.FailureReason = err.Error()
+			_, _ = s.Payments.Update(p, from)
+			s.audit("payment_forfeit_failed", p.ID, err.Error())
+			return 0, err
+		}
+		p.Status, p.Refunded = payments.StatusPartiallyRefunded, p.Refunded+refund
+	default:
+		return 0, s.release(p)
+	}
+	return fee, s.save(p, from)
+}
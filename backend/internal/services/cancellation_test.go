@@ -0,0 +1,97 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestCancellationPolicyKeepsFeeAndRefundsRest(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	svc.CancellationPolicies = &repositories.CancellationPolicyRepository{DB: svc.Reservations.DB}
+	policy := &models.CancellationPolicy{Name: "Standard", Tiers: []models.CancellationTier{{HoursBefore: 24, FeePercent: 100}, {HoursBefore: 48, FeePercent: 50}}, Categories: []string{"Sedan"}}
+	if err := ValidateCancellationPolicy(policy); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if policy.Tiers[0].HoursBefore != 24 || policy.Categories[0] != "sedan" {
+		t.Fatalf("expected tiers sorted by notice and lowercase categories, got %+v", policy)
+	}
+	if err := svc.CancellationPolicies.Create(policy); err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	customer := Actor{UserID: userID}
+	book := func(start time.Time) *models.Reservation {
+		t.Helper()
+		res := &models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: start.AddDate(0, 0, 2), PickupLocation: "A", DropoffLocation: "B"}
+		if err := svc.Create(res, nil); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		return res
+	}
+
+	early := book(time.Now().UTC().AddDate(0, 1, 0).Truncate(time.Hour))
+	if early.CancellationPolicy == nil || early.CancellationPolicy.ID != policy.ID {
+		t.Fatalf("booking should keep its policy, got %+v", early.CancellationPolicy)
+	}
+	if _, err := svc.Transition(early.ID, "cancelled", customer); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	re, _ := svc.Reservations.GetByID(early.ID)
+	if re.CancellationFee == nil || *re.CancellationFee != 0 || *re.Refund != 100_00 {
+		t.Fatalf("early cancellation should be free, got fee=%v refund=%v", re.CancellationFee, re.Refund)
+	}
+
+	late := book(time.Now().UTC().Add(30 * time.Hour).Truncate(time.Hour))
+	q, err := svc.QuoteCancellation(late.ID, customer)
+	if err != nil {
+		t.Fatalf("quote: %v", err)
+	}
+	if !q.Allowed || q.FeePercent != 50 || q.Fee != 50_00 || q.Refund != 50_00 {
+		t.Fatalf("expected half of 100 kept, got %+v", q)
+	}
+	if _, err := svc.Transition(late.ID, "cancelled", customer); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	p, _ := svc.Payments.Payments.Latest(late.ID, PaymentRental)
+	if p.Status != payments.StatusCaptured || p.Captured != 50_00 {
+		t.Fatalf("the fee should be captured and the rest released, got %+v", p)
+	}
+	if got := capturedUSD(svc, PaymentRental); got != 50_00 {
+		t.Fatalf("cancellation fees count as revenue, got %v", got)
+	}
+
+	staffCancelled := book(time.Now().UTC().Add(30 * time.Hour).Truncate(time.Hour))
+	if _, err := svc.Transition(staffCancelled.ID, "cancelled", Actor{UserID: "staff", Staff: true}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if p, _ := svc.Payments.Payments.Latest(staffCancelled.ID, PaymentRental); p.Status != payments.StatusVoided {
+		t.Fatalf("staff cancellations are free, got %+v", p)
+	}
+}
+
+func TestFailedCancellationFeeReleasesThePayment(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	svc.CancellationPolicies = &repositories.CancellationPolicyRepository{DB: svc.Reservations.DB}
+	if err := svc.CancellationPolicies.Create(&models.CancellationPolicy{Name: "Strict", Tiers: []models.CancellationTier{{HoursBefore: 48, FeePercent: 100}}, Categories: []string{"sedan"}}); err != nil {
+		t.Fatalf("create policy: %v", err)
+	}
+	start := time.Now().UTC().Add(30 * time.Hour).Truncate(time.Hour)
+	res := &models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: start.AddDate(0, 0, 2), PickupLocation: "A", DropoffLocation: "B", PaymentMethod: payments.FakeMethodDeclineCapture}
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Transition(res.ID, "cancelled", Actor{UserID: userID}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	p, _ := svc.Payments.Payments.Latest(res.ID, PaymentRental)
+	if p.Status != payments.StatusVoided {
+		t.Fatalf("a fee the gateway would not take must not keep the funds held, got %+v", p)
+	}
+	re, _ := svc.Reservations.GetByID(res.ID)
+	if re.CancellationFee == nil || *re.CancellationFee != 0 || *re.Refund != 100_00 {
+		t.Fatalf("expected no fee and a full refund to be recorded, got fee=%v refund=%v", re.CancellationFee, re.Refund)
+	}
+}
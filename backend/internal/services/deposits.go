@@ -0,0 +1,142 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+)
+
+var (
+	ErrInvalidDeposit       = errors.New("amount must be 0 or more and category is required")
+	ErrInvalidDepositCharge = errors.New("depositCharge must be between 0 and the deposit held")
+	ErrNoDepositHeld        = errors.New("no authorized deposit to charge")
+)
+
+// ValidateCategoryDeposit normalizes a deposit entered in the base currency.
+// An amount of 0 means cars of the category need no deposit.
+func ValidateCategoryDeposit(d *models.CategoryDeposit, base string) error {
+	d.Category = strings.ToLower(strings.TrimSpace(d.Category))
+	if d.Category == "" || d.Amount < 0 {
+		return ErrInvalidDeposit
+	}
+	d.Currency = base
+	return nil
+}
+
+// DepositFor is the deposit held at pickup for car: its category's amount,
+// or Deposit when the category has none.
+func (s *ReservationService) DepositFor(car *models.Car) (models.Money, error) {
+	if s.Deposits == nil {
+		return s.Deposit, nil
+	}
+	d, err := s.Deposits.Get(strings.ToLower(car.Category))
+	if IsNotFound(err) {
+		return s.Deposit, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if d.Currency != s.BaseCurrency() {
+		return 0, ErrCurrencyMismatch
+	}
+	return d.Amount, nil
+}
+
+// holdDeposit authorizes the deposit when the car is picked up, on the card
+// the booking was paid with. The amount is the one the booking was quoted,
+// or the car's current one for bookings priced without a deposit line. A
+// declined hold stops the pickup.
+func (s *ReservationService) holdDeposit(re *models.Reservation) error {
+	amount, err := s.bookedDeposit(re)
+	if err != nil || amount == 0 {
+		return err
+	}
+	_, err = s.Payments.HoldDeposit(re, amount)
+	return err
+}
+
+func (s *ReservationService) bookedDeposit(re *models.Reservation) (models.Money, error) {
+	lines, err := s.Reservations.LineItems(re.ID)
+	if err != nil {
+		return 0, err
+	}
+	for _, li := range lines {
+		if li.Kind == LineDeposit {
+			return li.Amount, nil
+		}
+	}
+	car, err := s.Cars.GetByID(re.CarID)
+	if err != nil {
+		return 0, err
+	}
+	return s.DepositFor(car)
+}
+
+// HoldDeposit authorizes amount as re's security deposit. A hold the
+// gateway reports on later does not stop the pickup; its state shows on the
+// reservation.
+func (s *PaymentService) HoldDeposit(re *models.Reservation, amount models.Money) (*models.Payment, error) {
+	method := ""
+	if paid, err := s.Payments.Latest(re.ID, PaymentRental); err == nil {
+		method = paid.Method
+	}
+	result, err := s.Provider.Authorize(payments.AuthorizeRequest{Reference: re.ID, Amount: amount, Currency: re.Currency, Method: method})
+	if err != nil {
+		return nil, err
+	}
+	p := &models.Payment{ReservationID: re.ID, Kind: PaymentDeposit, Method: method, Provider: s.Provider.Name(), ProviderRef: result.Ref, Status: result.Status, Amount: amount, Currency: re.Currency, FailureReason: result.Reason}
+	if err := s.Payments.Create(p); err != nil {
+		return nil, err
+	}
+	s.audit("deposit_"+p.Status, p.ID, re.ID)
+	if p.Status == payments.StatusFailed {
+		return p, fmt.Errorf("%w: deposit hold: %s", ErrPaymentDeclined, p.FailureReason)
+	}
+	return p, nil
+}
+
+// SettleDeposit ends the deposit hold when the car is returned: charge is
+// captured and the rest released, or the whole hold is voided when charge
+// is 0.
+func (s *PaymentService) SettleDeposit(re *models.Reservation, charge models.Money) error {
+	p, err := s.Payments.Latest(re.ID, PaymentDeposit)
+	if IsNotFound(err) {
+		if charge > 0 {
+			return ErrNoDepositHeld
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if charge == 0 {
+		if p.Status != payments.StatusPending && p.Status != payments.StatusAuthorized {
+			return nil
+		}
+		return s.release(p)
+	}
+	if p.Status != payments.StatusAuthorized {
+		return ErrNoDepositHeld
+	}
+	if charge > p.Amount {
+		return ErrInvalidDepositCharge
+	}
+	if err := s.Provider.Capture(p.ProviderRef, charge); err != nil {
+		s.audit("deposit_capture_failed", p.ID, err.Error())
+		return err
+	}
+	p.Status, p.Captured = payments.StatusCaptured, charge
+	return s.save(p, payments.StatusAuthorized)
+}
+
+// CompleteRental records the return of a car, keeping charge from the
+// deposit for damage or missing fuel and releasing the rest.
+func (s *ReservationService) CompleteRental(id string, charge models.Money, actor Actor) (*models.Reservation, error) {
+	if charge < 0 {
+		return nil, ErrInvalidDepositCharge
+	}
+	return s.transition(id, "completed", actor, charge)
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestDepositHeldAtPickupAndSettledOnReturn(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	svc.Deposits = &repositories.DepositRepository{DB: svc.Reservations.DB}
+	if err := svc.Deposits.Set(&models.CategoryDeposit{Category: "sedan", Amount: 300_00, Currency: svc.BaseCurrency()}); err != nil {
+		t.Fatalf("set deposit: %v", err)
+	}
+	staff := Actor{UserID: "staff", Username: "staff", Staff: true}
+	pickUp := func(id string) error {
+		if _, err := svc.Transition(id, "approved", staff); err != nil {
+			t.Fatalf("approve: %v", err)
+		}
+		_, err := svc.Transition(id, "active", staff)
+		return err
+	}
+
+	res := paymentTestBooking(carID, userID, "", 0)
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := pickUp(res.ID); err != nil {
+		t.Fatalf("pick up: %v", err)
+	}
+	d, _ := svc.Payments.Payments.Latest(res.ID, PaymentDeposit)
+	if d == nil || d.Status != payments.StatusAuthorized || d.Amount != 300_00 {
+		t.Fatalf("pickup should hold the category deposit, got %+v", d)
+	}
+	if _, err := svc.CompleteRental(res.ID, 400_00, staff); !errors.Is(err, ErrInvalidDepositCharge) {
+		t.Fatalf("expected ErrInvalidDepositCharge, got %v", err)
+	}
+	if _, err := svc.CompleteRental(res.ID, 50_00, staff); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	d, _ = svc.Payments.Payments.Latest(res.ID, PaymentDeposit)
+	if d.Status != payments.StatusCaptured || d.Captured != 50_00 {
+		t.Fatalf("return should capture the charge, got %+v", d)
+	}
+	if revenue, charges := capturedUSD(svc, PaymentRental), capturedUSD(svc, PaymentDeposit); revenue != 100_00 || charges != 50_00 {
+		t.Fatalf("deposit charges are counted apart from revenue, got %v and %v", revenue, charges)
+	}
+
+	clean := paymentTestBooking(carID, userID, "", 1)
+	if err := svc.Create(clean, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if err := pickUp(clean.ID); err != nil {
+		t.Fatalf("pick up: %v", err)
+	}
+	if _, err := svc.Transition(clean.ID, "completed", staff); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if d, _ := svc.Payments.Payments.Latest(clean.ID, PaymentDeposit); d.Status != payments.StatusVoided {
+		t.Fatalf("a clean return should release the hold, got %+v", d)
+	}
+
+	declined := paymentTestBooking(carID, userID, "", 2)
+	if err := svc.Create(declined, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Reservations.DB.Exec(`UPDATE payments SET method=? WHERE reservation_id=?`, payments.FakeMethodDecline, declined.ID); err != nil {
+		t.Fatalf("change card: %v", err)
+	}
+	if err := pickUp(declined.ID); !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("expected declined deposit hold, got %v", err)
+	}
+	if re, _ := svc.Reservations.GetByID(declined.ID); re.Status != "approved" {
+		t.Fatalf("car must not be handed over without a deposit, got %s", re.Status)
+	}
+}
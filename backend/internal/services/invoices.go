@@ -0,0 +1,177 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/pdf"
+	"rentacar/backend/internal/repositories"
+)
+
+var ErrNotInvoiced = errors.New("invoices are issued once the rental is completed")
+
+const DefaultInvoicePrefix = "INV"
+
+// InvoiceService issues an invoice for every completed rental.
+type InvoiceService struct {
+	Invoices     *repositories.InvoiceRepository
+	Reservations *repositories.ReservationRepository
+	Users        *repositories.UserRepository
+	Payments     *repositories.PaymentRepository
+	Audit        *repositories.AuditLogRepository
+	// Seller is the company named on invoices, from configuration.
+	Seller models.InvoiceParty
+	Prefix string
+}
+
+// Issue creates the invoice of a completed reservation, or returns the one
+// it already has. The lines are the ones the rental was booked with, less
+// the refundable deposit, plus whatever was kept from the deposit on return.
+func (s *InvoiceService) Issue(reservationID string) (*models.Invoice, error) {
+	if inv, err := s.Invoices.ForReservation(reservationID); !IsNotFound(err) {
+		return inv, err
+	}
+	re, err := s.Reservations.GetByID(reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if re.Status != "completed" {
+		return nil, ErrNotInvoiced
+	}
+	inv := &models.Invoice{ReservationID: re.ID, UserID: re.UserID, IssuedAt: time.Now().UTC(), Seller: s.Seller, Currency: re.Currency}
+	u, err := s.Users.FindByID(re.UserID)
+	if err != nil {
+		return nil, err
+	}
+	inv.Customer = models.InvoiceParty{Name: u.FullName, Email: u.Email}
+	if inv.Customer.Name == "" {
+		inv.Customer.Name = u.Username
+	}
+	lines, err := s.Reservations.LineItems(re.ID)
+	if err != nil {
+		return nil, err
+	}
+	var taxes []models.LineItem
+	inv.Lines = []models.LineItem{}
+	for _, li := range lines {
+		switch li.Kind {
+		case LineDeposit:
+		case LineTax:
+			taxes = append(taxes, li)
+			inv.Tax += li.Amount
+		default:
+			inv.Lines = append(inv.Lines, li)
+			inv.Subtotal += li.Amount
+		}
+	}
+	if s.Payments != nil {
+		if d, err := s.Payments.Latest(re.ID, PaymentDeposit); err == nil && d.Captured > 0 {
+			inv.Lines = append(inv.Lines, single(LineFee, "deposit_charge", "Charged from deposit", d.Captured))
+			inv.Subtotal += d.Captured
+		}
+	}
+	inv.Lines = append(inv.Lines, taxes...)
+	inv.Total = inv.Subtotal + inv.Tax
+	if err := s.Invoices.Create(inv, s.prefix()); err != nil {
+		// Lost a race with another request issuing the same invoice.
+		if existing, ferr := s.Invoices.ForReservation(re.ID); ferr == nil {
+			return existing, nil
+		}
+		return nil, err
+	}
+	if s.Audit != nil {
+		_ = s.Audit.Create("system", "system", "issue", "invoice", inv.ID, inv.Number)
+	}
+	return inv, nil
+}
+
+func (s *InvoiceService) prefix() string {
+	if s.Prefix != "" {
+		return s.Prefix
+	}
+	return DefaultInvoicePrefix
+}
+
+// ForReservation returns the invoice of one of the actor's reservations,
+// issuing it if completing the rental did not.
+func (s *InvoiceService) ForReservation(reservationID string, actor Actor) (*models.Invoice, error) {
+	re, err := s.Reservations.GetByID(reservationID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Staff && re.UserID != actor.UserID {
+		return nil, sql.ErrNoRows
+	}
+	return s.Issue(re.ID)
+}
+
+// RenderInvoicePDF lays an invoice out on A4 pages.
+func RenderInvoicePDF(inv *models.Invoice) []byte {
+	const left, right, bottom = 50.0, pdf.PageWidth - 50, 60.0
+	doc := pdf.New()
+	y := pdf.PageHeight - 60
+	doc.Text(left, y, 20, true, "Invoice "+inv.Number)
+	doc.TextRight(right, y, 10, false, "Issued "+inv.IssuedAt.Format("2006-01-02"))
+	y -= 36
+	party := func(x float64, title string, p models.InvoiceParty) {
+		py := y
+		doc.Text(x, py, 9, true, title)
+		for _, line := range []string{p.Name, p.Address, taxIDLine(p.TaxID), p.Email} {
+			if line == "" {
+				continue
+			}
+			py -= 13
+			doc.Text(x, py, 10, false, line)
+		}
+	}
+	party(left, "FROM", inv.Seller)
+	party(pdf.PageWidth/2, "BILL TO", inv.Customer)
+	y -= 80
+	doc.Text(left, y, 9, false, "Reservation "+inv.ReservationID)
+	y -= 24
+
+	header := func() {
+		doc.Text(left, y, 9, true, "Description")
+		doc.TextRight(right-170, y, 9, true, "Qty")
+		doc.TextRight(right-90, y, 9, true, "Unit price")
+		doc.TextRight(right, y, 9, true, "Amount")
+		y -= 6
+		doc.Line(left, y, right, y)
+		y -= 14
+	}
+	header()
+	for _, li := range inv.Lines {
+		if y < bottom+60 {
+			doc.AddPage()
+			y = pdf.PageHeight - 60
+			header()
+		}
+		doc.Text(left, y, 10, false, li.Label)
+		doc.TextRight(right-170, y, 10, false, fmt.Sprint(li.Quantity))
+		doc.TextRight(right-90, y, 10, false, li.UnitPrice.String())
+		doc.TextRight(right, y, 10, false, li.Amount.String())
+		y -= 16
+	}
+	doc.Line(right-200, y+6, right, y+6)
+	y -= 10
+	for _, row := range []struct {
+		label  string
+		amount models.Money
+		bold   bool
+	}{{"Subtotal", inv.Subtotal, false}, {"Tax", inv.Tax, false}, {"Total " + inv.Currency, inv.Total, true}} {
+		doc.Text(right-200, y, 10, row.bold, row.label)
+		doc.TextRight(right, y, 10, row.bold, row.amount.String())
+		y -= 16
+	}
+	return doc.Bytes()
+}
+
+func taxIDLine(id string) string {
+	if id == "" {
+		return ""
+	}
+	return "Tax ID " + id
+}
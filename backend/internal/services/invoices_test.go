@@ -0,0 +1,71 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestInvoicesAreNumberedWithoutGaps(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	db := svc.Reservations.DB
+	svc.Invoices = &InvoiceService{
+		Invoices:     &repositories.InvoiceRepository{DB: db},
+		Reservations: svc.Reservations,
+		Users:        &repositories.UserRepository{DB: db},
+		Payments:     svc.Payments.Payments,
+		Seller:       models.InvoiceParty{Name: "RentACar d.o.o.", TaxID: "4200000000000"},
+	}
+	staff := Actor{UserID: "staff", Username: "staff", Staff: true}
+	rent := func(week int) *models.Reservation {
+		t.Helper()
+		res := paymentTestBooking(carID, userID, "", week)
+		if err := svc.Create(res, nil); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		for _, to := range []string{"approved", "active", "completed"} {
+			if _, err := svc.Transition(res.ID, to, staff); err != nil {
+				t.Fatalf("%s: %v", to, err)
+			}
+		}
+		return res
+	}
+
+	pending := paymentTestBooking(carID, userID, "", 5)
+	if err := svc.Create(pending, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Invoices.ForReservation(pending.ID, Actor{UserID: userID}); !errors.Is(err, ErrNotInvoiced) {
+		t.Fatalf("expected ErrNotInvoiced, got %v", err)
+	}
+
+	year := time.Now().UTC().Year()
+	for i := 1; i <= 2; i++ {
+		res := rent(i)
+		inv, err := svc.Invoices.ForReservation(res.ID, Actor{UserID: userID})
+		if err != nil {
+			t.Fatalf("invoice: %v", err)
+		}
+		if want := fmt.Sprintf("INV-%d-%06d", year, i); inv.Number != want {
+			t.Fatalf("expected %s, got %s", want, inv.Number)
+		}
+		if inv.Total != 100_00 || len(inv.Lines) == 0 || inv.Seller.TaxID != "4200000000000" {
+			t.Fatalf("unexpected invoice %+v", inv)
+		}
+		if _, err := svc.Invoices.ForReservation(res.ID, Actor{UserID: "someone-else"}); !IsNotFound(err) {
+			t.Fatalf("other users must not see the invoice, got %v", err)
+		}
+		if pdf := RenderInvoicePDF(inv); !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte(inv.Number)) {
+			t.Fatalf("expected a PDF naming %s", inv.Number)
+		}
+	}
+	items, _ := svc.Invoices.Invoices.List(year)
+	if len(items) != 2 {
+		t.Fatalf("completing again must not issue another invoice, got %d", len(items))
+	}
+}
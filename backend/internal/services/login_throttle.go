@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"rentacar/backend/internal/repositories"
+)
+
+const (
+	DefaultMaxUserFailures = 5
+	DefaultMaxIPFailures   = 20
+	DefaultLockoutDuration = 15 * time.Minute
+	loginBackoffBase       = time.Second
+	loginBackoffMax        = 30 * time.Second
+	loginFailureWindow     = time.Hour
+)
+
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return "too many failed attempts, account temporarily locked"
+	}
+	return "too many failed attempts, try again later"
+}
+
+// LoginThrottle tracks failed logins per username and per client IP. Every
+// failure doubles the wait before the next attempt and reaching the limit
+// locks the key for LockoutDuration.
+type LoginThrottle struct {
+	Throttles       *repositories.LoginThrottleRepository
+	Audit           *repositories.AuditLogRepository
+	MaxUserFailures int
+	MaxIPFailures   int
+	LockoutDuration time.Duration
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + strings.ToLower(strings.TrimSpace(username))
+}
+func ipThrottleKey(ip string) string { return "ip:" + ip }
+
+func backoff(failures int) time.Duration {
+	if failures <= 0 {
+		return 0
+	}
+	d := time.Duration(float64(loginBackoffBase) * math.Pow(2, float64(failures-1)))
+	if d > loginBackoffMax {
+		return loginBackoffMax
+	}
+	return d
+}
+
+func (t *LoginThrottle) limits() (int, int, time.Duration) {
+	user, ip, lockout := t.MaxUserFailures, t.MaxIPFailures, t.LockoutDuration
+	if user <= 0 {
+		user = DefaultMaxUserFailures
+	}
+	if ip <= 0 {
+		ip = DefaultMaxIPFailures
+	}
+	if lockout <= 0 {
+		lockout = DefaultLockoutDuration
+	}
+	return user, ip, lockout
+}
+
+func (t *LoginThrottle) keys(username, ip string) []string {
+	keys := []string{userThrottleKey(username)}
+	if ip != "" {
+		keys = append(keys, ipThrottleKey(ip))
+	}
+	return keys
+}
+
+// Check returns a *ThrottledError while any key of the attempt is locked or
+// still inside its backoff window.
+func (t *LoginThrottle) Check(username, ip string) error {
+	now := time.Now().UTC()
+	var wait time.Duration
+	locked := false
+	for _, key := range t.keys(username, ip) {
+		st, err := t.Throttles.Get(key)
+		if err != nil {
+			if IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if st.LockedUntil != nil && now.Before(*st.LockedUntil) {
+			locked = true
+			if d := st.LockedUntil.Sub(now); d > wait {
+				wait = d
+			}
+			continue
+		}
+		if now.Sub(st.LastFailureAt) > loginFailureWindow {
+			continue
+		}
+		if d := st.LastFailureAt.Add(backoff(st.Failures)).Sub(now); d > wait {
+			wait = d
+		}
+	}
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait, Locked: locked}
+	}
+	return nil
+}
+
+func (t *LoginThrottle) Failure(username, ip string) error {
+	maxUser, maxIP, lockout := t.limits()
+	now := time.Now().UTC()
+	for _, key := range t.keys(username, ip) {
+		failures, err := t.Throttles.RecordFailure(key, now, now.Add(-loginFailureWindow))
+		if err != nil {
+			return err
+		}
+		limit := maxUser
+		if strings.HasPrefix(key, "ip:") {
+			limit = maxIP
+		}
+		if failures < limit {
+			continue
+		}
+		if err := t.Throttles.Lock(key, now.Add(lockout)); err != nil {
+			return err
+		}
+		if failures == limit && t.Audit != nil {
+			_ = t.Audit.Create("system", "system", "lockout", "login", key, fmt.Sprintf("%d failed attempts, locked for %s", failures, lockout))
+		}
+	}
+	return nil
+}
+
+// Success clears the username counter only, so one valid account cannot be
+// used to reset an attacking IP.
+func (t *LoginThrottle) Success(username string) error {
+	return t.Throttles.Clear(userThrottleKey(username))
+}
+
+func (t *LoginThrottle) UnlockUser(username string) error {
+	return t.Throttles.Clear(userThrottleKey(username))
+}
+
+func (t *LoginThrottle) UnlockIP(ip string) error {
+	return t.Throttles.Clear(ipThrottleKey(ip))
+}
@@ -0,0 +1,55 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"rentacar/backend/internal/repositories"
+)
+
+func TestLoginThrottleBacksOffAndLocksOut(t *testing.T) {
+	db := newTestDB(t)
+	audit := &repositories.AuditLogRepository{DB: db}
+	throttle := &LoginThrottle{Throttles: &repositories.LoginThrottleRepository{DB: db}, Audit: audit, MaxUserFailures: 3}
+	svc := &AuthService{
+		Users:     &repositories.UserRepository{DB: db},
+		Sessions:  &repositories.SessionRepository{DB: db},
+		Throttle:  throttle,
+		JWTSecret: "test-secret",
+	}
+	if err := svc.Register("ivan", "secret123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+
+	if _, _, err := svc.Login("ivan", "wrong", ClientInfo{IP: "10.0.0.1"}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected invalid credentials, got %v", err)
+	}
+	// The correct password is refused while the backoff window is open.
+	var throttled *ThrottledError
+	if _, _, err := svc.Login("ivan", "secret123", ClientInfo{IP: "10.0.0.1"}); !errors.As(err, &throttled) || throttled.Locked {
+		t.Fatalf("expected backoff, got %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := throttle.Failure("IVAN", "10.0.0.1"); err != nil {
+			t.Fatalf("record failure: %v", err)
+		}
+	}
+	if err := throttle.Check("ivan", ""); !errors.As(err, &throttled) || !throttled.Locked {
+		t.Fatalf("expected lockout after 3 failures, got %v", err)
+	}
+	logs, _ := audit.ListRecent(10)
+	if len(logs) != 1 || logs[0].Action != "lockout" || logs[0].EntityID != "user:ivan" {
+		t.Fatalf("expected one lockout audit entry, got %#v", logs)
+	}
+
+	if err := throttle.UnlockUser("ivan"); err != nil {
+		t.Fatalf("unlock: %v", err)
+	}
+	if err := throttle.Check("ivan", ""); err != nil {
+		t.Fatalf("expected user to be unlocked, got %v", err)
+	}
+	if err := throttle.Check("ivan", "10.0.0.1"); err == nil {
+		t.Fatalf("unlocking the user must not clear the IP backoff")
+	}
+}
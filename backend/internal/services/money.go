@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"rentacar/backend/internal/models"
+)
+
+const (
+	DefaultCurrency = "USD"
+	DefaultTaxName  = "VAT"
+)
+
+var (
+	ErrInvalidCurrency     = errors.New("currency must be a three-letter ISO 4217 code")
+	ErrUnsupportedCurrency = errors.New("currency must have two decimal places, such as USD or EUR")
+	ErrCurrencyMismatch    = errors.New("car and extras must be priced in the shop currency")
+	ErrCurrencyChange      = errors.New("price is in another currency and would not be converted")
+	ErrInvalidExchangeRate = errors.New("rate must be greater than 0")
+	ErrInvalidTaxRate      = errors.New("taxRate must be between 0 and 100")
+)
+
+// NormalizeCurrency upper-cases a currency code and checks its shape.
+func NormalizeCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if len(code) != 3 {
+		return "", ErrInvalidCurrency
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", ErrInvalidCurrency
+		}
+	}
+	return code, nil
+}
+
+// twoDecimalCurrencies are the ISO 4217 currencies whose minor unit is a
+// hundredth, the only ones models.Money can hold.
+var twoDecimalCurrencies = strings.Fields(`
+	AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BMD BND BOB BRL BSD BTN
+	BWP BYN BZD CAD CDF CHF CNY COP CRC CUP CVE CZK DKK DOP DZD EGP ERN ETB EUR FJD
+	FKP GBP GEL GHS GIP GMD GTQ GYD HKD HNL HTG HUF IDR ILS INR IRR JMD KES KGS KHR
+	KPW KYD KZT LAK LBP LKR LRD LSL MAD MDL MKD MMK MNT MOP MUR MVR MWK MXN MYR MZN
+	NAD NGN NIO NOK NPR NZD PAB PEN PGK PHP PKR PLN QAR RON RSD RUB SAR SBD SCR SDG
+	SEK SGD SHP SLE SOS SRD SSP STN SVC SYP SZL THB TJS TMT TOP TRY TTD TWD TZS UAH
+	USD UZS VES WST XCD YER ZAR ZMW ZWL`)
+
+// NormalizeBaseCurrency normalizes the currency prices are kept in. Money
+// has two decimals, so currencies such as JPY or KWD are refused.
+func NormalizeBaseCurrency(code string) (string, error) {
+	code, err := NormalizeCurrency(code)
+	if err != nil {
+		return "", err
+	}
+	for _, c := range twoDecimalCurrencies {
+		if c == code {
+			return code, nil
+		}
+	}
+	return "", ErrUnsupportedCurrency
+}
+
+// BaseCurrency is the currency prices are entered in and bookings are
+// charged in. The server does not start while stored prices are in another.
+func (s *ReservationService) BaseCurrency() string {
+	if s.Currency != "" {
+		return s.Currency
+	}
+	return DefaultCurrency
+}
+
+// checkCurrency rejects a booking whose car or extras are still priced in a
+// previous base currency, since the deposit and fees are in the current one.
+func (s *ReservationService) checkCurrency(car *models.Car, extras []models.Extra) error {
+	if car.Currency != s.BaseCurrency() {
+		return ErrCurrencyMismatch
+	}
+	for _, e := range extras {
+		if e.Currency != car.Currency {
+			return ErrCurrencyMismatch
+		}
+	}
+	return nil
+}
+
+// taxFor returns the tax charged on bookings picked up at b: the branch's
+// own rate, or TaxRate when it has none.
+func (s *ReservationService) taxFor(b *models.Branch) (float64, string) {
+	rate, name := s.TaxRate, DefaultTaxName
+	if b != nil && b.TaxName != "" {
+		name = b.TaxName
+	}
+	if b != nil && b.TaxRate != nil {
+		rate = *b.TaxRate
+	}
+	return rate, name
+}
+
+// ValidateExchangeRate normalizes the target currency of a rate from base.
+func ValidateExchangeRate(x *models.ExchangeRate, base string) error {
+	code, err := NormalizeCurrency(x.Currency)
+	if err != nil {
+		return err
+	}
+	if code == base {
+		return errors.New("currency must differ from the base currency")
+	}
+	if !(x.Rate > 0) || math.IsInf(x.Rate, 0) {
+		return ErrInvalidExchangeRate
+	}
+	x.Base, x.Currency = base, code
+	return nil
+}
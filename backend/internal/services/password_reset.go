@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/email"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+const DefaultPasswordResetTTL = time.Hour
+
+var ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+type PasswordResetService struct {
+	Users     *repositories.UserRepository
+	Resets    *repositories.PasswordResetRepository
+	Sessions  *repositories.SessionRepository
+	UserCache *UserCache
+	Mailer    email.Mailer
+	BaseURL   string
+	TTL       time.Duration
+}
+
+func (s *PasswordResetService) ttl() time.Duration {
+	if s.TTL > 0 {
+		return s.TTL
+	}
+	return DefaultPasswordResetTTL
+}
+
+// RequestReset mails a reset link when the address belongs to an active
+// account. It never reports whether the address exists.
+func (s *PasswordResetService) RequestReset(address string) error {
+	u, err := s.Users.FindByEmail(strings.ToLower(strings.TrimSpace(address)))
+	if err != nil {
+		if IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if u.DisabledAt != nil || u.DeletedAt != nil {
+		return nil
+	}
+	return s.SendResetLink(u)
+}
+
+// SendResetLink replaces any outstanding token of the user with a new one.
+func (s *PasswordResetService) SendResetLink(u *models.User) error {
+	if u.Email == "" {
+		return nil
+	}
+	token, err := auth.NewOpaqueToken()
+	if err != nil {
+		return err
+	}
+	if err := s.Resets.InvalidateForUser(u.ID); err != nil {
+		return err
+	}
+	expiresAt := time.Now().UTC().Add(s.ttl())
+	if err := s.Resets.Create(u.ID, auth.HashToken(token), expiresAt); err != nil {
+		return err
+	}
+	link := strings.TrimSuffix(s.BaseURL, "/") + "/reset-password?token=" + url.QueryEscape(token)
+	body := fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can be used once.\n\n%s\n\nIf you did not ask for this, you can ignore this email.\n", u.Username, s.ttl(), link)
+	if err := s.Mailer.Send(email.Message{To: u.Email, Subject: "Reset your RentACar password", Body: body}); err != nil {
+		log.Printf("password reset mail to user %s failed: %v", u.ID, err)
+		return err
+	}
+	return nil
+}
+
+func (s *PasswordResetService) Reset(token, password string) error {
+	if len(password) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+	rt, err := s.Resets.FindByHash(auth.HashToken(token))
+	if err != nil || rt.UsedAt != nil || time.Now().UTC().After(rt.ExpiresAt) {
+		return ErrInvalidResetToken
+	}
+	u, err := s.Users.FindByID(rt.UserID)
+	if err != nil || u.DisabledAt != nil {
+		return ErrInvalidResetToken
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	ok, err := s.Resets.MarkUsed(rt.ID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrInvalidResetToken
+	}
+	if err := s.Users.UpdatePassword(u.ID, string(hash)); err != nil {
+		return err
+	}
+	if err := s.Sessions.RevokeAllForUser(u.ID); err != nil {
+		return err
+	}
+	if s.UserCache != nil {
+		s.UserCache.Invalidate(u.ID)
+	}
+	return nil
+}
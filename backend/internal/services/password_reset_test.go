@@ -0,0 +1,82 @@
+package services
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"rentacar/backend/internal/email"
+	"rentacar/backend/internal/repositories"
+)
+
+var resetLinkRe = regexp.MustCompile(`token=([A-Za-z0-9_%\-]+)`)
+
+func readResetToken(t *testing.T, dir string) string {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if err != nil || len(files) != 1 {
+		t.Fatalf("expected exactly one mail in outbox, got %d (err=%v)", len(files), err)
+	}
+	body, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatalf("read mail: %v", err)
+	}
+	m := resetLinkRe.FindSubmatch(body)
+	if m == nil {
+		t.Fatalf("no reset link in mail:\n%s", body)
+	}
+	token, _ := url.QueryUnescape(string(m[1]))
+	return token
+}
+
+func TestPasswordResetFlowIsSingleUse(t *testing.T) {
+	userSvc, authSvc := newTestUserService(t)
+	outbox := t.TempDir()
+	svc := &PasswordResetService{
+		Users:     userSvc.Users,
+		Resets:    &repositories.PasswordResetRepository{DB: userSvc.Users.DB},
+		Sessions:  userSvc.Sessions,
+		UserCache: userSvc.UserCache,
+		Mailer:    &email.LogMailer{Dir: outbox},
+		BaseURL:   "http://app.test",
+	}
+	if err := authSvc.Register("heidi", "old-secret"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	u, _ := userSvc.Users.FindByUsername("heidi")
+	if _, err := userSvc.UpdateProfile(u.ID, ProfileInput{Email: "heidi@example.com"}); err != nil {
+		t.Fatalf("set email: %v", err)
+	}
+	session, _, _ := authSvc.Login("heidi", "old-secret", ClientInfo{})
+
+	if err := svc.RequestReset("nobody@example.com"); err != nil {
+		t.Fatalf("unknown address must not error: %v", err)
+	}
+	if err := svc.RequestReset("HEIDI@example.com"); err != nil {
+		t.Fatalf("request reset: %v", err)
+	}
+	token := readResetToken(t, outbox)
+
+	if err := svc.Reset("not-a-token", "new-secret"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected ErrInvalidResetToken, got %v", err)
+	}
+	if err := svc.Reset(token, "new-secret"); err != nil {
+		t.Fatalf("reset: %v", err)
+	}
+	if err := svc.Reset(token, "another-secret"); !errors.Is(err, ErrInvalidResetToken) {
+		t.Fatalf("expected token to be single-use, got %v", err)
+	}
+
+	if _, _, err := authSvc.Authenticate(session.AccessToken); err == nil {
+		t.Fatalf("expected existing sessions to be revoked after reset")
+	}
+	if _, _, err := authSvc.Login("heidi", "old-secret", ClientInfo{}); err == nil {
+		t.Fatalf("old password must stop working")
+	}
+	if _, _, err := authSvc.Login("heidi", "new-secret", ClientInfo{}); err != nil {
+		t.Fatalf("login with new password: %v", err)
+	}
+}
@@ -0,0 +1,309 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+	"rentacar/backend/internal/repositories"
+)
+
+// Payment kinds: what the booking costs, and the security deposit held from
+// pickup until the car is back.
+const (
+	PaymentRental  = "rental"
+	PaymentDeposit = "deposit"
+)
+
+var (
+	ErrPaymentDeclined    = payments.ErrDeclined
+	ErrPaymentRequired    = errors.New("reservation has not been paid")
+	ErrPaymentInProgress  = errors.New("a payment for this reservation is already in progress")
+	ErrNotAwaitingPayment = errors.New("reservation is not awaiting payment")
+	ErrPaymentHoldExpired = errors.New("the time to pay for this reservation has run out")
+)
+
+// DefaultPaymentHoldTTL is how long a booking awaiting payment keeps the car.
+const DefaultPaymentHoldTTL = 30 * time.Minute
+
+// PaymentService ties gateway payments to the reservation lifecycle: a
+// booking waits in awaiting_payment until its payment is authorized, the
+// payment is captured when staff approve it and voided or refunded when it
+// is denied or cancelled. A declined payment cancels the booking, and so
+// does a payment that is not authorized within HoldTTL.
+type PaymentService struct {
+	Payments     *repositories.PaymentRepository
+	Reservations *repositories.ReservationRepository
+	Provider     payments.PaymentProvider
+	Audit        *repositories.AuditLogRepository
+	HoldTTL      time.Duration
+}
+
+func (s *PaymentService) holdTTL() time.Duration {
+	if s.HoldTTL > 0 {
+		return s.HoldTTL
+	}
+	return DefaultPaymentHoldTTL
+}
+
+// ExpireHolds cancels bookings whose payment hold has run out and voids
+// whatever their payment still holds, so the car can be booked again. It
+// returns how many were cancelled.
+func (s *PaymentService) ExpireHolds(now time.Time) (int, error) {
+	ids, err := s.Reservations.ExpiredHolds(now)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	var firstErr error
+	for _, id := range ids {
+		// A webhook may have moved it on in the meantime.
+		ok, err := s.Reservations.Transition(id, "awaiting_payment", "cancelled", now.UTC())
+		if err == nil && ok {
+			n++
+			if s.Audit != nil {
+				_ = s.Audit.Create("system", "system", "status_change", "reservation", id, "awaiting_payment -> cancelled: payment hold expired")
+			}
+			err = s.Release(&models.Reservation{ID: id})
+		}
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return n, firstErr
+}
+
+func (s *PaymentService) audit(action, paymentID, details string) {
+	if s.Audit != nil {
+		_ = s.Audit.Create("system", "system", action, "payment", paymentID, details)
+	}
+}
+
+// moveReservation is the system side of the state machine: payment results
+// move a booking out of awaiting_payment without a user asking for it.
+func (s *PaymentService) moveReservation(id, to string) error {
+	ok, err := s.Reservations.Transition(id, "awaiting_payment", to, time.Now().UTC())
+	if err != nil || !ok {
+		return err
+	}
+	if s.Audit != nil {
+		_ = s.Audit.Create("system", "system", "status_change", "reservation", id, "awaiting_payment -> "+to)
+	}
+	return nil
+}
+
+// Pay authorizes the reservation's total with the gateway. The payment is
+// returned even when it was declined, alongside ErrPaymentDeclined.
+func (s *PaymentService) Pay(res *models.Reservation, method string) (*models.Payment, error) {
+	if res.Status != "awaiting_payment" {
+		return nil, ErrNotAwaitingPayment
+	}
+	if res.HoldExpiresAt == nil || !time.Now().Before(*res.HoldExpiresAt) {
+		if _, err := s.ExpireHolds(time.Now()); err != nil {
+			return nil, err
+		}
+		return nil, ErrPaymentHoldExpired
+	}
+	if last, err := s.Payments.Latest(res.ID, PaymentRental); err == nil && last.Status == payments.StatusPending {
+		return nil, ErrPaymentInProgress
+	} else if err != nil && !IsNotFound(err) {
+		return nil, err
+	}
+	result, err := s.Provider.Authorize(payments.AuthorizeRequest{Reference: res.ID, Amount: res.TotalPrice, Currency: res.Currency, Method: method})
+	if err != nil {
+		return nil, err
+	}
+	p := &models.Payment{ReservationID: res.ID, Kind: PaymentRental, Method: method, Provider: s.Provider.Name(), ProviderRef: result.Ref, Status: result.Status, Amount: res.TotalPrice, Currency: res.Currency, FailureReason: result.Reason}
+	if err := s.Payments.Create(p); err != nil {
+		return nil, err
+	}
+	s.audit("payment_"+p.Status, p.ID, res.ID)
+	if err := s.settle(p); err != nil {
+		return p, err
+	}
+	if p.Status == payments.StatusFailed {
+		return p, fmt.Errorf("%w: %s", ErrPaymentDeclined, p.FailureReason)
+	}
+	return p, nil
+}
+
+// settle moves the reservation on once a rental payment has an outcome.
+// An authorization that arrives after the booking was called off is voided
+// straight away. Deposit holds do not move the reservation.
+func (s *PaymentService) settle(p *models.Payment) error {
+	if p.Kind != PaymentRental {
+		return nil
+	}
+	switch p.Status {
+	case payments.StatusAuthorized:
+		re, err := s.Reservations.GetByID(p.ReservationID)
+		if err != nil {
+			return err
+		}
+		if re.Status == "awaiting_payment" {
+			return s.moveReservation(re.ID, "pending")
+		}
+		return s.Release(re)
+	case payments.StatusFailed:
+		return s.moveReservation(p.ReservationID, "cancelled")
+	}
+	return nil
+}
+
+// Capture takes the authorized amount. It must succeed before a booking is
+// approved.
+func (s *PaymentService) Capture(res *models.Reservation) error {
+	p, err := s.Payments.Latest(res.ID, PaymentRental)
+	if IsNotFound(err) {
+		return ErrPaymentRequired
+	}
+	if err != nil {
+		return err
+	}
+	switch p.Status {
+	case payments.StatusCaptured:
+		return nil
+	case payments.StatusAuthorized:
+	default:
+		return ErrPaymentRequired
+	}
+	if err := s.Provider.Capture(p.ProviderRef, p.Amount); err != nil {
+		s.audit("payment_capture_failed", p.ID, err.Error())
+		return err
+	}
+	p.Status, p.Captured = payments.StatusCaptured, p.Amount
+	return s.save(p, payments.StatusAuthorized)
+}
+
+// Release gives back whatever the reservation's payment still holds: an
+// authorization is voided and a capture refunded in full. Reservations
+// without a payment have nothing to release.
+func (s *PaymentService) Release(res *models.Reservation) error {
+	p, err := s.Payments.Latest(res.ID, PaymentRental)
+	if IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.release(p)
+}
+
+func (s *PaymentService) release(p *models.Payment) error {
+	var err error
+	from := p.Status
+	switch p.Status {
+	case payments.StatusPending, payments.StatusAuthorized:
+		err = s.Provider.Void(p.ProviderRef)
+		p.Status = payments.StatusVoided
+	case payments.StatusCaptured, payments.StatusPartiallyRefunded:
+		err = s.Provider.Refund(p.ProviderRef, p.Captured-p.Refunded)
+		p.Status, p.Refunded = payments.StatusRefunded, p.Captured
+	default:
+		return nil
+	}
+	if err != nil {
+		// Keep the payment as it was and leave the reason on it for staff.
+		p.Status, p.FailureReason = from, err.Error()
+		_, _ = s.Payments.Update(p, from)
+		s.audit("payment_release_failed", p.ID, err.Error())
+		return err
+	}
+	return s.save(p, from)
+}
+
+func (s *PaymentService) save(p *models.Payment, from string) error {
+	ok, err := s.Payments.Update(p, from)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("payment %s changed concurrently", p.ID)
+	}
+	s.audit("payment_"+p.Status, p.ID, p.ReservationID)
+	return nil
+}
+
+// ReleaseForUser releases the payments of a user's cancelled reservations,
+// used when an account is closed.
+func (s *PaymentService) ReleaseForUser(userID string) error {
+	held, err := s.Payments.HeldForCancelled(userID)
+	if err != nil {
+		return err
+	}
+	var firstErr error
+	for _, p := range held {
+		if err := s.Release(&models.Reservation{ID: p.ReservationID}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// HandleWebhook applies an asynchronous result from the gateway. Deliveries
+// are idempotent: an event seen before is acknowledged and ignored.
+func (s *PaymentService) HandleWebhook(body []byte, header http.Header) error {
+	e, err := s.Provider.ParseWebhook(body, header)
+	if err != nil {
+		return err
+	}
+	p, err := s.Payments.GetByRef(s.Provider.Name(), e.Ref)
+	if err != nil {
+		return err
+	}
+	fresh, err := s.Payments.RecordEvent(s.Provider.Name(), e.ID, p.ID, e.Status)
+	if err != nil || !fresh {
+		return err
+	}
+	if err := s.applyEvent(p, e); err != nil {
+		_ = s.Payments.ForgetEvent(s.Provider.Name(), e.ID)
+		return err
+	}
+	return nil
+}
+
+func (s *PaymentService) applyEvent(p *models.Payment, e payments.Event) error {
+	from := p.Status
+	switch e.Status {
+	case payments.StatusAuthorized, payments.StatusFailed:
+		if from != payments.StatusPending {
+			return nil
+		}
+		p.Status, p.FailureReason = e.Status, e.Reason
+		if err := s.save(p, from); err != nil {
+			return err
+		}
+		return s.settle(p)
+	case payments.StatusCaptured:
+		if from != payments.StatusAuthorized {
+			return nil
+		}
+		p.Status, p.Captured = payments.StatusCaptured, p.Amount
+		if e.Amount > 0 && e.Amount < p.Amount {
+			p.Captured = e.Amount
+		}
+	case payments.StatusVoided:
+		if from != payments.StatusPending && from != payments.StatusAuthorized {
+			return nil
+		}
+		p.Status = payments.StatusVoided
+	case payments.StatusRefunded, payments.StatusPartiallyRefunded:
+		if from != payments.StatusCaptured && from != payments.StatusPartiallyRefunded {
+			return nil
+		}
+		p.Refunded += e.Amount
+		if e.Amount <= 0 || p.Refunded >= p.Captured {
+			p.Refunded = p.Captured
+		}
+		p.Status = payments.StatusPartiallyRefunded
+		if p.Refunded == p.Captured {
+			p.Status = payments.StatusRefunded
+		}
+	default:
+		return fmt.Errorf("%w: unknown status %q", payments.ErrInvalidEvent, e.Status)
+	}
+	return s.save(p, from)
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/payments"
+	"rentacar/backend/internal/repositories"
+)
+
+func newPaymentTestService(t *testing.T) (*ReservationService, *payments.FakeProvider, string, string) {
+	t.Helper()
+	db := newTestDB(t)
+	reservations := &repositories.ReservationRepository{DB: db}
+	provider := &payments.FakeProvider{Secret: "webhook-secret"}
+	svc := &ReservationService{
+		Cars:         &repositories.CarRepository{DB: db},
+		Reservations: reservations,
+		Extras:       &repositories.ExtraRepository{DB: db},
+		Payments:     &PaymentService{Payments: &repositories.PaymentRepository{DB: db}, Reservations: reservations, Provider: provider},
+	}
+	return svc, provider, insertTestCar(t, db), insertTestUser(t, db)
+}
+
+func paymentTestBooking(carID, userID, method string, week int) *models.Reservation {
+	start := time.Date(2027, 3, 1, 10, 0, 0, 0, time.UTC).AddDate(0, 0, 7*week)
+	return &models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: start.AddDate(0, 0, 2), PickupLocation: "A", DropoffLocation: "B", PaymentMethod: method}
+}
+
+// capturedUSD is what payments of kind have taken in USD, net of refunds.
+func capturedUSD(svc *ReservationService, kind string) models.Money {
+	totals, _ := svc.Payments.Payments.Captured(kind)
+	return totals["USD"]
+}
+
+func TestPaymentFollowsReservationLifecycle(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	staff := Actor{UserID: "staff", Username: "staff", Staff: true}
+
+	res := paymentTestBooking(carID, userID, "", 0)
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.Status != "pending" || res.Payment == nil || res.Payment.Status != payments.StatusAuthorized || res.Payment.Amount != 100_00 {
+		t.Fatalf("expected pending booking with 100 authorized, got status=%s payment=%+v", res.Status, res.Payment)
+	}
+	if got := capturedUSD(svc, PaymentRental); got != 0 {
+		t.Fatalf("authorized money is not revenue yet, got %v", got)
+	}
+
+	if _, err := svc.Transition(res.ID, "approved", staff); err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	p, _ := svc.Payments.Payments.Latest(res.ID, PaymentRental)
+	if p.Status != payments.StatusCaptured || p.Captured != 100_00 {
+		t.Fatalf("approval should capture, got %+v", p)
+	}
+	if got := capturedUSD(svc, PaymentRental); got != 100_00 {
+		t.Fatalf("expected revenue 100, got %v", got)
+	}
+
+	if _, err := svc.Transition(res.ID, "cancelled", staff); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	p, _ = svc.Payments.Payments.Latest(res.ID, PaymentRental)
+	if p.Status != payments.StatusRefunded || p.Refunded != 100_00 {
+		t.Fatalf("cancelling a paid booking should refund it, got %+v", p)
+	}
+	if got := capturedUSD(svc, PaymentRental); got != 0 {
+		t.Fatalf("refunds come off revenue, got %v", got)
+	}
+
+	denied := paymentTestBooking(carID, userID, "", 1)
+	if err := svc.Create(denied, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Transition(denied.ID, "denied", staff); err != nil {
+		t.Fatalf("deny: %v", err)
+	}
+	if p, _ := svc.Payments.Payments.Latest(denied.ID, PaymentRental); p.Status != payments.StatusVoided {
+		t.Fatalf("denying should void the authorization, got %+v", p)
+	}
+}
+
+func TestDeclinedPaymentsDoNotConfirmBookings(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+
+	res := paymentTestBooking(carID, userID, payments.FakeMethodDecline, 0)
+	if err := svc.Create(res, nil); !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("expected ErrPaymentDeclined, got %v", err)
+	}
+	if res.Status != "cancelled" || res.Payment.Status != payments.StatusFailed {
+		t.Fatalf("declined booking should be cancelled, got status=%s payment=%+v", res.Status, res.Payment)
+	}
+	if err := svc.Create(paymentTestBooking(carID, userID, "", 0), nil); err != nil {
+		t.Fatalf("declined booking must not hold the car: %v", err)
+	}
+
+	res = paymentTestBooking(carID, userID, payments.FakeMethodDeclineCapture, 1)
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Transition(res.ID, "approved", Actor{UserID: "staff", Staff: true}); !errors.Is(err, ErrPaymentDeclined) {
+		t.Fatalf("expected capture to be declined, got %v", err)
+	}
+	if re, _ := svc.Reservations.GetByID(res.ID); re.Status != "pending" {
+		t.Fatalf("booking must stay pending when capture fails, got %s", re.Status)
+	}
+}
+
+func TestPaymentWebhookConfirmsAsyncPayment(t *testing.T) {
+	svc, provider, carID, userID := newPaymentTestService(t)
+
+	res := paymentTestBooking(carID, userID, payments.FakeMethodAsync, 0)
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.Status != "awaiting_payment" || res.Payment.Status != payments.StatusPending {
+		t.Fatalf("expected booking awaiting payment, got status=%s payment=%+v", res.Status, res.Payment)
+	}
+	if _, err := svc.Transition(res.ID, "approved", Actor{UserID: "staff", Staff: true}); !errors.Is(err, ErrInvalidTransition) {
+		t.Fatalf("unpaid booking must not be approved, got %v", err)
+	}
+
+	body, _ := json.Marshal(payments.Event{ID: "evt_1", Ref: res.Payment.ProviderRef, Status: payments.StatusAuthorized})
+	if err := svc.Payments.HandleWebhook(body, http.Header{payments.FakeSignatureHeader: {"forged"}}); !errors.Is(err, payments.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	header := http.Header{payments.FakeSignatureHeader: {provider.Sign(body)}}
+	for i := 0; i < 2; i++ {
+		if err := svc.Payments.HandleWebhook(body, header); err != nil {
+			t.Fatalf("delivery %d: %v", i+1, err)
+		}
+	}
+	re, _ := svc.Reservations.GetByID(res.ID)
+	p, _ := svc.Payments.Payments.Latest(res.ID, PaymentRental)
+	if re.Status != "pending" || p.Status != payments.StatusAuthorized {
+		t.Fatalf("authorized webhook should confirm payment, got status=%s payment=%+v", re.Status, p)
+	}
+	var events int
+	_ = svc.Reservations.DB.QueryRow(`SELECT COUNT(*) FROM payment_events`).Scan(&events)
+	if events != 1 {
+		t.Fatalf("redelivered event should be recorded once, got %d", events)
+	}
+}
+
+func TestExpiredPaymentHoldFreesTheCar(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+
+	res := paymentTestBooking(carID, userID, payments.FakeMethodAsync, 0)
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.HoldExpiresAt == nil {
+		t.Fatal("a booking awaiting payment should have a hold")
+	}
+	if err := svc.Create(paymentTestBooking(carID, userID, "", 0), nil); !errors.Is(err, ErrCarUnavailable) {
+		t.Fatalf("an unexpired hold keeps the car, got %v", err)
+	}
+	if n, err := svc.Payments.ExpireHolds(time.Now()); err != nil || n != 0 {
+		t.Fatalf("nothing has expired yet, got %d %v", n, err)
+	}
+
+	if n, err := svc.Payments.ExpireHolds(time.Now().Add(time.Hour)); err != nil || n != 1 {
+		t.Fatalf("expected one expired hold, got %d %v", n, err)
+	}
+	re, _ := svc.Reservations.GetByID(res.ID)
+	p, _ := svc.Payments.Payments.Latest(res.ID, PaymentRental)
+	if re.Status != "cancelled" || p.Status != payments.StatusVoided {
+		t.Fatalf("an expired hold should be cancelled and voided, got status=%s payment=%+v", re.Status, p)
+	}
+	if _, err := svc.Payments.Pay(re, ""); !errors.Is(err, ErrNotAwaitingPayment) {
+		t.Fatalf("a cancelled booking cannot be paid, got %v", err)
+	}
+	if err := svc.Create(paymentTestBooking(carID, userID, "", 0), nil); err != nil {
+		t.Fatalf("an expired hold must not keep the car: %v", err)
+	}
+
+	// A hold that ran out before the sweep got to it no longer blocks either.
+	stale := paymentTestBooking(carID, userID, payments.FakeMethodAsync, 1)
+	if err := svc.Create(stale, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := svc.Reservations.DB.Exec(`UPDATE reservations SET hold_expires_at=? WHERE id=?`, time.Now().UTC().Add(-time.Minute), stale.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Create(paymentTestBooking(carID, userID, "", 1), nil); err != nil {
+		t.Fatalf("a lapsed hold must not keep the car: %v", err)
+	}
+}
+
+func TestCapturedIsTotalledPerCurrency(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	staff := Actor{UserID: "staff", Staff: true}
+	for week, currency := range []string{"USD", "EUR"} {
+		res := paymentTestBooking(carID, userID, "", week)
+		if err := svc.Create(res, nil); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+		if _, err := svc.Transition(res.ID, "approved", staff); err != nil {
+			t.Fatalf("approve: %v", err)
+		}
+		if _, err := svc.Reservations.DB.Exec(`UPDATE payments SET currency=? WHERE reservation_id=?`, currency, res.ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	totals, err := svc.Payments.Payments.Captured(PaymentRental)
+	if err != nil {
+		t.Fatalf("captured: %v", err)
+	}
+	if len(totals) != 2 || totals["USD"] != 100_00 || totals["EUR"] != 100_00 {
+		t.Fatalf("expected 100 in each currency, got %v", totals)
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	"rentacar/backend/internal/repositories"
+)
+
+const (
+	PermCarsWrite           = "cars:write"
+	PermBranchesWrite       = "branches:write"
+	PermPricingManage       = "pricing:manage"
+	PermReservationsRead    = "reservations:read"
+	PermReservationsApprove = "reservations:approve"
+	PermDashboardRead       = "dashboard:read"
+	PermAuditRead           = "audit:read"
+	PermUsersRead           = "users:read"
+	PermUsersWrite          = "users:write"
+	PermRolesManage         = "roles:manage"
+)
+
+var Permissions = []string{
+	PermCarsWrite,
+	PermBranchesWrite,
+	PermPricingManage,
+	PermReservationsRead,
+	PermReservationsApprove,
+	PermDashboardRead,
+	PermAuditRead,
+	PermUsersRead,
+	PermUsersWrite,
+	PermRolesManage,
+}
+
+var (
+	ErrInvalidPermission = errors.New("invalid permission")
+	ErrImmutableRole     = errors.New("permissions of this role cannot be changed")
+)
+
+func IsValidPermission(p string) bool {
+	for _, known := range Permissions {
+		if known == p {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionService resolves role permissions. The admin role always holds
+// every permission so nobody can lock the system out of role management;
+// plain users hold none. Assignments for the staff roles in between live in
+// the database and are cached until they change.
+type PermissionService struct {
+	Permissions *repositories.PermissionRepository
+
+	mu     sync.RWMutex
+	byRole map[string]map[string]bool
+}
+
+func (s *PermissionService) load() (map[string]map[string]bool, error) {
+	s.mu.RLock()
+	cached := s.byRole
+	s.mu.RUnlock()
+	if cached != nil {
+		return cached, nil
+	}
+	all, err := s.Permissions.All()
+	if err != nil {
+		return nil, err
+	}
+	byRole := map[string]map[string]bool{}
+	for role, perms := range all {
+		byRole[role] = map[string]bool{}
+		for _, p := range perms {
+			byRole[role][p] = true
+		}
+	}
+	s.mu.Lock()
+	s.byRole = byRole
+	s.mu.Unlock()
+	return byRole, nil
+}
+
+func (s *PermissionService) Has(role, perm string) (bool, error) {
+	if role == "admin" {
+		return true, nil
+	}
+	byRole, err := s.load()
+	if err != nil {
+		return false, err
+	}
+	return byRole[role][perm], nil
+}
+
+func (s *PermissionService) ForRole(role string) ([]string, error) {
+	if role == "admin" {
+		return append([]string(nil), Permissions...), nil
+	}
+	byRole, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	out := []string{}
+	for p := range byRole[role] {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// Assignments lists the permissions of every known role.
+func (s *PermissionService) Assignments() (map[string][]string, error) {
+	out := map[string][]string{}
+	for _, role := range Roles {
+		perms, err := s.ForRole(role)
+		if err != nil {
+			return nil, err
+		}
+		out[role] = perms
+	}
+	return out, nil
+}
+
+func (s *PermissionService) SetRolePermissions(role string, perms []string) ([]string, error) {
+	if !IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	if role == "admin" || role == "user" {
+		return nil, ErrImmutableRole
+	}
+	for _, p := range perms {
+		if !IsValidPermission(p) {
+			return nil, ErrInvalidPermission
+		}
+	}
+	if err := s.Permissions.Replace(role, perms); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	s.byRole = nil
+	s.mu.Unlock()
+	return s.ForRole(role)
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"rentacar/backend/internal/repositories"
+)
+
+func TestPermissionServiceRoleAssignments(t *testing.T) {
+	db := newTestDB(t)
+	svc := &PermissionService{Permissions: &repositories.PermissionRepository{DB: db}}
+
+	check := func(role, perm string, want bool) {
+		t.Helper()
+		got, err := svc.Has(role, perm)
+		if err != nil {
+			t.Fatalf("Has(%s, %s): %v", role, perm, err)
+		}
+		if got != want {
+			t.Fatalf("Has(%s, %s) = %t, want %t", role, perm, got, want)
+		}
+	}
+	check("agent", PermReservationsApprove, true)
+	check("agent", PermCarsWrite, false)
+	check("agent", PermAuditRead, false)
+	check("fleet_manager", PermCarsWrite, true)
+	check("admin", PermRolesManage, true)
+	check("user", PermReservationsRead, false)
+
+	if _, err := svc.SetRolePermissions("agent", []string{PermReservationsRead, PermAuditRead}); err != nil {
+		t.Fatalf("set agent permissions: %v", err)
+	}
+	check("agent", PermAuditRead, true)
+	check("agent", PermReservationsApprove, false)
+
+	if _, err := svc.SetRolePermissions("admin", nil); !errors.Is(err, ErrImmutableRole) {
+		t.Fatalf("expected ErrImmutableRole, got %v", err)
+	}
+	if _, err := svc.SetRolePermissions("agent", []string{"cars:fly"}); !errors.Is(err, ErrInvalidPermission) {
+		t.Fatalf("expected ErrInvalidPermission, got %v", err)
+	}
+}
@@ -0,0 +1,293 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+// Pricing rule kinds.
+const (
+	RuleSeason      = "season"
+	RuleWeekend     = "weekend"
+	RuleLength      = "length"
+	RuleEarlyBird   = "early_bird"
+	RuleLastMinute  = "last_minute"
+	RuleYoungDriver = "young_driver"
+)
+
+var RuleKinds = []string{RuleSeason, RuleWeekend, RuleLength, RuleEarlyBird, RuleLastMinute, RuleYoungDriver}
+
+// Line item kinds.
+const (
+	LineBase       = "base"
+	LineAdjustment = "adjustment"
+	LineExtra      = "extra"
+	LineFee        = "fee"
+	LineDiscount   = "discount"
+	LineTax        = "tax"
+	LineDeposit    = "deposit"
+)
+
+var ErrInvalidPricingRule = errors.New("invalid pricing rule")
+
+// PriceBreakdown is what a rental costs and why, in the car's currency. The
+// deposit is listed as a line but is refundable and not part of Total.
+type PriceBreakdown struct {
+	Currency string            `json:"currency"`
+	Days     int               `json:"days"`
+	Hours    int               `json:"hours"`
+	Lines    []models.LineItem `json:"lines"`
+	Discount models.Money      `json:"discount,omitempty"`
+	Subtotal models.Money      `json:"subtotal"`
+	Tax      models.Money      `json:"tax"`
+	Total    models.Money      `json:"total"`
+	Deposit  models.Money      `json:"deposit"`
+}
+
+// PricingInput is everything the rules may look at. BookedAt drives the
+// early-bird and last-minute rules; DriverBirthDate the young-driver fee,
+// which is skipped when the age is unknown. Branch, the pickup location,
+// decides the tax rate.
+type PricingInput struct {
+	Car             *models.Car
+	Extras          []models.Extra
+	Start, End      time.Time
+	BookedAt        time.Time
+	DriverBirthDate *time.Time
+	Branch          *models.Branch
+	// Deposit is listed after the total; Price fills it in from the car's
+	// category.
+	Deposit models.Money
+	// Promo is a promo code already checked to apply to this rental.
+	Promo *models.PromoCode
+}
+
+// priceUnit is one billed day, or the trailing part-day, and its rate.
+type priceUnit struct {
+	day  time.Time
+	rate models.Money
+}
+
+func perUnit(kind, code, label string, qty int, unit models.Money) models.LineItem {
+	return models.LineItem{Kind: kind, Code: code, Label: label, Quantity: qty, UnitPrice: unit, Amount: unit.Times(qty)}
+}
+
+func single(kind, code, label string, amount models.Money) models.LineItem {
+	return models.LineItem{Kind: kind, Code: code, Label: label, Quantity: 1, UnitPrice: amount, Amount: amount}
+}
+
+// Charge prices a rental without any pricing rules.
+func (p RentalPolicy) Charge(car *models.Car, extras []models.Extra, start, end time.Time) PriceBreakdown {
+	return p.Price(nil, PricingInput{Car: car, Extras: extras, Start: start, End: end})
+}
+
+// Price evaluates rules in order. Every billed day starts at the car's daily
+// price (the trailing part-day at its hourly price); season and weekend
+// rules change the rate of the days they cover, length, early-bird and
+// last-minute rules change every day, each on top of the rules before it.
+// Only the matching length rule with the most days applies, so a monthly
+// rate replaces the weekly one. Young-driver fees and extras are charged per
+// started day and are not affected by percentages; fees in another currency
+// than the car's are skipped. Every change is rounded to the minor unit.
+func (p RentalPolicy) Price(rules []models.PricingRule, in PricingInput) PriceBreakdown {
+	days, hours := p.billable(in.Start, in.End)
+	units := make([]priceUnit, 0, days+1)
+	for i := 0; i < days; i++ {
+		units = append(units, priceUnit{day: in.Start.AddDate(0, 0, i), rate: in.Car.DailyPrice})
+	}
+	if hours > 0 {
+		units = append(units, priceUnit{day: in.Start.AddDate(0, 0, days), rate: hourlyPrice(in.Car, hours)})
+	}
+	out := PriceBreakdown{Currency: in.Car.Currency, Days: days, Hours: hours, Lines: []models.LineItem{}}
+	if days > 0 {
+		out.Lines = append(out.Lines, perUnit(LineBase, "daily", "Daily rate", days, in.Car.DailyPrice))
+	}
+	if hours > 0 {
+		out.Lines = append(out.Lines, single(LineBase, "hourly", fmt.Sprintf("%d hours", hours), units[len(units)-1].rate))
+	}
+	chargedDays := len(units)
+	if chargedDays == 0 {
+		chargedDays = 1
+	}
+
+	length := longestLengthRule(rules, in.Car, days)
+	for i := range rules {
+		r := &rules[i]
+		if !r.Active || (r.Category != "" && !strings.EqualFold(r.Category, in.Car.Category)) {
+			continue
+		}
+		if r.Kind == RuleYoungDriver {
+			if r.Currency == in.Car.Currency && in.DriverBirthDate != nil && ageAt(*in.DriverBirthDate, in.Start) < r.MaxDriverAge {
+				out.Lines = append(out.Lines, perUnit(LineFee, r.Kind, r.Name, chargedDays, r.AmountPerDay))
+			}
+			continue
+		}
+		if r.Kind == RuleLength && r != length {
+			continue
+		}
+		var delta models.Money
+		for u := range units {
+			if !ruleCovers(r, units[u].day, in) {
+				continue
+			}
+			change := units[u].rate.Percent(r.Percent)
+			units[u].rate += change
+			delta += change
+		}
+		if delta != 0 {
+			out.Lines = append(out.Lines, single(LineAdjustment, r.Kind, r.Name, delta))
+		}
+	}
+
+	for _, e := range in.Extras {
+		out.Lines = append(out.Lines, perUnit(LineExtra, e.ID, e.Name, chargedDays, e.PricePerDay))
+	}
+	for _, l := range out.Lines {
+		out.Subtotal += l.Amount
+	}
+	out.Total = out.Subtotal
+	return out
+}
+
+// ActivePricingRules returns the rules Price evaluates, in order.
+func (s *ReservationService) ActivePricingRules() ([]models.PricingRule, error) {
+	if s.PricingRules == nil {
+		return nil, nil
+	}
+	return s.PricingRules.List(true)
+}
+
+// Price runs the active pricing rules for a rental and adds tax and the
+// deposit. Bookings, quotes and search results all go through here.
+func (s *ReservationService) Price(in PricingInput) (PriceBreakdown, error) {
+	rules, err := s.ActivePricingRules()
+	if err != nil {
+		return PriceBreakdown{}, err
+	}
+	if in.Deposit, err = s.DepositFor(in.Car); err != nil {
+		return PriceBreakdown{}, err
+	}
+	return s.PriceWith(rules, in), nil
+}
+
+// PriceWith is Price with already loaded rules. A promo code comes off
+// before tax.
+func (s *ReservationService) PriceWith(rules []models.PricingRule, in PricingInput) PriceBreakdown {
+	b := s.Policy.Price(rules, in)
+	if d := promoDiscount(in.Promo, b.Subtotal); d > 0 {
+		b.Discount = d
+		b.Lines = append(b.Lines, single(LineDiscount, "promo", "Promo code "+in.Promo.Code, -d))
+		b.Subtotal -= d
+		b.Total = b.Subtotal
+	}
+	if rate, name := s.taxFor(in.Branch); rate > 0 {
+		b.Tax = b.Subtotal.Percent(rate)
+		b.Lines = append(b.Lines, single(LineTax, "tax", fmt.Sprintf("%s %g%%", name, rate), b.Tax))
+		b.Total = b.Subtotal + b.Tax
+	}
+	if in.Deposit > 0 {
+		b.Deposit = in.Deposit
+		b.Lines = append(b.Lines, single(LineDeposit, "deposit", "Refundable deposit", in.Deposit))
+	}
+	return b
+}
+
+// ruleCovers reports whether a rate rule applies to the billed day starting
+// at day. Dates are read in the zone of the pickup time.
+func ruleCovers(r *models.PricingRule, day time.Time, in PricingInput) bool {
+	switch r.Kind {
+	case RuleSeason:
+		return inSeason(day.Format("01-02"), r.SeasonStart, r.SeasonEnd)
+	case RuleWeekend:
+		return day.Weekday() == time.Saturday || day.Weekday() == time.Sunday
+	case RuleLength:
+		return true
+	case RuleEarlyBird:
+		return in.Start.Sub(in.BookedAt) >= time.Duration(r.MinLeadDays)*24*time.Hour
+	case RuleLastMinute:
+		return in.Start.Sub(in.BookedAt) < time.Duration(r.MaxLeadHours)*time.Hour
+	}
+	return false
+}
+
+// inSeason compares MM-DD strings; a season may wrap the new year.
+func inSeason(md, from, to string) bool {
+	if from <= to {
+		return md >= from && md <= to
+	}
+	return md >= from || md <= to
+}
+
+func longestLengthRule(rules []models.PricingRule, car *models.Car, days int) *models.PricingRule {
+	var best *models.PricingRule
+	for i := range rules {
+		r := &rules[i]
+		if r.Kind != RuleLength || !r.Active || days < r.MinDays || (r.Category != "" && !strings.EqualFold(r.Category, car.Category)) {
+			continue
+		}
+		if best == nil || r.MinDays > best.MinDays {
+			best = r
+		}
+	}
+	return best
+}
+
+func ageAt(birth, at time.Time) int {
+	age := at.Year() - birth.Year()
+	if at.Month() < birth.Month() || (at.Month() == birth.Month() && at.Day() < birth.Day()) {
+		age--
+	}
+	return age
+}
+
+func validMonthDay(v string) bool {
+	_, err := time.Parse("01-02", v)
+	return err == nil
+}
+
+// ValidatePricingRule checks the fields the rule's kind relies on.
+func ValidatePricingRule(r *models.PricingRule) error {
+	r.Name = strings.TrimSpace(r.Name)
+	r.Category = strings.ToLower(strings.TrimSpace(r.Category))
+	invalid := func(msg string) error { return fmt.Errorf("%w: %s", ErrInvalidPricingRule, msg) }
+	if r.Name == "" {
+		return invalid("name is required")
+	}
+	if r.Percent <= -100 || r.Percent > 1000 {
+		return invalid("percent must be greater than -100 and at most 1000")
+	}
+	switch r.Kind {
+	case RuleSeason:
+		if !validMonthDay(r.SeasonStart) || !validMonthDay(r.SeasonEnd) {
+			return invalid("seasonStart and seasonEnd must be MM-DD")
+		}
+	case RuleWeekend:
+	case RuleLength:
+		if r.MinDays < 2 {
+			return invalid("minDays must be at least 2")
+		}
+	case RuleEarlyBird:
+		if r.MinLeadDays < 1 {
+			return invalid("minLeadDays must be at least 1")
+		}
+	case RuleLastMinute:
+		if r.MaxLeadHours < 1 {
+			return invalid("maxLeadHours must be at least 1")
+		}
+	case RuleYoungDriver:
+		if r.MaxDriverAge < 18 || r.AmountPerDay <= 0 {
+			return invalid("maxDriverAge must be at least 18 and amountPerDay greater than 0")
+		}
+		return nil
+	default:
+		return invalid("kind must be one of " + strings.Join(RuleKinds, ", "))
+	}
+	if r.Percent == 0 {
+		return invalid("percent is required")
+	}
+	return nil
+}
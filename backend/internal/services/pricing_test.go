@@ -0,0 +1,224 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestRentalPolicyPrice(t *testing.T) {
+	car := &models.Car{DailyPrice: 100_00, Category: "suv"}
+	at := func(m time.Month, d int) time.Time { return time.Date(2026, m, d, 10, 0, 0, 0, time.UTC) }
+	birth := func(y int) *time.Time { b := time.Date(y, 3, 1, 0, 0, 0, 0, time.UTC); return &b }
+	rule := func(r models.PricingRule) models.PricingRule { r.Active = true; return r }
+	weekend := rule(models.PricingRule{Name: "Weekend", Kind: RuleWeekend, Percent: 20})
+	summer := rule(models.PricingRule{Name: "Summer", Kind: RuleSeason, Percent: 50, SeasonStart: "07-01", SeasonEnd: "08-31"})
+	holidays := rule(models.PricingRule{Name: "Holidays", Kind: RuleSeason, Percent: 30, SeasonStart: "12-20", SeasonEnd: "01-10"})
+	weekly := rule(models.PricingRule{Name: "Weekly", Kind: RuleLength, Percent: -10, MinDays: 7})
+	monthly := rule(models.PricingRule{Name: "Monthly", Kind: RuleLength, Percent: -25, MinDays: 28})
+	earlyBird := rule(models.PricingRule{Name: "Early bird", Kind: RuleEarlyBird, Percent: -15, MinLeadDays: 30})
+	lastMinute := rule(models.PricingRule{Name: "Last minute", Kind: RuleLastMinute, Percent: 10, MaxLeadHours: 24})
+	youngDriver := rule(models.PricingRule{Name: "Young driver", Kind: RuleYoungDriver, AmountPerDay: 15_00, MaxDriverAge: 25})
+	sedanOnly := rule(models.PricingRule{Name: "Sedan weekend", Kind: RuleWeekend, Percent: 20, Category: "sedan"})
+	inactive := weekend
+	inactive.Active = false
+
+	cases := []struct {
+		name       string
+		rules      []models.PricingRule
+		start, end time.Time
+		bookedAt   time.Time
+		birth      *time.Time
+		extras     []models.Extra
+		total      models.Money
+		lines      int
+	}{
+		{"no rules", nil, at(7, 3), at(7, 6), at(6, 1), nil, nil, 300_00, 1},
+		{"weekend days only", []models.PricingRule{weekend}, at(7, 3), at(7, 6), at(6, 1), nil, nil, 340_00, 2},
+		{"season covers part of the rental", []models.PricingRule{summer}, at(6, 30), at(7, 2), at(6, 1), nil, nil, 250_00, 2},
+		{"season wraps the new year", []models.PricingRule{holidays}, time.Date(2026, 12, 30, 10, 0, 0, 0, time.UTC), time.Date(2027, 1, 2, 10, 0, 0, 0, time.UTC), at(6, 1), nil, nil, 390_00, 2},
+		{"weekly rate", []models.PricingRule{weekly, monthly}, at(7, 6), at(7, 14), at(6, 1), nil, nil, 720_00, 2},
+		{"monthly rate replaces weekly", []models.PricingRule{weekly, monthly}, at(7, 6), at(8, 5), at(6, 1), nil, nil, 2250_00, 2},
+		{"rules compound in order", []models.PricingRule{weekend, weekly}, at(7, 6), at(7, 14), at(6, 1), nil, nil, 756_00, 3},
+		{"early bird", []models.PricingRule{earlyBird}, at(7, 3), at(7, 6), at(5, 20), nil, nil, 255_00, 2},
+		{"early bird not reached", []models.PricingRule{earlyBird}, at(7, 3), at(7, 6), at(6, 25), nil, nil, 300_00, 1},
+		{"last minute", []models.PricingRule{lastMinute}, at(7, 3), at(7, 6), at(7, 3).Add(-2 * time.Hour), nil, nil, 330_00, 2},
+		{"young driver fee", []models.PricingRule{youngDriver}, at(7, 3), at(7, 6), at(6, 1), birth(2004), nil, 345_00, 2},
+		{"driver old enough", []models.PricingRule{youngDriver}, at(7, 3), at(7, 6), at(6, 1), birth(1996), nil, 300_00, 1},
+		{"driver age unknown", []models.PricingRule{youngDriver}, at(7, 3), at(7, 6), at(6, 1), nil, nil, 300_00, 1},
+		{"other category", []models.PricingRule{sedanOnly}, at(7, 3), at(7, 6), at(6, 1), nil, nil, 300_00, 1},
+		{"inactive rule", []models.PricingRule{inactive}, at(7, 3), at(7, 6), at(6, 1), nil, nil, 300_00, 1},
+		{"extras are not adjusted", []models.PricingRule{weekend}, at(7, 3), at(7, 6), at(6, 1), nil, []models.Extra{{ID: "gps", Name: "GPS", PricePerDay: 10_00}}, 370_00, 3},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := RentalPolicy{}.Price(tc.rules, PricingInput{Car: car, Extras: tc.extras, Start: tc.start, End: tc.end, BookedAt: tc.bookedAt, DriverBirthDate: tc.birth})
+			if got.Total != tc.total || len(got.Lines) != tc.lines {
+				t.Fatalf("got total=%v lines=%+v, want total=%v with %d lines", got.Total, got.Lines, tc.total, tc.lines)
+			}
+		})
+	}
+}
+
+func TestValidatePricingRule(t *testing.T) {
+	cases := []struct {
+		name string
+		rule models.PricingRule
+		ok   bool
+	}{
+		{"season", models.PricingRule{Name: "Summer", Kind: RuleSeason, Percent: 20, SeasonStart: "06-15", SeasonEnd: "09-15"}, true},
+		{"season without dates", models.PricingRule{Name: "Summer", Kind: RuleSeason, Percent: 20}, false},
+		{"weekend without percent", models.PricingRule{Name: "Weekend", Kind: RuleWeekend}, false},
+		{"length of one day", models.PricingRule{Name: "Daily", Kind: RuleLength, Percent: -5, MinDays: 1}, false},
+		{"full discount", models.PricingRule{Name: "Free", Kind: RuleWeekend, Percent: -100}, false},
+		{"young driver", models.PricingRule{Name: "Young", Kind: RuleYoungDriver, AmountPerDay: 10_00, MaxDriverAge: 25}, true},
+		{"unknown kind", models.PricingRule{Name: "Moon", Kind: "full_moon", Percent: 5}, false},
+		{"missing name", models.PricingRule{Kind: RuleWeekend, Percent: 5}, false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidatePricingRule(&tc.rule)
+			if tc.ok && err != nil {
+				t.Fatalf("expected valid, got %v", err)
+			}
+			if !tc.ok && !errors.Is(err, ErrInvalidPricingRule) {
+				t.Fatalf("expected ErrInvalidPricingRule, got %v", err)
+			}
+		})
+	}
+}
+
+func TestCreateReservationAppliesPricingRules(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	rules := &repositories.PricingRuleRepository{DB: db}
+	if err := rules.Create(&models.PricingRule{Name: "Weekend", Kind: RuleWeekend, Percent: 20, Active: true}); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}, PricingRules: rules}
+
+	// Friday to Monday at 50 per day: Saturday and Sunday cost 60.
+	start := time.Date(2027, 7, 2, 10, 0, 0, 0, time.UTC)
+	res := &models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: start.AddDate(0, 0, 3)}
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.TotalPrice != 170_00 {
+		t.Fatalf("expected 170, got %v", res.TotalPrice)
+	}
+}
+
+func TestReservationKeepsBookedLineItems(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	if _, err := db.Exec(`INSERT INTO extras(id, name, price_per_day_minor) VALUES('gps', 'GPS', 1000)`); err != nil {
+		t.Fatalf("insert extra: %v", err)
+	}
+	repo := &repositories.ReservationRepository{DB: db}
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: repo, Extras: &repositories.ExtraRepository{DB: db}, TaxRate: 10}
+
+	start := time.Date(2027, 7, 5, 10, 0, 0, 0, time.UTC)
+	res := &models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: start.AddDate(0, 0, 2)}
+	if err := svc.Create(res, []string{"gps"}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE extras SET name='Navigation', price_per_day_minor=2500 WHERE id='gps'`); err != nil {
+		t.Fatalf("update extra: %v", err)
+	}
+	if _, err := db.Exec(`UPDATE cars SET daily_price_minor=9000 WHERE id=?`, carID); err != nil {
+		t.Fatalf("update car: %v", err)
+	}
+
+	lines, err := repo.LineItems(res.ID)
+	if err != nil {
+		t.Fatalf("line items: %v", err)
+	}
+	want := []models.LineItem{
+		{Kind: LineBase, Code: "daily", Label: "Daily rate", Quantity: 2, UnitPrice: 50_00, Amount: 100_00},
+		{Kind: LineExtra, Code: "gps", Label: "GPS", Quantity: 2, UnitPrice: 10_00, Amount: 20_00},
+		{Kind: LineTax, Code: "tax", Label: "VAT 10%", Quantity: 1, UnitPrice: 12_00, Amount: 12_00},
+	}
+	if len(lines) != len(want) {
+		t.Fatalf("expected %d lines, got %+v", len(want), lines)
+	}
+	for i := range want {
+		if lines[i] != want[i] {
+			t.Fatalf("line %d: expected %+v, got %+v", i, want[i], lines[i])
+		}
+	}
+	extras, err := repo.ExtrasForReservation(res.ID)
+	if err != nil {
+		t.Fatalf("extras: %v", err)
+	}
+	if len(extras) != 1 || extras[0].Name != "GPS" || extras[0].PricePerDay != 10_00 {
+		t.Fatalf("expected booked GPS at 10, got %+v", extras)
+	}
+}
+
+func TestCreateReservationUsesBranchTaxAndCurrency(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	if _, err := db.Exec(`INSERT INTO extras(id, name, price_per_day_minor, currency) VALUES('seat', 'Child seat', 500, 'EUR')`); err != nil {
+		t.Fatalf("insert extra: %v", err)
+	}
+	branches := &repositories.BranchRepository{DB: db}
+	rate := 17.0
+	for _, b := range []*models.Branch{{Name: "Airport", Timezone: "UTC", TaxRate: &rate, TaxName: "PDV"}, {Name: "Downtown", Timezone: "UTC"}} {
+		if err := branches.Create(b); err != nil {
+			t.Fatalf("create branch: %v", err)
+		}
+	}
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}, Branches: branches, TaxRate: 10}
+
+	// One day at 50 per week of July, taxed where the car is picked up.
+	start := time.Date(2027, 7, 5, 10, 0, 0, 0, time.UTC)
+	book := func(branch string, week int) *models.Reservation {
+		day := start.AddDate(0, 0, 7*week)
+		return &models.Reservation{CarID: carID, UserID: userID, PickupLocation: branch, StartDate: day, EndDate: day.AddDate(0, 0, 1)}
+	}
+	for i, tc := range []struct {
+		branch string
+		total  models.Money
+		label  string
+	}{
+		{"Airport", 58_50, "PDV 17%"},
+		{"Downtown", 55_00, "VAT 10%"},
+	} {
+		res := book(tc.branch, i)
+		if err := svc.Create(res, nil); err != nil {
+			t.Fatalf("%s: create: %v", tc.branch, err)
+		}
+		last := res.LineItems[len(res.LineItems)-1]
+		if res.TotalPrice != tc.total || res.Currency != DefaultCurrency || last.Kind != LineTax || last.Label != tc.label {
+			t.Fatalf("%s: got total=%v currency=%q tax line %+v", tc.branch, res.TotalPrice, res.Currency, last)
+		}
+	}
+
+	if err := svc.Create(book("Downtown", 2), []string{"seat"}); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("extra in another currency: expected ErrCurrencyMismatch, got %v", err)
+	}
+	svc.Currency = "EUR"
+	if err := svc.Create(book("Downtown", 2), nil); !errors.Is(err, ErrCurrencyMismatch) {
+		t.Fatalf("car in the previous currency: expected ErrCurrencyMismatch, got %v", err)
+	}
+}
+
+func TestBaseCurrencyMustHaveTwoDecimals(t *testing.T) {
+	if code, err := NormalizeBaseCurrency(" eur "); err != nil || code != "EUR" {
+		t.Fatalf("expected EUR, got %q %v", code, err)
+	}
+	for _, code := range []string{"JPY", "KWD", "ABC"} {
+		if _, err := NormalizeBaseCurrency(code); !errors.Is(err, ErrUnsupportedCurrency) {
+			t.Fatalf("%s: expected ErrUnsupportedCurrency, got %v", code, err)
+		}
+	}
+	if _, err := NormalizeBaseCurrency("EU"); !errors.Is(err, ErrInvalidCurrency) {
+		t.Fatalf("expected ErrInvalidCurrency, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+// Promo code kinds.
+const (
+	PromoPercent = "percent"
+	PromoFixed   = "fixed"
+)
+
+var (
+	ErrInvalidPromoCode  = errors.New("invalid promo code")
+	ErrPromoCodeNotValid = errors.New("promo code cannot be used for this booking")
+	ErrPromoCodeUsedUp   = repositories.ErrPromoCodeUsedUp
+)
+
+// NormalizePromoCode is how codes are stored and looked up, so customers
+// can type them in any case.
+func NormalizePromoCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidatePromoCode checks an admin's promo code. Fixed amounts are in the
+// shop currency, like every other price.
+func ValidatePromoCode(p *models.PromoCode, currency string) error {
+	p.Code = NormalizePromoCode(p.Code)
+	p.Kind = strings.ToLower(strings.TrimSpace(p.Kind))
+	invalid := func(msg string) error { return fmt.Errorf("%w: %s", ErrInvalidPromoCode, msg) }
+	if p.Code == "" || strings.ContainsAny(p.Code, " \t") {
+		return invalid("code is required and cannot contain spaces")
+	}
+	switch p.Kind {
+	case PromoPercent:
+		if p.Percent <= 0 || p.Percent > 100 {
+			return invalid("percent must be above 0 and at most 100")
+		}
+		p.Amount = 0
+	case PromoFixed:
+		if p.Amount <= 0 {
+			return invalid("amount must be positive")
+		}
+		p.Percent = 0
+	default:
+		return invalid("kind must be percent or fixed")
+	}
+	p.Currency = currency
+	if p.MinDays < 0 || p.MaxUses < 0 || p.MaxUsesPerUser < 0 {
+		return invalid("minDays and usage limits cannot be negative")
+	}
+	if p.ValidFrom != nil && p.ValidTo != nil && !p.ValidTo.After(*p.ValidFrom) {
+		return invalid("validTo must be after validFrom")
+	}
+	categories := []string{}
+	seen := map[string]bool{}
+	for _, c := range p.Categories {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c != "" && !seen[c] {
+			seen[c] = true
+			categories = append(categories, c)
+		}
+	}
+	p.Categories = categories
+	return nil
+}
+
+// promoDiscount is what a promo code takes off subtotal; a fixed amount
+// never takes it below zero.
+func promoDiscount(p *models.PromoCode, subtotal models.Money) models.Money {
+	if p == nil || subtotal <= 0 {
+		return 0
+	}
+	d := p.Amount
+	if p.Kind == PromoPercent {
+		d = subtotal.Percent(p.Percent)
+	}
+	if d > subtotal {
+		d = subtotal
+	}
+	return d
+}
+
+// promoFor looks up the promo code of a booking made now and checks that it
+// applies. The usage limits are checked again when the booking is saved.
+func (s *ReservationService) promoFor(res *models.Reservation, car *models.Car, now time.Time) (*models.PromoCode, error) {
+	res.PromoCode = NormalizePromoCode(res.PromoCode)
+	res.PromoCodeID = ""
+	if res.PromoCode == "" {
+		return nil, nil
+	}
+	notValid := func(msg string) error { return fmt.Errorf("%w: %s", ErrPromoCodeNotValid, msg) }
+	if s.PromoCodes == nil {
+		return nil, notValid("unknown code")
+	}
+	p, err := s.PromoCodes.GetByCode(res.PromoCode)
+	if IsNotFound(err) || (err == nil && !p.Active) {
+		return nil, notValid("unknown code")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if (p.ValidFrom != nil && now.Before(*p.ValidFrom)) || (p.ValidTo != nil && !now.Before(*p.ValidTo)) {
+		return nil, notValid("the code is not valid at this time")
+	}
+	if days, _ := s.Policy.billable(res.StartDate, res.EndDate); days < p.MinDays {
+		return nil, notValid(fmt.Sprintf("the rental must be at least %d days", p.MinDays))
+	}
+	if len(p.Categories) > 0 && !slices.Contains(p.Categories, strings.ToLower(car.Category)) {
+		return nil, notValid("the code does not cover this car")
+	}
+	if p.Kind == PromoFixed && p.Currency != car.Currency {
+		return nil, notValid("the code is in another currency")
+	}
+	if p.MaxUses > 0 && p.Uses >= p.MaxUses {
+		return nil, ErrPromoCodeUsedUp
+	}
+	if p.MaxUsesPerUser > 0 {
+		n, err := s.PromoCodes.UsesBy(p.ID, res.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if n >= p.MaxUsesPerUser {
+			return nil, ErrPromoCodeUsedUp
+		}
+	}
+	res.PromoCodeID = p.ID
+	return p, nil
+}
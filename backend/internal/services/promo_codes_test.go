@@ -0,0 +1,56 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestPromoCodeDiscountsAndLimitsBookings(t *testing.T) {
+	svc, _, carID, userID := newPaymentTestService(t)
+	svc.PromoCodes = &repositories.PromoCodeRepository{DB: svc.Reservations.DB}
+	promo := &models.PromoCode{Code: " spring10 ", Kind: PromoPercent, Percent: 10, MinDays: 2, MaxUsesPerUser: 1, Categories: []string{"Sedan"}, Active: true}
+	if err := ValidatePromoCode(promo, svc.BaseCurrency()); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if err := svc.PromoCodes.Create(promo); err != nil {
+		t.Fatalf("create promo code: %v", err)
+	}
+
+	short := paymentTestBooking(carID, userID, "", 0)
+	short.EndDate = short.StartDate.AddDate(0, 0, 1)
+	short.PromoCode = "spring10"
+	if err := svc.Create(short, nil); !errors.Is(err, ErrPromoCodeNotValid) {
+		t.Fatalf("a one day rental is below minDays, got %v", err)
+	}
+
+	res := paymentTestBooking(carID, userID, "", 1)
+	res.PromoCode = "spring10"
+	if err := svc.Create(res, nil); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if res.Discount != 10_00 || res.TotalPrice != 90_00 || res.Payment.Amount != 90_00 {
+		t.Fatalf("expected 10 off 100, got discount=%v total=%v payment=%+v", res.Discount, res.TotalPrice, res.Payment)
+	}
+	re, _ := svc.Reservations.GetByID(res.ID)
+	if re.PromoCode != "SPRING10" || re.Discount != 10_00 {
+		t.Fatalf("the booking should record its code and discount, got %q %v", re.PromoCode, re.Discount)
+	}
+
+	again := paymentTestBooking(carID, userID, "", 2)
+	again.PromoCode = "SPRING10"
+	if err := svc.Create(again, nil); !errors.Is(err, ErrPromoCodeUsedUp) {
+		t.Fatalf("the code allows one use per user, got %v", err)
+	}
+	if _, err := svc.Transition(res.ID, "cancelled", Actor{UserID: userID}); err != nil {
+		t.Fatalf("cancel: %v", err)
+	}
+	if err := svc.Create(again, nil); err != nil {
+		t.Fatalf("cancelling should give the use back, got %v", err)
+	}
+	if items, _ := svc.PromoCodes.List(); items[0].Uses != 1 || items[0].Discounted != 10_00 {
+		t.Fatalf("expected one standing use worth 10, got %+v", items[0])
+	}
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/models"
+)
+
+const DefaultQuoteTTL = 30 * time.Minute
+
+var ErrInvalidQuote = errors.New("quote is invalid, expired or does not match the booking")
+
+// Quote is a priced booking that has not been made yet. ID is a signed
+// token that POST /reservations accepts as quoteId until ExpiresAt.
+type Quote struct {
+	ID             string    `json:"quoteId"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	CarID          string    `json:"carId"`
+	StartDate      time.Time `json:"startDate"`
+	EndDate        time.Time `json:"endDate"`
+	PickupLocation string    `json:"pickupLocation"`
+	Timezone       string    `json:"timezone"`
+	PromoCode      string    `json:"promoCode,omitempty"`
+	PriceBreakdown
+	CancellationPolicy *models.CancellationPolicy `json:"cancellationPolicy,omitempty"`
+}
+
+func (s *ReservationService) quoteTTL() time.Duration {
+	if s.QuoteTTL > 0 {
+		return s.QuoteTTL
+	}
+	return DefaultQuoteTTL
+}
+
+func sortedIDs(ids []string) []string {
+	out := append([]string(nil), ids...)
+	sort.Strings(out)
+	return out
+}
+
+// Quote prices a booking exactly like Create would, without saving it.
+func (s *ReservationService) Quote(res *models.Reservation, extraIDs []string, now time.Time) (*Quote, error) {
+	price, err := s.prepare(res, extraIDs, now)
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(price)
+	if err != nil {
+		return nil, err
+	}
+	claims := auth.QuoteClaims{UserID: res.UserID, CarID: res.CarID, Pickup: res.PickupLocation, Start: res.StartDate.Unix(), End: res.EndDate.Unix(), ExtraIDs: sortedIDs(extraIDs), Promo: res.PromoCode, Price: encoded}
+	id, exp, err := auth.GenerateQuoteToken(s.QuoteSecret, claims, s.quoteTTL())
+	if err != nil {
+		return nil, err
+	}
+	return &Quote{ID: id, ExpiresAt: exp, CarID: res.CarID, StartDate: res.StartDate, EndDate: res.EndDate, PickupLocation: res.PickupLocation, Timezone: res.Timezone, PromoCode: res.PromoCode, PriceBreakdown: price, CancellationPolicy: res.CancellationPolicy}, nil
+}
+
+// quotedPrice checks that res.QuoteID was issued to the same user for the
+// same car, times, pickup location, extras and promo code, and returns its
+// breakdown.
+func (s *ReservationService) quotedPrice(res *models.Reservation, extraIDs []string) (PriceBreakdown, error) {
+	var price PriceBreakdown
+	q, err := auth.ParseQuoteToken(s.QuoteSecret, res.QuoteID)
+	if err != nil {
+		return price, ErrInvalidQuote
+	}
+	ids := sortedIDs(extraIDs)
+	if q.UserID != res.UserID || q.CarID != res.CarID || q.Pickup != res.PickupLocation || q.Start != res.StartDate.Unix() || q.End != res.EndDate.Unix() || q.Promo != res.PromoCode || len(ids) != len(q.ExtraIDs) {
+		return price, ErrInvalidQuote
+	}
+	for i := range ids {
+		if ids[i] != q.ExtraIDs[i] {
+			return price, ErrInvalidQuote
+		}
+	}
+	if err := json.Unmarshal(q.Price, &price); err != nil {
+		return price, ErrInvalidQuote
+	}
+	return price, nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestQuoteMatchesBookingAndIsHonoured(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	otherID := insertTestUser(t, db)
+	if _, err := db.Exec(`INSERT INTO extras(id, name, price_per_day_minor) VALUES('gps', 'GPS', 1000)`); err != nil {
+		t.Fatalf("insert extra: %v", err)
+	}
+	rules := &repositories.PricingRuleRepository{DB: db}
+	weekend := &models.PricingRule{Name: "Weekend", Kind: RuleWeekend, Percent: 20, Active: true}
+	if err := rules.Create(weekend); err != nil {
+		t.Fatalf("create rule: %v", err)
+	}
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}, PricingRules: rules, TaxRate: 10, Deposit: 200_00, QuoteSecret: "test-secret"}
+
+	// Friday to Monday at 50 per day plus GPS: 50+60+60 + 30 = 200, 10% tax.
+	start := time.Date(2027, 7, 2, 10, 0, 0, 0, time.UTC)
+	booking := func(user string, end time.Time) *models.Reservation {
+		return &models.Reservation{CarID: carID, UserID: user, PickupLocation: "Airport", StartDate: start, EndDate: end}
+	}
+	q, err := svc.Quote(booking(userID, start.AddDate(0, 0, 3)), []string{"gps"}, time.Now())
+	if err != nil {
+		t.Fatalf("quote: %v", err)
+	}
+	if q.Subtotal != 200_00 || q.Tax != 20_00 || q.Total != 220_00 || q.Deposit != 200_00 || q.ID == "" {
+		t.Fatalf("unexpected quote: %+v", q)
+	}
+	kinds := map[string]int{}
+	for _, l := range q.Lines {
+		kinds[l.Kind]++
+	}
+	if kinds[LineBase] != 1 || kinds[LineAdjustment] != 1 || kinds[LineExtra] != 1 || kinds[LineTax] != 1 || kinds[LineDeposit] != 1 {
+		t.Fatalf("unexpected lines: %+v", q.Lines)
+	}
+
+	// The price goes up after quoting; the quote still holds.
+	weekend.Percent = 50
+	if _, err := rules.Update(weekend.ID, weekend); err != nil {
+		t.Fatalf("update rule: %v", err)
+	}
+	for name, res := range map[string]*models.Reservation{
+		"different dates": booking(userID, start.AddDate(0, 0, 4)),
+		"different user":  booking(otherID, start.AddDate(0, 0, 3)),
+	} {
+		res.QuoteID = q.ID
+		if err := svc.Create(res, []string{"gps"}); !errors.Is(err, ErrInvalidQuote) {
+			t.Fatalf("%s: expected ErrInvalidQuote, got %v", name, err)
+		}
+	}
+	res := booking(userID, start.AddDate(0, 0, 3))
+	res.QuoteID = q.ID
+	if err := svc.Create(res, nil); !errors.Is(err, ErrInvalidQuote) {
+		t.Fatalf("different extras: expected ErrInvalidQuote, got %v", err)
+	}
+	if err := svc.Create(res, []string{"gps"}); err != nil {
+		t.Fatalf("create with quote: %v", err)
+	}
+	if res.TotalPrice != 220_00 {
+		t.Fatalf("expected quoted 220, got %v", res.TotalPrice)
+	}
+
+	late := booking(userID, start.AddDate(0, 0, 10))
+	late.StartDate = start.AddDate(0, 0, 7)
+	late.QuoteID, _, err = auth.GenerateQuoteToken(svc.QuoteSecret, auth.QuoteClaims{UserID: userID, CarID: carID, Pickup: "Airport", Start: late.StartDate.Unix(), End: late.EndDate.Unix()}, -time.Minute)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	if err := svc.Create(late, nil); !errors.Is(err, ErrInvalidQuote) {
+		t.Fatalf("expired quote: expected ErrInvalidQuote, got %v", err)
+	}
+}
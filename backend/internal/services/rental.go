@@ -0,0 +1,104 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+const (
+	RentalDay24h      = "24h"
+	RentalDayCalendar = "calendar"
+	MinRentalDuration = time.Hour
+
+	DefaultGracePeriod      = 59 * time.Minute
+	DefaultTurnaroundBuffer = time.Hour
+)
+
+var ErrRentalTooShort = errors.New("rental must be at least one hour")
+
+// localLayouts are wall-clock forms read in the pickup branch's zone.
+var localLayouts = []string{"2006-01-02T15:04", "2006-01-02T15:04:05", "2006-01-02"}
+
+// ParseRentalTime accepts an RFC 3339 timestamp, or a wall-clock time
+// (YYYY-MM-DDTHH:MM, or a bare date meaning midnight) in loc. Wall-clock
+// times skipped by a DST change are rejected. The result is in loc,
+// truncated to the minute.
+func ParseRentalTime(v string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t.In(loc).Truncate(time.Minute), nil
+	}
+	for _, layout := range localLayouts {
+		t, err := time.ParseInLocation(layout, v, loc)
+		if err != nil {
+			continue
+		}
+		if t.Format(layout) != v {
+			return time.Time{}, ErrInvalidLocalTime
+		}
+		return t.Truncate(time.Minute), nil
+	}
+	return time.Time{}, errors.New("invalid date")
+}
+
+// RentalPolicy decides how a pickup/return pair is billed and how far apart
+// two rentals of the same car must be.
+//
+// With DayMode "24h" every full 24 hours is a day and the hours left over
+// are billed at the car's hourly tiers, never more than one more day; with
+// "calendar" every calendar date the rental touches is a day. In both modes
+// a rental shorter than a day is billed by the hour. Lateness up to
+// GracePeriod is not charged: past the last full day in "24h" mode, past
+// midnight of the return date in "calendar" mode. TurnaroundBuffer is kept
+// free before and after each booking for cleaning and handover.
+type RentalPolicy struct {
+	DayMode          string
+	GracePeriod      time.Duration
+	TurnaroundBuffer time.Duration
+}
+
+// BlockingWindow widens [start, end) by the turnaround buffer on both sides;
+// any blocking reservation inside it conflicts.
+func (p RentalPolicy) BlockingWindow(start, end time.Time) (time.Time, time.Time) {
+	return start.Add(-p.TurnaroundBuffer), end.Add(p.TurnaroundBuffer)
+}
+
+// billable splits a rental into whole days and leftover hours.
+func (p RentalPolicy) billable(start, end time.Time) (int, int) {
+	d := end.Sub(start)
+	if p.DayMode == RentalDayCalendar {
+		// Anything shorter than a day stays hourly; otherwise count dates,
+		// ignoring a return within the grace period after midnight.
+		if d < 24*time.Hour {
+			return 0, int(math.Ceil(d.Hours()))
+		}
+		ret := end.Add(-p.GracePeriod)
+		if ret.Before(start) {
+			ret = start
+		}
+		sy, sm, sd := start.Date()
+		ry, rm, rd := ret.Add(-time.Nanosecond).Date()
+		first := time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC)
+		last := time.Date(ry, rm, rd, 0, 0, 0, 0, time.UTC)
+		return int(last.Sub(first).Hours()/24) + 1, 0
+	}
+	days := int(d / (24 * time.Hour))
+	rem := d - time.Duration(days)*24*time.Hour
+	if days > 0 && rem <= p.GracePeriod {
+		rem = 0
+	}
+	return days, int(math.Ceil(rem.Hours()))
+}
+
+// hourlyPrice prices a part-day using the car's tiers and never charges more
+// than a full day. Cars without tiers are billed the full day.
+func hourlyPrice(car *models.Car, hours int) models.Money {
+	for _, tier := range car.HourlyRates {
+		if hours <= tier.MaxHours {
+			return min(tier.PricePerHour.Times(hours), car.DailyPrice)
+		}
+	}
+	return car.DailyPrice
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestRentalPolicyCharge(t *testing.T) {
+	car := &models.Car{DailyPrice: 80_00, HourlyRates: []models.HourlyRate{{MaxHours: 4, PricePerHour: 15_00}, {MaxHours: 12, PricePerHour: 12_00}}}
+	noTiers := &models.Car{DailyPrice: 80_00}
+	gps := []models.Extra{{PricePerDay: 5_00}}
+	at := func(day, hour, min int) time.Time { return time.Date(2026, 7, day, hour, min, 0, 0, time.UTC) }
+	day24 := RentalPolicy{DayMode: RentalDay24h, GracePeriod: 59 * time.Minute}
+	calendar := RentalPolicy{DayMode: RentalDayCalendar, GracePeriod: 59 * time.Minute}
+
+	cases := []struct {
+		name        string
+		policy      RentalPolicy
+		car         *models.Car
+		extras      []models.Extra
+		start, end  time.Time
+		days, hours int
+		total       models.Money
+	}{
+		{"whole days", day24, car, nil, at(1, 0, 0), at(4, 0, 0), 3, 0, 240_00},
+		{"four hours on the first tier", day24, car, nil, at(1, 10, 0), at(1, 14, 0), 0, 4, 60_00},
+		{"started hour is billed", day24, car, nil, at(1, 10, 0), at(1, 14, 1), 0, 5, 60_00},
+		{"hourly capped at daily price", day24, car, nil, at(1, 6, 0), at(1, 20, 0), 0, 14, 80_00},
+		{"late return within grace", day24, car, nil, at(1, 10, 0), at(3, 10, 45), 2, 0, 160_00},
+		{"late return past grace", day24, car, nil, at(1, 10, 0), at(3, 13, 0), 2, 3, 205_00},
+		{"no tiers bills a full day", day24, noTiers, nil, at(1, 10, 0), at(1, 14, 0), 0, 4, 80_00},
+		{"extras per started day", day24, car, gps, at(1, 10, 0), at(3, 13, 0), 2, 3, 220_00},
+		{"calendar pickup 10 return 18", calendar, car, nil, at(1, 10, 0), at(3, 18, 0), 3, 0, 240_00},
+		{"calendar return after midnight within grace", calendar, car, nil, at(1, 10, 0), at(3, 0, 30), 2, 0, 160_00},
+		{"calendar same day is hourly", calendar, car, nil, at(1, 10, 0), at(1, 18, 0), 0, 8, 80_00},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := tc.policy.Charge(tc.car, tc.extras, tc.start, tc.end)
+			if got.Days != tc.days || got.Hours != tc.hours || got.Total != tc.total {
+				t.Fatalf("got days=%d hours=%d total=%v, want days=%d hours=%d total=%v", got.Days, got.Hours, got.Total, tc.days, tc.hours, tc.total)
+			}
+		})
+	}
+}
+
+func TestCreateReservationKeepsTurnaroundBuffer(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	userID := insertTestUser(t, db)
+	at := func(hour int) time.Time { return time.Date(2026, 7, 1, hour, 0, 0, 0, time.UTC) }
+	insertReservationWithStatus(t, db, carID, userID, "approved", at(10), at(14))
+	svc := &ReservationService{Cars: &repositories.CarRepository{DB: db}, Reservations: &repositories.ReservationRepository{DB: db}, Extras: &repositories.ExtraRepository{DB: db}, Policy: RentalPolicy{TurnaroundBuffer: 2 * time.Hour}}
+
+	create := func(start, end time.Time) error {
+		return svc.Create(&models.Reservation{CarID: carID, UserID: userID, StartDate: start, EndDate: end}, nil)
+	}
+	if err := create(at(15), at(18)); !errors.Is(err, ErrCarUnavailable) {
+		t.Fatalf("expected pickup inside the buffer to conflict, got %v", err)
+	}
+	if err := create(at(7), at(9)); !errors.Is(err, ErrCarUnavailable) {
+		t.Fatalf("expected return inside the buffer to conflict, got %v", err)
+	}
+	if err := create(at(16), at(16).Add(30*time.Minute)); !errors.Is(err, ErrRentalTooShort) {
+		t.Fatalf("expected ErrRentalTooShort, got %v", err)
+	}
+	if err := create(at(16), at(20)); err != nil {
+		t.Fatalf("pickup after the buffer should succeed: %v", err)
+	}
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestReservationCreateConcurrentBookingsOnlyOneWins(t *testing.T) {
+	db := newTestDB(t)
+	carID := insertTestCar(t, db)
+	svc := &ReservationService{
+		Cars:         &repositories.CarRepository{DB: db},
+		Reservations: &repositories.ReservationRepository{DB: db},
+		Extras:       &repositories.ExtraRepository{DB: db},
+	}
+
+	const attempts = 16
+	users := make([]string, attempts)
+	for i := range users {
+		users[i] = insertTestUser(t, db)
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, attempts)
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = svc.Create(&models.Reservation{
+				CarID:           carID,
+				UserID:          users[i],
+				StartDate:       time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+				EndDate:         time.Date(2026, 3, 4, 0, 0, 0, 0, time.UTC),
+				PickupLocation:  "A",
+				DropoffLocation: "B",
+			}, nil)
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	wins := 0
+	for _, err := range errs {
+		switch {
+		case err == nil:
+			wins++
+		case !errors.Is(err, ErrCarUnavailable):
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if wins != 1 {
+		t.Fatalf("expected exactly one booking to succeed, got %d", wins)
+	}
+	var stored int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM reservations WHERE car_id=?`, carID).Scan(&stored); err != nil {
+		t.Fatalf("count reservations: %v", err)
+	}
+	if stored != 1 {
+		t.Fatalf("expected one stored reservation, got %d", stored)
+	}
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"rentacar/backend/internal/models"
+)
+
+var (
+	ErrUnknownStatus       = errors.New("invalid status")
+	ErrInvalidTransition   = errors.New("invalid status transition")
+	ErrTransitionForbidden = errors.New("not allowed to make this status change")
+)
+
+var ReservationStatuses = []string{"awaiting_payment", "pending", "approved", "denied", "active", "completed", "cancelled"}
+
+// Actor is whoever asks for a status change. Staff are users whose role
+// holds reservations:approve; everyone else may only act on their own
+// reservations.
+type Actor struct {
+	UserID   string
+	Username string
+	Staff    bool
+}
+
+type reservationTransition struct {
+	from, to string
+	staff    bool
+	customer bool
+}
+
+// reservationTransitions is the whole lifecycle: a booking waits for its
+// payment, is approved or denied, an approved booking is picked up and later
+// returned, and it can be cancelled until pickup. Customers can only cancel,
+// and only before the rental starts (see CanCancel). Leaving
+// awaiting_payment for pending is left to PaymentService.
+var reservationTransitions = []reservationTransition{
+	{from: "awaiting_payment", to: "pending"},
+	{from: "awaiting_payment", to: "cancelled", staff: true, customer: true},
+	{from: "pending", to: "approved", staff: true},
+	{from: "pending", to: "denied", staff: true},
+	{from: "pending", to: "cancelled", staff: true, customer: true},
+	{from: "approved", to: "active", staff: true},
+	{from: "approved", to: "cancelled", staff: true, customer: true},
+	{from: "active", to: "completed", staff: true},
+}
+
+func isReservationStatus(status string) bool {
+	for _, s := range ReservationStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func findTransition(from, to string) (reservationTransition, bool) {
+	for _, t := range reservationTransitions {
+		if t.from == from && t.to == to {
+			return t, true
+		}
+	}
+	return reservationTransition{}, false
+}
+
+// NextStatuses lists the statuses a reservation may move to from its
+// current one.
+func NextStatuses(from string, staff bool) []string {
+	out := []string{}
+	for _, t := range reservationTransitions {
+		if t.from == from && ((staff && t.staff) || (!staff && t.customer)) {
+			out = append(out, t.to)
+		}
+	}
+	return out
+}
+
+// Transition applies a status change after checking it against the state
+// machine and writes the audit entry. With payments set up, approving
+// captures the payment first and denying or cancelling releases it; a
+// failed release leaves the reason on the payment for staff to follow up.
+// A customer cancelling pays the fee of the booking's cancellation policy
+// (see QuoteCancellation), which is kept from the payment and recorded on
+// the reservation. Pickup holds the security deposit and completion
+// releases it in full (see CompleteRental to keep part of it) and issues
+// the invoice.
+func (s *ReservationService) Transition(id, to string, actor Actor) (*models.Reservation, error) {
+	return s.transition(id, to, actor, 0)
+}
+
+func (s *ReservationService) transition(id, to string, actor Actor, depositCharge models.Money) (*models.Reservation, error) {
+	if !isReservationStatus(to) {
+		return nil, ErrUnknownStatus
+	}
+	re, err := s.Reservations.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Staff && re.UserID != actor.UserID {
+		return nil, sql.ErrNoRows
+	}
+	t, ok := findTransition(re.Status, to)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidTransition, re.Status, to)
+	}
+	if (actor.Staff && !t.staff) || (!actor.Staff && !t.customer) {
+		return nil, ErrTransitionForbidden
+	}
+	if !actor.Staff && !CanCancel(re) {
+		return nil, fmt.Errorf("%w: the rental has already started", ErrInvalidTransition)
+	}
+	var cancellation *CancellationQuote
+	if to == "cancelled" {
+		if cancellation, err = s.quoteCancellation(re, actor, time.Now().UTC()); err != nil {
+			return nil, err
+		}
+	}
+	if s.Payments != nil {
+		if err := s.beforeTransition(re, to, depositCharge); err != nil {
+			return nil, err
+		}
+	}
+	ok, err = s.Reservations.Transition(re.ID, re.Status, to, time.Now().UTC())
+	if err == nil && !ok {
+		err = fmt.Errorf("%w: reservation changed concurrently", ErrInvalidTransition)
+	}
+	if err != nil {
+		if s.Payments != nil {
+			s.undoBeforeTransition(re.ID, to)
+		}
+		return nil, err
+	}
+	var forfeitErr error
+	if s.Payments != nil {
+		forfeitErr = s.afterTransition(re, to, depositCharge, cancellation)
+	}
+	// When the money could not be settled the payment keeps the failure for
+	// staff, and no fee or refund is recorded.
+	if cancellation != nil && forfeitErr == nil {
+		_ = s.Reservations.SetCancellation(re.ID, cancellation.Fee, cancellation.Refund)
+	}
+	if to == "completed" && s.Invoices != nil {
+		// An invoice that fails here is issued when it is first asked for.
+		_, _ = s.Invoices.Issue(re.ID)
+	}
+	if s.Audit != nil {
+		_ = s.Audit.Create(actor.UserID, actor.Username, "status_change", "reservation", re.ID, re.Status+" -> "+to)
+	}
+	return s.Reservations.GetByID(re.ID)
+}
+
+// beforeTransition takes the money a status change depends on: the booking
+// is paid for before it is approved, the deposit held before the car goes
+// out and any deposit charge captured before the return is recorded.
+func (s *ReservationService) beforeTransition(re *models.Reservation, to string, depositCharge models.Money) error {
+	switch {
+	case to == "approved":
+		return s.Payments.Capture(re)
+	case to == "active":
+		return s.holdDeposit(re)
+	case to == "completed" && depositCharge > 0:
+		return s.Payments.SettleDeposit(re, depositCharge)
+	}
+	return nil
+}
+
+// afterTransition gives back what the new status no longer needs held,
+// less any cancellation fee. Only settling a cancellation reports an error.
+func (s *ReservationService) afterTransition(re *models.Reservation, to string, depositCharge models.Money, cancellation *CancellationQuote) error {
+	switch {
+	case to == "denied":
+		_ = s.Payments.Release(re)
+	case to == "cancelled":
+		kept, err := s.Payments.Forfeit(re, cancellation.Fee)
+		if err == nil && kept < cancellation.Fee {
+			// The fee could not be taken and the payment was let go in full.
+			cancellation.Refund += cancellation.Fee - kept
+			cancellation.Fee = kept
+		}
+		return err
+	case to == "completed" && depositCharge == 0:
+		_ = s.Payments.SettleDeposit(re, 0)
+	}
+	return nil
+}
+
+// undoBeforeTransition runs when the status change lost a race after
+// beforeTransition. A booking that was called off meanwhile must not keep
+// the money, and a deposit held for a pickup that did not happen is voided.
+func (s *ReservationService) undoBeforeTransition(id, to string) {
+	re, err := s.Reservations.GetByID(id)
+	if err != nil {
+		return
+	}
+	switch {
+	case to == "approved" && (re.Status == "denied" || re.Status == "cancelled"):
+		_ = s.Payments.Release(re)
+	case to == "active" && re.Status != "active":
+		_ = s.Payments.SettleDeposit(re, 0)
+	}
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestReservationTransitions(t *testing.T) {
+	cases := []struct {
+		from, to string
+		staff    bool
+		future   bool
+		wantErr  error
+	}{
+		{"pending", "approved", true, true, nil},
+		{"pending", "denied", true, true, nil},
+		{"approved", "active", true, true, nil},
+		{"active", "completed", true, false, nil},
+		{"pending", "cancelled", false, true, nil},
+		{"approved", "cancelled", false, true, nil},
+		{"awaiting_payment", "cancelled", false, true, nil},
+		{"awaiting_payment", "pending", true, true, ErrTransitionForbidden},
+		{"awaiting_payment", "approved", true, true, ErrInvalidTransition},
+		{"pending", "completed", true, true, ErrInvalidTransition},
+		{"cancelled", "active", true, true, ErrInvalidTransition},
+		{"completed", "approved", true, false, ErrInvalidTransition},
+		{"denied", "approved", true, true, ErrInvalidTransition},
+		{"pending", "approved", false, true, ErrTransitionForbidden},
+		{"approved", "cancelled", false, false, ErrInvalidTransition},
+		{"pending", "archived", true, true, ErrUnknownStatus},
+	}
+	for _, tc := range cases {
+		t.Run(tc.from+"->"+tc.to, func(t *testing.T) {
+			db := newTestDB(t)
+			carID := insertTestCar(t, db)
+			userID := insertTestUser(t, db)
+			start := time.Now().UTC().Add(-24 * time.Hour)
+			if tc.future {
+				start = time.Now().UTC().Add(72 * time.Hour)
+			}
+			id := uuid.NewString()
+			if _, err := db.Exec(`INSERT INTO reservations(id, car_id, user_id, start_date, end_date, pickup_location, dropoff_location, notes, status, total_price_minor)
+			VALUES(?,?,?,?,?,?,?,?,?,?)`, id, carID, userID, start, start.Add(48*time.Hour), "A", "B", "", tc.from, 10000); err != nil {
+				t.Fatalf("insert reservation: %v", err)
+			}
+			svc := &ReservationService{
+				Cars:         &repositories.CarRepository{DB: db},
+				Reservations: &repositories.ReservationRepository{DB: db},
+				Audit:        &repositories.AuditLogRepository{DB: db},
+			}
+
+			re, err := svc.Transition(id, tc.to, Actor{UserID: userID, Username: "someone", Staff: tc.staff})
+			if tc.wantErr != nil {
+				if !errors.Is(err, tc.wantErr) {
+					t.Fatalf("expected %v, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("transition: %v", err)
+			}
+			if re.Status != tc.to {
+				t.Fatalf("status = %s, want %s", re.Status, tc.to)
+			}
+			stamp := map[string]*time.Time{"approved": re.ApprovedAt, "active": re.PickedUpAt, "completed": re.ReturnedAt, "cancelled": re.CancelledAt}
+			if at, ok := stamp[tc.to]; ok && at == nil {
+				t.Fatalf("expected timestamp to be set for %s", tc.to)
+			}
+			var audits int
+			_ = db.QueryRow(`SELECT COUNT(*) FROM audit_logs WHERE entity_id=? AND action='status_change'`, id).Scan(&audits)
+			if audits != 1 {
+				t.Fatalf("expected one audit entry, got %d", audits)
+			}
+		})
+	}
+}
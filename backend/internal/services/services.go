@@ -12,11 +12,56 @@ import (
 	"rentacar/backend/internal/repositories"
 )
 
-var ErrUsernameExists = errors.New("username already exists")
+var (
+	ErrUsernameExists      = errors.New("username already exists")
+	ErrInvalidCredentials  = errors.New("invalid credentials")
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrSessionRevoked      = errors.New("session revoked")
+	ErrAccountDisabled     = errors.New("account disabled")
+	ErrPasswordResetNeeded = errors.New("password reset required")
+	ErrCarUnavailable      = errors.New("car already reserved for selected dates")
+)
+
+const (
+	DefaultAccessTokenTTL  = 15 * time.Minute
+	DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
 
 type AuthService struct {
-	Users     *repositories.UserRepository
-	JWTSecret string
+	Users           *repositories.UserRepository
+	Sessions        *repositories.SessionRepository
+	UserCache       *UserCache
+	Throttle        *LoginThrottle
+	TOTP            *repositories.TOTPRepository
+	TOTPIssuer      string
+	JWTSecret       string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	PreAuthTokenTTL time.Duration
+}
+
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+}
+
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresAt    time.Time
+}
+
+func (s *AuthService) accessTTL() time.Duration {
+	if s.AccessTokenTTL > 0 {
+		return s.AccessTokenTTL
+	}
+	return DefaultAccessTokenTTL
+}
+func (s *AuthService) refreshTTL() time.Duration {
+	if s.RefreshTokenTTL > 0 {
+		return s.RefreshTokenTTL
+	}
+	return DefaultRefreshTokenTTL
 }
 
 func (s *AuthService) Register(username, password string) error {
@@ -31,57 +76,310 @@ func (s *AuthService) Register(username, password string) error {
 	_, err = s.Users.Create(username, string(hash), "user")
 	return err
 }
-func (s *AuthService) Login(username, password string) (string, *models.User, error) {
+
+// dummyHash keeps the response time of unknown usernames close to that of
+// a wrong password.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+// Login checks the password and starts a session. When the account needs a
+// second factor it returns *MFARequiredError instead and the session is only
+// started by CompleteLogin.
+func (s *AuthService) Login(username, password string, client ClientInfo) (*TokenPair, *models.User, error) {
+	if s.Throttle != nil {
+		if err := s.Throttle.Check(username, client.IP); err != nil {
+			return nil, nil, err
+		}
+	}
+	u, err := s.checkCredentials(username, password)
+	if err != nil {
+		if s.Throttle != nil && errors.Is(err, ErrInvalidCredentials) {
+			_ = s.Throttle.Failure(username, client.IP)
+		}
+		return nil, nil, err
+	}
+	if s.Throttle != nil {
+		_ = s.Throttle.Success(username)
+	}
+	if u.DisabledAt != nil {
+		return nil, nil, ErrAccountDisabled
+	}
+	if u.PasswordResetRequired {
+		return nil, nil, ErrPasswordResetNeeded
+	}
+	if err := s.requireMFA(u); err != nil {
+		return nil, u, err
+	}
+	tokens, err := s.startSession(u, client, false)
+	return tokens, u, err
+}
+
+func (s *AuthService) checkCredentials(username, password string) (*models.User, error) {
 	u, err := s.Users.FindByUsername(username)
 	if err != nil {
-		return "", nil, errors.New("invalid credentials")
+		_ = bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		if IsNotFound(err) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
 	}
 	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
-		return "", nil, errors.New("invalid credentials")
+		return nil, ErrInvalidCredentials
+	}
+	return u, nil
+}
+
+// startSession opens a session; mfa records that it was started with a
+// second factor.
+func (s *AuthService) startSession(u *models.User, client ClientInfo, mfa bool) (*TokenPair, error) {
+	refresh, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, err
+	}
+	sess := &models.Session{UserID: u.ID, TokenHash: auth.HashToken(refresh), UserAgent: client.UserAgent, IP: client.IP, ExpiresAt: time.Now().UTC().Add(s.refreshTTL()), MFAVerified: mfa}
+	if err := s.Sessions.Create(sess); err != nil {
+		return nil, err
+	}
+	access, exp, err := auth.GenerateToken(s.JWTSecret, u.ID, u.Username, sess.ID, s.accessTTL())
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, ExpiresAt: exp}, nil
+}
+
+// Refresh rotates a refresh token. Presenting any token the session has
+// already rotated away from is treated as theft and revokes the whole
+// session, and so is refreshing an admin session that was started without a
+// second factor.
+func (s *AuthService) Refresh(refreshToken string) (*TokenPair, *models.User, error) {
+	hash := auth.HashToken(refreshToken)
+	sess, err := s.Sessions.FindByTokenHash(hash)
+	if err != nil {
+		if reused, err := s.Sessions.FindBySpentTokenHash(hash); err == nil {
+			_ = s.Sessions.Revoke(reused.ID)
+		}
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if sess.RevokedAt != nil || time.Now().UTC().After(sess.ExpiresAt) {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	u, err := s.Users.FindByID(sess.UserID)
+	if err != nil || u.DisabledAt != nil {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	if s.lacksMFA(u, sess) {
+		_ = s.Sessions.Revoke(sess.ID)
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	next, err := auth.NewOpaqueToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	ok, err := s.Sessions.Rotate(sess.ID, hash, auth.HashToken(next), time.Now().UTC().Add(s.refreshTTL()))
+	if err != nil {
+		return nil, nil, err
+	}
+	if !ok {
+		return nil, nil, ErrInvalidRefreshToken
+	}
+	access, exp, err := auth.GenerateToken(s.JWTSecret, u.ID, u.Username, sess.ID, s.accessTTL())
+	if err != nil {
+		return nil, nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: next, ExpiresAt: exp}, u, nil
+}
+
+func (s *AuthService) Logout(refreshToken string) error {
+	sess, err := s.Sessions.FindByTokenHash(auth.HashToken(refreshToken))
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	return s.Sessions.Revoke(sess.ID)
+}
+
+func (s *AuthService) LogoutAll(userID string) error {
+	return s.Sessions.RevokeAllForUser(userID)
+}
+
+func (s *AuthService) lookupUser(id string) (*models.User, error) {
+	if s.UserCache != nil {
+		return s.UserCache.Get(id)
+	}
+	return s.Users.FindByID(id)
+}
+
+// Authenticate validates an access token, checks that its session has not
+// been revoked and loads the current user, so role and account state always
+// come from the database rather than the token. Admins need a session that
+// was started with a second factor.
+func (s *AuthService) Authenticate(tokenStr string) (*auth.Claims, *models.User, error) {
+	claims, err := auth.ParseToken(s.JWTSecret, tokenStr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if claims.SessionID == "" || claims.Purpose != "" {
+		return nil, nil, ErrSessionRevoked
+	}
+	sess, err := s.Sessions.GetByID(claims.SessionID)
+	if err != nil || sess.RevokedAt != nil || sess.UserID != claims.UserID {
+		return nil, nil, ErrSessionRevoked
 	}
-	t, err := auth.GenerateToken(s.JWTSecret, u.ID, u.Username, u.Role)
-	return t, u, err
+	u, err := s.lookupUser(claims.UserID)
+	if err != nil {
+		return nil, nil, ErrSessionRevoked
+	}
+	if u.DisabledAt != nil {
+		return nil, nil, ErrAccountDisabled
+	}
+	if s.lacksMFA(u, sess) {
+		return nil, nil, ErrSessionRevoked
+	}
+	return claims, u, nil
 }
 
 type ReservationService struct {
 	Cars         *repositories.CarRepository
 	Reservations *repositories.ReservationRepository
 	Extras       *repositories.ExtraRepository
+	Audit        *repositories.AuditLogRepository
+	Branches     *repositories.BranchRepository
+	PricingRules *repositories.PricingRuleRepository
+	Users        *repositories.UserRepository
+	Payments     *PaymentService
+	Deposits     *repositories.DepositRepository
+	// CancellationPolicies decide what customers pay to cancel; without
+	// them cancelling is free.
+	CancellationPolicies *repositories.CancellationPolicyRepository
+	Invoices             *InvoiceService
+	PromoCodes           *repositories.PromoCodeRepository
+	Policy               RentalPolicy
+	Currency             string
+	TaxRate              float64
+	Deposit              models.Money
+	QuoteSecret          string
+	QuoteTTL             time.Duration
 }
 
-func (s *ReservationService) Create(res *models.Reservation, extraIDs []string) error {
+// prepare validates a booking request and prices it the way it would be
+// charged now.
+func (s *ReservationService) prepare(res *models.Reservation, extraIDs []string, now time.Time) (PriceBreakdown, error) {
 	if !res.EndDate.After(res.StartDate) {
-		return errors.New("endDate must be greater than startDate")
+		return PriceBreakdown{}, errors.New("endDate must be greater than startDate")
 	}
-	if _, err := s.Cars.GetByID(res.CarID); err != nil {
-		return errors.New("car not found")
+	if res.EndDate.Sub(res.StartDate) < MinRentalDuration {
+		return PriceBreakdown{}, ErrRentalTooShort
 	}
-	overlap, err := s.Reservations.HasOverlap(res.CarID, res.StartDate, res.EndDate)
+	car, err := s.Cars.GetByID(res.CarID)
 	if err != nil {
-		return err
+		return PriceBreakdown{}, errors.New("car not found")
 	}
-	if overlap {
-		return errors.New("car already reserved for selected dates")
+	if car.Status != CarAvailable {
+		return PriceBreakdown{}, ErrCarNotBookable
 	}
 	extras, err := s.Extras.ByIDs(extraIDs)
 	if err != nil {
-		return err
+		return PriceBreakdown{}, err
+	}
+	if err := s.checkCurrency(car, extras); err != nil {
+		return PriceBreakdown{}, err
+	}
+	if res.CancellationPolicy, err = s.CancellationPolicyFor(car); err != nil {
+		return PriceBreakdown{}, err
 	}
-	days := int(res.EndDate.Sub(res.StartDate).Hours() / 24)
-	if days < 1 {
-		days = 1
+	in := PricingInput{Car: car, Extras: extras, Start: res.StartDate, End: res.EndDate, BookedAt: now}
+	if in.Promo, err = s.promoFor(res, car, now); err != nil {
+		return PriceBreakdown{}, err
 	}
-	car, _ := s.Cars.GetByID(res.CarID)
-	extraDaily := 0.0
-	for _, e := range extras {
-		extraDaily += e.PricePerDay
+	if s.Branches != nil && res.PickupLocation != "" {
+		if in.Branch, _, err = s.Branch(res.PickupLocation); err != nil {
+			return PriceBreakdown{}, err
+		}
 	}
-	res.TotalPrice = float64(days) * (car.DailyPrice + extraDaily)
+	if s.Users != nil {
+		if u, err := s.Users.FindByID(res.UserID); err == nil {
+			in.DriverBirthDate = u.DateOfBirth
+		}
+	}
+	return s.Price(in)
+}
+
+// Create books a car and keeps the line items, the cancellation policy and
+// the promo code discount it was priced with. With a QuoteID the quoted
+// price is charged instead of the current one, as long as the quote is
+// unexpired and matches. When payments are set up the booking holds the car
+// in awaiting_payment while res.PaymentMethod is authorized, for at most
+// the payment hold; a declined payment cancels it again and returns
+// ErrPaymentDeclined.
+func (s *ReservationService) Create(res *models.Reservation, extraIDs []string) error {
+	price, err := s.prepare(res, extraIDs, time.Now())
+	if err != nil {
+		return err
+	}
+	if res.QuoteID != "" {
+		if price, err = s.quotedPrice(res, extraIDs); err != nil {
+			return err
+		}
+	}
+	res.TotalPrice = price.Total
+	res.Currency = price.Currency
+	res.LineItems = price.Lines
+	res.Discount = price.Discount
 	res.Status = "pending"
-	return s.Reservations.Create(res, extraIDs)
+	if s.Payments != nil {
+		res.Status = "awaiting_payment"
+		hold := time.Now().UTC().Add(s.Payments.holdTTL())
+		res.HoldExpiresAt = &hold
+	}
+	ok, err := s.Reservations.CreateIfAvailable(res, extraIDs, s.Policy.TurnaroundBuffer)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCarUnavailable
+	}
+	if s.Payments == nil {
+		return nil
+	}
+	p, err := s.Payments.Pay(res, res.PaymentMethod)
+	if p == nil && err != nil {
+		// The gateway was never asked, so nobody is paying for the hold.
+		_ = s.Payments.moveReservation(res.ID, "cancelled")
+	}
+	s.reloadPayment(res, p)
+	return err
 }
+
+// Pay retries payment for one of the user's reservations that is still
+// awaiting it, such as bookings made before payments were required.
+func (s *ReservationService) Pay(id, method string, actor Actor) (*models.Reservation, error) {
+	if s.Payments == nil {
+		return nil, ErrNotAwaitingPayment
+	}
+	re, err := s.Reservations.GetByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if re.UserID != actor.UserID {
+		return nil, sql.ErrNoRows
+	}
+	p, err := s.Payments.Pay(re, method)
+	s.reloadPayment(re, p)
+	return re, err
+}
+
+// reloadPayment attaches a payment attempt to res along with the status it
+// moved the reservation to.
+func (s *ReservationService) reloadPayment(res *models.Reservation, p *models.Payment) {
+	res.Payment = p
+	if re, err := s.Reservations.GetByID(res.ID); err == nil {
+		res.Status = re.Status
+		res.CancelledAt = re.CancelledAt
+	}
+}
+
+// CanCancel compares instants, so the cutoff is the pickup time at the
+// branch regardless of where the customer or the server is.
 func CanCancel(res *models.Reservation) bool {
-	return time.Now().UTC().Before(res.StartDate) && (res.Status == "pending" || res.Status == "approved")
+	return time.Now().UTC().Before(res.StartDate) && (res.Status == "awaiting_payment" || res.Status == "pending" || res.Status == "approved")
 }
 
 func IsNotFound(err error) bool { return errors.Is(err, sql.ErrNoRows) }
@@ -0,0 +1,316 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/models"
+)
+
+const (
+	DefaultPreAuthTokenTTL = 5 * time.Minute
+	DefaultTOTPIssuer      = "RentACar"
+	recoveryCodeCount      = 10
+)
+
+var (
+	ErrInvalidPreAuthToken = errors.New("invalid or expired pre-auth token")
+	ErrInvalidTOTPCode     = errors.New("invalid two-factor code")
+	ErrTOTPNotEnabled      = errors.New("two-factor authentication is not enabled")
+	ErrTOTPAlreadyEnabled  = errors.New("two-factor authentication is already enabled")
+	ErrTOTPNotPending      = errors.New("start two-factor setup first")
+	ErrTOTPRequired        = errors.New("two-factor authentication is required for admins")
+)
+
+// MFARequiredError is returned by Login when the password was correct but a
+// second factor is still needed. The pre-auth token is only good for the
+// /auth/login/totp endpoints.
+type MFARequiredError struct {
+	PreAuthToken       string
+	ExpiresAt          time.Time
+	EnrollmentRequired bool
+}
+
+func (e *MFARequiredError) Error() string { return "two-factor authentication required" }
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauthUrl"`
+}
+
+type TOTPStatus struct {
+	Enabled       bool `json:"enabled"`
+	Required      bool `json:"required"`
+	RecoveryCodes int  `json:"recoveryCodesRemaining"`
+}
+
+func mfaMandatory(u *models.User) bool { return u.Role == "admin" }
+
+// lacksMFA reports a session the user may not keep because it was started
+// without the second factor their role requires.
+func (s *AuthService) lacksMFA(u *models.User, sess *models.Session) bool {
+	return s.TOTP != nil && mfaMandatory(u) && !sess.MFAVerified
+}
+
+func (s *AuthService) preAuthTTL() time.Duration {
+	if s.PreAuthTokenTTL > 0 {
+		return s.PreAuthTokenTTL
+	}
+	return DefaultPreAuthTokenTTL
+}
+
+func (s *AuthService) issuer() string {
+	if s.TOTPIssuer != "" {
+		return s.TOTPIssuer
+	}
+	return DefaultTOTPIssuer
+}
+
+func (s *AuthService) totpState(userID string) (*models.TOTP, error) {
+	t, err := s.TOTP.Get(userID)
+	if IsNotFound(err) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// requireMFA decides whether a password login has to continue with a second
+// step. Two-factor is skipped entirely when no TOTP store is configured.
+func (s *AuthService) requireMFA(u *models.User) error {
+	if s.TOTP == nil {
+		return nil
+	}
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return err
+	}
+	enabled := state != nil && state.EnabledAt != nil
+	if !enabled && !mfaMandatory(u) {
+		return nil
+	}
+	token, exp, err := auth.GeneratePreAuthToken(s.JWTSecret, u.ID, u.Username, s.preAuthTTL())
+	if err != nil {
+		return err
+	}
+	return &MFARequiredError{PreAuthToken: token, ExpiresAt: exp, EnrollmentRequired: !enabled}
+}
+
+func (s *AuthService) preAuthUser(token string) (*models.User, error) {
+	claims, err := auth.ParseToken(s.JWTSecret, token)
+	if err != nil || claims.Purpose != auth.PurposeMFA {
+		return nil, ErrInvalidPreAuthToken
+	}
+	u, err := s.Users.FindByID(claims.UserID)
+	if err != nil {
+		return nil, ErrInvalidPreAuthToken
+	}
+	if u.DisabledAt != nil {
+		return nil, ErrAccountDisabled
+	}
+	return u, nil
+}
+
+func (s *AuthService) newEnrollment(u *models.User) (*TOTPEnrollment, error) {
+	secret, err := auth.NewTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.TOTP.SetPending(u.ID, secret); err != nil {
+		return nil, err
+	}
+	return &TOTPEnrollment{Secret: secret, URI: auth.TOTPProvisioningURI(s.issuer(), u.Username, secret)}, nil
+}
+
+func newRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		c, err := auth.NewRecoveryCode()
+		if err != nil {
+			return nil, nil, err
+		}
+		codes = append(codes, c)
+		hashes = append(hashes, auth.HashToken(auth.NormalizeRecoveryCode(c)))
+	}
+	return codes, hashes, nil
+}
+
+// checkCode accepts either a current TOTP code or an unused recovery code.
+func (s *AuthService) checkCode(state *models.TOTP, code string) error {
+	if step, ok := auth.VerifyTOTP(state.Secret, code, time.Now()); ok {
+		fresh, err := s.TOTP.UseStep(state.UserID, step)
+		if err != nil {
+			return err
+		}
+		if fresh {
+			return nil
+		}
+		return ErrInvalidTOTPCode
+	}
+	used, err := s.TOTP.UseRecoveryCode(state.UserID, auth.HashToken(auth.NormalizeRecoveryCode(code)))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return ErrInvalidTOTPCode
+	}
+	return nil
+}
+
+// confirmEnrollment verifies the first code for a pending secret, turns
+// two-factor on and returns the freshly generated recovery codes.
+func (s *AuthService) confirmEnrollment(state *models.TOTP, code string) ([]string, error) {
+	step, ok := auth.VerifyTOTP(state.Secret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidTOTPCode
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	enabled, err := s.TOTP.Enable(state.UserID, step, hashes)
+	if err != nil {
+		return nil, err
+	}
+	if !enabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	return codes, nil
+}
+
+// BeginLoginEnrollment hands out a TOTP secret to a user who must enrol
+// before their first two-factor login, e.g. a newly promoted admin.
+func (s *AuthService) BeginLoginEnrollment(preAuthToken string) (*TOTPEnrollment, error) {
+	u, err := s.preAuthUser(preAuthToken)
+	if err != nil {
+		return nil, err
+	}
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && state.EnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	return s.newEnrollment(u)
+}
+
+// CompleteLogin finishes a two-step login. For users that are still
+// enrolling the code confirms the pending secret and the recovery codes are
+// returned once. Wrong codes count as failed logins for throttling.
+func (s *AuthService) CompleteLogin(preAuthToken, code string, client ClientInfo) (*TokenPair, *models.User, []string, error) {
+	u, err := s.preAuthUser(preAuthToken)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if s.Throttle != nil {
+		if err := s.Throttle.Check(u.Username, client.IP); err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	var recovery []string
+	switch {
+	case state == nil:
+		return nil, nil, nil, ErrTOTPNotPending
+	case state.EnabledAt == nil:
+		recovery, err = s.confirmEnrollment(state, code)
+	default:
+		err = s.checkCode(state, code)
+	}
+	if err != nil {
+		if s.Throttle != nil && errors.Is(err, ErrInvalidTOTPCode) {
+			_ = s.Throttle.Failure(u.Username, client.IP)
+		}
+		return nil, nil, nil, err
+	}
+	if s.Throttle != nil {
+		_ = s.Throttle.Success(u.Username)
+	}
+	tokens, err := s.startSession(u, client, true)
+	return tokens, u, recovery, err
+}
+
+func (s *AuthService) TOTPStatus(u *models.User) (*TOTPStatus, error) {
+	st := &TOTPStatus{Required: mfaMandatory(u)}
+	state, err := s.totpState(u.ID)
+	if err != nil || state == nil || state.EnabledAt == nil {
+		return st, err
+	}
+	st.Enabled = true
+	st.RecoveryCodes, err = s.TOTP.CountRecoveryCodes(u.ID)
+	return st, err
+}
+
+func (s *AuthService) SetupTOTP(u *models.User) (*TOTPEnrollment, error) {
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state != nil && state.EnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	return s.newEnrollment(u)
+}
+
+func (s *AuthService) EnableTOTP(u *models.User, code string) ([]string, error) {
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return nil, ErrTOTPNotPending
+	}
+	if state.EnabledAt != nil {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	return s.confirmEnrollment(state, code)
+}
+
+// DisableTOTP needs both the password and a current code. Admins cannot
+// opt out.
+func (s *AuthService) DisableTOTP(u *models.User, password, code string) error {
+	if mfaMandatory(u) {
+		return ErrTOTPRequired
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return err
+	}
+	if state == nil || state.EnabledAt == nil {
+		return ErrTOTPNotEnabled
+	}
+	if err := s.checkCode(state, code); err != nil {
+		return err
+	}
+	return s.TOTP.Delete(u.ID)
+}
+
+func (s *AuthService) RegenerateRecoveryCodes(u *models.User, code string) ([]string, error) {
+	state, err := s.totpState(u.ID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil || state.EnabledAt == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+	if err := s.checkCode(state, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.TOTP.ReplaceRecoveryCodes(u.ID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/auth"
+	"rentacar/backend/internal/repositories"
+)
+
+func TestAdminLoginRequiresTOTPEnrollment(t *testing.T) {
+	db := newTestDB(t)
+	users := &repositories.UserRepository{DB: db}
+	svc := &AuthService{Users: users, Sessions: &repositories.SessionRepository{DB: db}, TOTP: &repositories.TOTPRepository{DB: db}, JWTSecret: "test-secret"}
+	if err := svc.Register("boss", "secret123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	boss, _ := users.FindByUsername("boss")
+	if err := users.UpdateRole(boss.ID, "admin"); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+
+	_, _, err := svc.Login("boss", "secret123", ClientInfo{})
+	var mfa *MFARequiredError
+	if !errors.As(err, &mfa) || !mfa.EnrollmentRequired {
+		t.Fatalf("expected enrollment to be required, got %v", err)
+	}
+	if _, _, err := svc.Authenticate(mfa.PreAuthToken); err == nil {
+		t.Fatal("pre-auth token must not work as an access token")
+	}
+	enrollment, err := svc.BeginLoginEnrollment(mfa.PreAuthToken)
+	if err != nil {
+		t.Fatalf("begin enrollment: %v", err)
+	}
+	step := auth.TOTPStep(time.Now())
+	code, _ := auth.TOTPCode(enrollment.Secret, step)
+	tokens, _, recovery, err := svc.CompleteLogin(mfa.PreAuthToken, code, ClientInfo{})
+	if err != nil {
+		t.Fatalf("complete enrollment: %v", err)
+	}
+	if len(recovery) != recoveryCodeCount || tokens.AccessToken == "" {
+		t.Fatalf("expected tokens and %d recovery codes, got %d", recoveryCodeCount, len(recovery))
+	}
+
+	_, _, err = svc.Login("boss", "secret123", ClientInfo{})
+	if !errors.As(err, &mfa) || mfa.EnrollmentRequired {
+		t.Fatalf("expected a plain TOTP challenge, got %v", err)
+	}
+	if _, _, _, err := svc.CompleteLogin(mfa.PreAuthToken, code, ClientInfo{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected replayed code to be rejected, got %v", err)
+	}
+	next, _ := auth.TOTPCode(enrollment.Secret, step+1)
+	if _, _, _, err := svc.CompleteLogin(mfa.PreAuthToken, next, ClientInfo{}); err != nil {
+		t.Fatalf("next step code: %v", err)
+	}
+	if _, _, _, err := svc.CompleteLogin(mfa.PreAuthToken, recovery[0], ClientInfo{}); err != nil {
+		t.Fatalf("recovery code: %v", err)
+	}
+	if _, _, _, err := svc.CompleteLogin(mfa.PreAuthToken, recovery[0], ClientInfo{}); !errors.Is(err, ErrInvalidTOTPCode) {
+		t.Fatalf("expected used recovery code to be rejected, got %v", err)
+	}
+	admin, _ := users.FindByID(boss.ID)
+	if err := svc.DisableTOTP(admin, "secret123", recovery[1]); !errors.Is(err, ErrTOTPRequired) {
+		t.Fatalf("expected admins to be unable to disable TOTP, got %v", err)
+	}
+}
+
+func TestAdminSessionWithoutSecondFactorIsRejected(t *testing.T) {
+	db := newTestDB(t)
+	users := &repositories.UserRepository{DB: db}
+	svc := &AuthService{Users: users, Sessions: &repositories.SessionRepository{DB: db}, TOTP: &repositories.TOTPRepository{DB: db}, JWTSecret: "test-secret"}
+	if err := svc.Register("boss", "secret123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	// A session opened before two-factor login was required of the account.
+	old, boss, err := svc.Login("boss", "secret123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	if err := users.UpdateRole(boss.ID, "admin"); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if _, _, err := svc.Authenticate(old.AccessToken); !errors.Is(err, ErrSessionRevoked) {
+		t.Fatalf("expected the old admin session to be refused, got %v", err)
+	}
+	if _, _, err := svc.Refresh(old.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Fatalf("expected the old admin session not to refresh, got %v", err)
+	}
+	claims, _ := auth.ParseToken(svc.JWTSecret, old.AccessToken)
+	if sess, _ := svc.Sessions.GetByID(claims.SessionID); sess.RevokedAt == nil {
+		t.Fatal("the refused session should be revoked")
+	}
+
+	_, _, err = svc.Login("boss", "secret123", ClientInfo{})
+	var mfa *MFARequiredError
+	if !errors.As(err, &mfa) {
+		t.Fatalf("expected a second factor to be required, got %v", err)
+	}
+	enrollment, err := svc.BeginLoginEnrollment(mfa.PreAuthToken)
+	if err != nil {
+		t.Fatalf("begin enrollment: %v", err)
+	}
+	code, _ := auth.TOTPCode(enrollment.Secret, auth.TOTPStep(time.Now()))
+	tokens, _, _, err := svc.CompleteLogin(mfa.PreAuthToken, code, ClientInfo{})
+	if err != nil {
+		t.Fatalf("complete login: %v", err)
+	}
+	if _, _, err := svc.Authenticate(tokens.AccessToken); err != nil {
+		t.Fatalf("a session started with a second factor should work: %v", err)
+	}
+	if _, _, err := svc.Refresh(tokens.RefreshToken); err != nil {
+		t.Fatalf("a session started with a second factor should refresh: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"sync"
+	"time"
+
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+const (
+	DefaultUserCacheTTL = 30 * time.Second
+	userCacheMaxEntries = 1024
+)
+
+// UserCache keeps recently authenticated users in memory so role and account
+// state checks do not hit the database on every request. Anything that
+// changes a user must call Invalidate.
+type UserCache struct {
+	Users *repositories.UserRepository
+	TTL   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]cachedUser
+}
+
+type cachedUser struct {
+	user      models.User
+	expiresAt time.Time
+}
+
+func (c *UserCache) ttl() time.Duration {
+	if c.TTL > 0 {
+		return c.TTL
+	}
+	return DefaultUserCacheTTL
+}
+
+func (c *UserCache) Get(id string) (*models.User, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[id]; ok && now.Before(e.expiresAt) {
+		c.mu.Unlock()
+		u := e.user
+		return &u, nil
+	}
+	c.mu.Unlock()
+
+	u, err := c.Users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = map[string]cachedUser{}
+	}
+	if len(c.entries) >= userCacheMaxEntries {
+		for k, e := range c.entries {
+			if !now.Before(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		if len(c.entries) >= userCacheMaxEntries {
+			c.entries = map[string]cachedUser{}
+		}
+	}
+	c.entries[id] = cachedUser{user: *u, expiresAt: now.Add(c.ttl())}
+	return u, nil
+}
+
+func (c *UserCache) Invalidate(id string) {
+	c.mu.Lock()
+	delete(c.entries, id)
+	c.mu.Unlock()
+}
@@ -0,0 +1,347 @@
+package services
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"rentacar/backend/internal/models"
+	"rentacar/backend/internal/repositories"
+)
+
+var (
+	ErrInvalidRole   = errors.New("invalid role")
+	ErrLastAdmin     = errors.New("cannot remove the last active admin")
+	ErrSelfAction    = errors.New("admins cannot do this to their own account")
+	ErrEmailExists   = errors.New("email already in use")
+	ErrWrongPassword = errors.New("current password is incorrect")
+	ErrWeakPassword  = errors.New("password must be at least 6 characters")
+	ErrActiveRental  = errors.New("account has an active rental")
+)
+
+type ValidationError struct{ Message string }
+
+func (e *ValidationError) Error() string { return e.Message }
+
+const MinPasswordLength = 6
+
+var Roles = []string{"user", "agent", "fleet_manager", "admin"}
+
+func IsValidRole(role string) bool {
+	for _, r := range Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// UserService holds every mutation of user accounts so that cached account
+// state and open sessions are always invalidated alongside the change.
+type UserService struct {
+	Users     *repositories.UserRepository
+	Sessions  *repositories.SessionRepository
+	UserCache *UserCache
+	Payments  *PaymentService
+	// Reservations cancels the upcoming bookings of closed accounts.
+	Reservations *ReservationService
+}
+
+func (s *UserService) invalidate(id string) {
+	if s.UserCache != nil {
+		s.UserCache.Invalidate(id)
+	}
+}
+
+func (s *UserService) guardLastAdmin(u *models.User) error {
+	if u.Role != "admin" || u.DisabledAt != nil {
+		return nil
+	}
+	n, err := s.Users.CountActiveAdmins()
+	if err != nil {
+		return err
+	}
+	if n <= 1 {
+		return ErrLastAdmin
+	}
+	return nil
+}
+
+func (s *UserService) ChangeRole(actorID, id, role string) (*models.User, error) {
+	if !IsValidRole(role) {
+		return nil, ErrInvalidRole
+	}
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if u.Role == role {
+		return u, nil
+	}
+	if actorID == id {
+		return nil, ErrSelfAction
+	}
+	if err := s.guardLastAdmin(u); err != nil {
+		return nil, err
+	}
+	if err := s.Users.UpdateRole(id, role); err != nil {
+		return nil, err
+	}
+	// New admins must sign in again so the mandatory second factor applies.
+	if role == "admin" {
+		if err := s.Sessions.RevokeAllForUser(id); err != nil {
+			return nil, err
+		}
+	}
+	s.invalidate(id)
+	u.Role = role
+	return u, nil
+}
+
+func (s *UserService) SetDisabled(actorID, id string, disabled bool) (*models.User, error) {
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if disabled {
+		if actorID == id {
+			return nil, ErrSelfAction
+		}
+		if err := s.guardLastAdmin(u); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.Users.SetDisabled(id, disabled); err != nil {
+		return nil, err
+	}
+	if disabled {
+		if err := s.Sessions.RevokeAllForUser(id); err != nil {
+			return nil, err
+		}
+	}
+	s.invalidate(id)
+	return s.Users.FindByID(id)
+}
+
+// RequirePasswordReset blocks further logins until the user sets a new
+// password and signs the user out everywhere.
+func (s *UserService) RequirePasswordReset(id string) error {
+	if _, err := s.Users.FindByID(id); err != nil {
+		return err
+	}
+	if err := s.Users.SetPasswordResetRequired(id, true); err != nil {
+		return err
+	}
+	if err := s.Sessions.RevokeAllForUser(id); err != nil {
+		return err
+	}
+	s.invalidate(id)
+	return nil
+}
+
+// Delete removes an account outright when it has no history and otherwise
+// anonymises it so reservations and reviews stay intact.
+func (s *UserService) Delete(actorID, id string) error {
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if actorID == id {
+		return ErrSelfAction
+	}
+	if err := s.guardLastAdmin(u); err != nil {
+		return err
+	}
+	history, err := s.Users.HasHistory(id)
+	if err != nil {
+		return err
+	}
+	if history {
+		return s.anonymize(u)
+	}
+	if err := s.Users.Delete(id); err != nil {
+		return err
+	}
+	s.invalidate(id)
+	return nil
+}
+
+func (s *UserService) anonymize(u *models.User) error {
+	active, err := s.Users.HasActiveRental(u.ID)
+	if err != nil {
+		return err
+	}
+	if active {
+		return ErrActiveRental
+	}
+	if err := s.cancelUpcoming(u); err != nil {
+		return err
+	}
+	if err := s.Users.Anonymize(u.ID); err != nil {
+		return err
+	}
+	s.invalidate(u.ID)
+	if s.Payments != nil {
+		// The account is closed either way; a failed release stays on the
+		// payment for staff to follow up.
+		_ = s.Payments.ReleaseForUser(u.ID)
+	}
+	return nil
+}
+
+// systemActor cancels bookings on behalf of a closed account. It acts as
+// staff, so no cancellation fee is kept.
+var systemActor = Actor{UserID: "system", Username: "system", Staff: true}
+
+// cancelUpcoming cancels a closing account's bookings through the state
+// machine so each is audited and its payment released. The account is
+// disabled first so no new booking slips in, and enabled again if a
+// cancellation fails.
+func (s *UserService) cancelUpcoming(u *models.User) error {
+	if u.DisabledAt == nil {
+		if err := s.Users.SetDisabled(u.ID, true); err != nil {
+			return err
+		}
+		s.invalidate(u.ID)
+	}
+	err := s.cancelEach(u.ID)
+	if err != nil && u.DisabledAt == nil {
+		_ = s.Users.SetDisabled(u.ID, false)
+		s.invalidate(u.ID)
+	}
+	return err
+}
+
+func (s *UserService) cancelEach(userID string) error {
+	ids, err := s.Reservations.Reservations.UpcomingIDs(userID)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if _, err := s.Reservations.Transition(id, "cancelled", systemActor); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type ProfileInput struct {
+	FullName      string
+	Email         string
+	Phone         string
+	DateOfBirth   *time.Time
+	LicenseNumber string
+	LicenseExpiry *time.Time
+}
+
+func validateProfile(p *ProfileInput) error {
+	p.FullName = strings.TrimSpace(p.FullName)
+	p.Email = strings.ToLower(strings.TrimSpace(p.Email))
+	p.Phone = strings.TrimSpace(p.Phone)
+	p.LicenseNumber = strings.ToUpper(strings.TrimSpace(p.LicenseNumber))
+	if len(p.FullName) > 120 {
+		return &ValidationError{"fullName is too long"}
+	}
+	if p.Email != "" {
+		if addr, err := mail.ParseAddress(p.Email); err != nil || addr.Address != p.Email {
+			return &ValidationError{"email is invalid"}
+		}
+	}
+	if p.Phone != "" {
+		digits := 0
+		for _, r := range p.Phone {
+			switch {
+			case r >= '0' && r <= '9':
+				digits++
+			case strings.ContainsRune("+-() ", r):
+			default:
+				return &ValidationError{"phone is invalid"}
+			}
+		}
+		if digits < 6 || digits > 15 {
+			return &ValidationError{"phone is invalid"}
+		}
+	}
+	if p.DateOfBirth != nil && !p.DateOfBirth.Before(time.Now().UTC()) {
+		return &ValidationError{"dateOfBirth must be in the past"}
+	}
+	if len(p.LicenseNumber) > 32 {
+		return &ValidationError{"licenseNumber is too long"}
+	}
+	return nil
+}
+
+func (s *UserService) UpdateProfile(id string, p ProfileInput) (*models.User, error) {
+	if err := validateProfile(&p); err != nil {
+		return nil, err
+	}
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if p.Email != "" && p.Email != u.Email {
+		if other, err := s.Users.FindByEmail(p.Email); err == nil && other.ID != id {
+			return nil, ErrEmailExists
+		}
+	}
+	u.FullName, u.Email, u.Phone = p.FullName, p.Email, p.Phone
+	u.DateOfBirth, u.LicenseNumber, u.LicenseExpiry = p.DateOfBirth, p.LicenseNumber, p.LicenseExpiry
+	if err := s.Users.UpdateProfile(u); err != nil {
+		if strings.Contains(strings.ToLower(err.Error()), "unique constraint failed") {
+			return nil, ErrEmailExists
+		}
+		return nil, err
+	}
+	s.invalidate(id)
+	return u, nil
+}
+
+func (s *UserService) checkPassword(u *models.User, password string) error {
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return ErrWrongPassword
+	}
+	return nil
+}
+
+// ChangePassword verifies the current password and signs out every other
+// session of the user.
+func (s *UserService) ChangePassword(id, sessionID, current, next string) error {
+	if len(next) < MinPasswordLength {
+		return ErrWeakPassword
+	}
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(u, current); err != nil {
+		return err
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(next), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	if err := s.Users.UpdatePassword(id, string(hash)); err != nil {
+		return err
+	}
+	if err := s.Sessions.RevokeAllForUserExcept(id, sessionID); err != nil {
+		return err
+	}
+	s.invalidate(id)
+	return nil
+}
+
+func (s *UserService) DeleteAccount(id, password string) error {
+	u, err := s.Users.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if err := s.checkPassword(u, password); err != nil {
+		return err
+	}
+	if err := s.guardLastAdmin(u); err != nil {
+		return err
+	}
+	return s.anonymize(u)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"rentacar/backend/internal/repositories"
+)
+
+func newTestUserService(t *testing.T) (*UserService, *AuthService) {
+	t.Helper()
+	db := newTestDB(t)
+	users := &repositories.UserRepository{DB: db}
+	sessions := &repositories.SessionRepository{DB: db}
+	cache := &UserCache{Users: users}
+	reservations := &ReservationService{
+		Cars:         &repositories.CarRepository{DB: db},
+		Reservations: &repositories.ReservationRepository{DB: db},
+		Extras:       &repositories.ExtraRepository{DB: db},
+		Audit:        &repositories.AuditLogRepository{DB: db},
+	}
+	return &UserService{Users: users, Sessions: sessions, UserCache: cache, Reservations: reservations},
+		&AuthService{Users: users, Sessions: sessions, UserCache: cache, JWTSecret: "test-secret"}
+}
+
+func TestUserServiceProtectsLastAdmin(t *testing.T) {
+	svc, authSvc := newTestUserService(t)
+	for _, name := range []string{"root", "other"} {
+		if err := authSvc.Register(name, "secret123"); err != nil {
+			t.Fatalf("register %s: %v", name, err)
+		}
+	}
+	root, _ := svc.Users.FindByUsername("root")
+	other, _ := svc.Users.FindByUsername("other")
+	if err := svc.Users.UpdateRole(root.ID, "admin"); err != nil {
+		t.Fatalf("seed admin: %v", err)
+	}
+
+	if _, err := svc.ChangeRole(other.ID, root.ID, "user"); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected ErrLastAdmin on demotion, got %v", err)
+	}
+	if _, err := svc.SetDisabled(other.ID, root.ID, true); !errors.Is(err, ErrLastAdmin) {
+		t.Fatalf("expected ErrLastAdmin on disable, got %v", err)
+	}
+	if _, err := svc.ChangeRole(root.ID, other.ID, "superuser"); !errors.Is(err, ErrInvalidRole) {
+		t.Fatalf("expected ErrInvalidRole, got %v", err)
+	}
+
+	if _, err := svc.ChangeRole(root.ID, other.ID, "admin"); err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if _, err := svc.ChangeRole(other.ID, root.ID, "user"); err != nil {
+		t.Fatalf("demote with another admin present: %v", err)
+	}
+}
+
+func TestUserServiceDisableAndForcedResetEndSessions(t *testing.T) {
+	svc, authSvc := newTestUserService(t)
+	if err := authSvc.Register("frank", "secret123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	tokens, u, err := authSvc.Login("frank", "secret123", ClientInfo{})
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	if _, err := svc.SetDisabled("admin-id", u.ID, true); err != nil {
+		t.Fatalf("disable: %v", err)
+	}
+	if _, _, err := authSvc.Authenticate(tokens.AccessToken); err == nil {
+		t.Fatalf("expected disabled user's token to be rejected")
+	}
+	if _, err := svc.SetDisabled("admin-id", u.ID, false); err != nil {
+		t.Fatalf("enable: %v", err)
+	}
+
+	if err := svc.RequirePasswordReset(u.ID); err != nil {
+		t.Fatalf("force reset: %v", err)
+	}
+	if _, _, err := authSvc.Login("frank", "secret123", ClientInfo{}); !errors.Is(err, ErrPasswordResetNeeded) {
+		t.Fatalf("expected ErrPasswordResetNeeded, got %v", err)
+	}
+}
+
+func TestUserServiceDeleteAccountAnonymisesHistory(t *testing.T) {
+	svc, authSvc := newTestUserService(t)
+	db := svc.Users.DB
+	if err := authSvc.Register("grace", "secret123"); err != nil {
+		t.Fatalf("register: %v", err)
+	}
+	u, _ := svc.Users.FindByUsername("grace")
+	if _, err := svc.UpdateProfile(u.ID, ProfileInput{FullName: "Grace Hopper", Email: "Grace@Example.com", Phone: "+1 555 0100"}); err != nil {
+		t.Fatalf("update profile: %v", err)
+	}
+	carID := insertTestCar(t, db)
+	start := time.Now().UTC().Add(48 * time.Hour)
+	insertReservationWithStatus(t, db, carID, u.ID, "pending", start, start.Add(48*time.Hour))
+	if _, err := db.Exec(`INSERT INTO reviews(id, car_id, user_id, rating, comment) VALUES('rv1',?,?,5,'call me on 555 0100')`, carID, u.ID); err != nil {
+		t.Fatalf("insert review: %v", err)
+	}
+
+	if err := svc.DeleteAccount(u.ID, "wrong"); !errors.Is(err, ErrWrongPassword) {
+		t.Fatalf("expected ErrWrongPassword, got %v", err)
+	}
+	if err := svc.DeleteAccount(u.ID, "secret123"); err != nil {
+		t.Fatalf("delete account: %v", err)
+	}
+
+	got, err := svc.Users.FindByID(u.ID)
+	if err != nil {
+		t.Fatalf("anonymised row must remain: %v", err)
+	}
+	if got.Username == "grace" || got.Email != "" || got.FullName != "" || got.Phone != "" || got.DeletedAt == nil {
+		t.Fatalf("personal data not scrubbed: %#v", got)
+	}
+	var status, comment string
+	var refund sql.NullInt64
+	_ = db.QueryRow(`SELECT status, refund_minor FROM reservations WHERE user_id=?`, u.ID).Scan(&status, &refund)
+	_ = db.QueryRow(`SELECT comment FROM reviews WHERE user_id=?`, u.ID).Scan(&comment)
+	if status != "cancelled" || !refund.Valid || comment != "" {
+		t.Fatalf("unexpected history after anonymisation: status=%q refund=%v comment=%q", status, refund, comment)
+	}
+	var audits int
+	_ = db.QueryRow(`SELECT COUNT(*) FROM audit_logs WHERE actor_id='system' AND action='status_change' AND details='pending -> cancelled'`).Scan(&audits)
+	if audits != 1 {
+		t.Fatalf("the cancellation should be audited, got %d entries", audits)
+	}
+	if _, _, err := authSvc.Login("grace", "secret123", ClientInfo{}); err == nil {
+		t.Fatalf("expected login to fail after deletion")
+	}
+}
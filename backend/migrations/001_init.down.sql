@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS reservation_extras;
+DROP TABLE IF EXISTS extras;
+DROP TABLE IF EXISTS reservations;
+DROP TABLE IF EXISTS cars;
+DROP TABLE IF EXISTS users;
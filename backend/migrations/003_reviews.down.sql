@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_reviews_car_created;
+DROP INDEX IF EXISTS idx_reviews_car_user_unique;
+DROP TABLE IF EXISTS reviews;
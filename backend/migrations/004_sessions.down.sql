@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_sessions_previous_token;
+DROP INDEX IF EXISTS idx_sessions_user;
+DROP TABLE IF EXISTS sessions;
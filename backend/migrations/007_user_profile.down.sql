@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_users_email_unique;
+ALTER TABLE users DROP COLUMN deleted_at;
+ALTER TABLE users DROP COLUMN license_expiry;
+ALTER TABLE users DROP COLUMN license_number;
+ALTER TABLE users DROP COLUMN date_of_birth;
+ALTER TABLE users DROP COLUMN phone;
+ALTER TABLE users DROP COLUMN email;
+ALTER TABLE users DROP COLUMN full_name;
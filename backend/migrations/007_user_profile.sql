@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN full_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN email TEXT;
+ALTER TABLE users ADD COLUMN phone TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN date_of_birth DATE;
+ALTER TABLE users ADD COLUMN license_number TEXT NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN license_expiry DATE;
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_unique ON users(email) WHERE email IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Nothing to restore: the GPS extra was already deleted on every boot before
+-- this migration, so the schema before it had none either.
+SELECT 1;
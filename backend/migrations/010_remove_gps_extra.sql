@@ -0,0 +1,2 @@
+-- GPS is intentionally removed from paid extras. This used to run on every boot.
+DELETE FROM extras WHERE LOWER(TRIM(name))='gps';
@@ -0,0 +1,2 @@
+UPDATE users SET role='user' WHERE role NOT IN ('user', 'admin');
+DROP TABLE IF EXISTS role_permissions;
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role TEXT NOT NULL,
+  permission TEXT NOT NULL,
+  PRIMARY KEY(role, permission)
+);
+
+INSERT OR IGNORE INTO role_permissions(role, permission) VALUES
+  ('agent', 'reservations:read'),
+  ('agent', 'reservations:approve'),
+  ('agent', 'dashboard:read'),
+  ('fleet_manager', 'cars:write'),
+  ('fleet_manager', 'reservations:read'),
+  ('fleet_manager', 'dashboard:read');
@@ -0,0 +1,4 @@
+ALTER TABLE reservations DROP COLUMN cancelled_at;
+ALTER TABLE reservations DROP COLUMN returned_at;
+ALTER TABLE reservations DROP COLUMN picked_up_at;
+ALTER TABLE reservations DROP COLUMN approved_at;
@@ -0,0 +1,4 @@
+ALTER TABLE reservations ADD COLUMN approved_at TIMESTAMP;
+ALTER TABLE reservations ADD COLUMN picked_up_at TIMESTAMP;
+ALTER TABLE reservations ADD COLUMN returned_at TIMESTAMP;
+ALTER TABLE reservations ADD COLUMN cancelled_at TIMESTAMP;
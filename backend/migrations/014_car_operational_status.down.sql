@@ -0,0 +1,2 @@
+-- Cars with a booking that still holds them were shown as "rented" before.
+UPDATE cars SET status='rented' WHERE status='available' AND id IN (SELECT car_id FROM reservations WHERE status IN ('pending','approved','active'));
@@ -0,0 +1,3 @@
+-- "rented" used to be flipped on and off from reservations. Occupancy is now
+-- computed per date range, so the column only holds the operational status.
+UPDATE cars SET status='available' WHERE status='rented';
@@ -0,0 +1 @@
+ALTER TABLE cars ADD COLUMN hourly_rates TEXT NOT NULL DEFAULT '[]';
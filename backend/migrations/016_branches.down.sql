@@ -0,0 +1,2 @@
+ALTER TABLE reservations DROP COLUMN timezone;
+DROP TABLE IF EXISTS branches;
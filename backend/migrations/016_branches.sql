@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS branches (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL UNIQUE,
+  timezone TEXT NOT NULL,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT OR IGNORE INTO branches(id, name, timezone) VALUES
+  ('branch-sarajevo-center', 'Sarajevo Center', 'Europe/Sarajevo'),
+  ('branch-sarajevo-airport', 'Sarajevo Airport', 'Europe/Sarajevo');
+
+ALTER TABLE reservations ADD COLUMN timezone TEXT NOT NULL DEFAULT 'UTC';
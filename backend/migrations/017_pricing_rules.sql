@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS pricing_rules (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  kind TEXT NOT NULL,
+  position INTEGER NOT NULL DEFAULT 0,
+  active INTEGER NOT NULL DEFAULT 1,
+  percent REAL NOT NULL DEFAULT 0,
+  amount_per_day REAL NOT NULL DEFAULT 0,
+  category TEXT NOT NULL DEFAULT '',
+  season_start TEXT NOT NULL DEFAULT '',
+  season_end TEXT NOT NULL DEFAULT '',
+  min_days INTEGER NOT NULL DEFAULT 0,
+  min_lead_days INTEGER NOT NULL DEFAULT 0,
+  max_lead_hours INTEGER NOT NULL DEFAULT 0,
+  max_driver_age INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
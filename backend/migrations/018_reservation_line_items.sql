@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS reservation_line_items (
+  id TEXT PRIMARY KEY,
+  reservation_id TEXT NOT NULL,
+  position INTEGER NOT NULL,
+  kind TEXT NOT NULL,
+  code TEXT NOT NULL DEFAULT '',
+  label TEXT NOT NULL,
+  quantity INTEGER NOT NULL DEFAULT 1,
+  unit_price REAL NOT NULL,
+  amount REAL NOT NULL,
+  FOREIGN KEY(reservation_id) REFERENCES reservations(id)
+);
+CREATE INDEX IF NOT EXISTS idx_reservation_line_items_reservation ON reservation_line_items(reservation_id, position);
+
+-- Earlier reservations only kept their total; record it as a single line.
+INSERT INTO reservation_line_items(id, reservation_id, position, kind, code, label, quantity, unit_price, amount)
+SELECT lower(hex(randomblob(16))), id, 0, 'base', 'legacy', 'Rental', 1, total_price, total_price FROM reservations;
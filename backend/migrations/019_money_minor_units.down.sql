@@ -0,0 +1,31 @@
+DROP TABLE IF EXISTS exchange_rates;
+
+ALTER TABLE branches DROP COLUMN tax_name;
+ALTER TABLE branches DROP COLUMN tax_rate;
+
+ALTER TABLE pricing_rules ADD COLUMN amount_per_day REAL NOT NULL DEFAULT 0;
+UPDATE pricing_rules SET amount_per_day = amount_per_day_minor / 100.0;
+ALTER TABLE pricing_rules DROP COLUMN amount_per_day_minor;
+ALTER TABLE pricing_rules DROP COLUMN currency;
+
+ALTER TABLE reservation_line_items ADD COLUMN unit_price REAL NOT NULL DEFAULT 0;
+ALTER TABLE reservation_line_items ADD COLUMN amount REAL NOT NULL DEFAULT 0;
+UPDATE reservation_line_items SET unit_price = unit_price_minor / 100.0, amount = amount_minor / 100.0;
+ALTER TABLE reservation_line_items DROP COLUMN unit_price_minor;
+ALTER TABLE reservation_line_items DROP COLUMN amount_minor;
+
+ALTER TABLE reservations ADD COLUMN total_price REAL NOT NULL DEFAULT 0;
+UPDATE reservations SET total_price = total_price_minor / 100.0;
+ALTER TABLE reservations DROP COLUMN total_price_minor;
+ALTER TABLE reservations DROP COLUMN currency;
+
+ALTER TABLE extras ADD COLUMN price_per_day REAL NOT NULL DEFAULT 0;
+UPDATE extras SET price_per_day = price_per_day_minor / 100.0;
+ALTER TABLE extras DROP COLUMN price_per_day_minor;
+ALTER TABLE extras DROP COLUMN currency;
+
+ALTER TABLE cars ADD COLUMN daily_price REAL NOT NULL DEFAULT 0;
+UPDATE cars SET daily_price = daily_price_minor / 100.0,
+  hourly_rates = (SELECT json_group_array(json_object('maxHours', json_extract(value, '$.maxHours'), 'pricePerHour', json_extract(value, '$.pricePerHour') / 100.0)) FROM json_each(cars.hourly_rates));
+ALTER TABLE cars DROP COLUMN daily_price_minor;
+ALTER TABLE cars DROP COLUMN currency;
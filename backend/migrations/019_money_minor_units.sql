@@ -0,0 +1,42 @@
+-- Amounts move from REAL to integer minor units (cents) labelled with a
+-- currency. Existing prices were shown in dollars.
+ALTER TABLE cars ADD COLUMN daily_price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE cars ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE cars SET daily_price_minor = CAST(ROUND(daily_price * 100) AS INTEGER),
+  hourly_rates = (SELECT json_group_array(json_object('maxHours', json_extract(value, '$.maxHours'), 'pricePerHour', CAST(ROUND(json_extract(value, '$.pricePerHour') * 100) AS INTEGER))) FROM json_each(cars.hourly_rates));
+ALTER TABLE cars DROP COLUMN daily_price;
+
+ALTER TABLE extras ADD COLUMN price_per_day_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE extras ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE extras SET price_per_day_minor = CAST(ROUND(price_per_day * 100) AS INTEGER);
+ALTER TABLE extras DROP COLUMN price_per_day;
+
+ALTER TABLE reservations ADD COLUMN total_price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE reservations ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE reservations SET total_price_minor = CAST(ROUND(total_price * 100) AS INTEGER);
+ALTER TABLE reservations DROP COLUMN total_price;
+
+ALTER TABLE reservation_line_items ADD COLUMN unit_price_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE reservation_line_items ADD COLUMN amount_minor INTEGER NOT NULL DEFAULT 0;
+UPDATE reservation_line_items SET unit_price_minor = CAST(ROUND(unit_price * 100) AS INTEGER), amount_minor = CAST(ROUND(amount * 100) AS INTEGER);
+ALTER TABLE reservation_line_items DROP COLUMN unit_price;
+ALTER TABLE reservation_line_items DROP COLUMN amount;
+
+ALTER TABLE pricing_rules ADD COLUMN amount_per_day_minor INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE pricing_rules ADD COLUMN currency TEXT NOT NULL DEFAULT 'USD';
+UPDATE pricing_rules SET amount_per_day_minor = CAST(ROUND(amount_per_day * 100) AS INTEGER);
+ALTER TABLE pricing_rules DROP COLUMN amount_per_day;
+
+-- A NULL rate falls back to TAX_RATE.
+ALTER TABLE branches ADD COLUMN tax_rate REAL;
+ALTER TABLE branches ADD COLUMN tax_name TEXT NOT NULL DEFAULT 'VAT';
+
+-- rate is how many units of currency one unit of base buys. Rates are only
+-- used to show prices; charges are always in the price's own currency.
+CREATE TABLE IF NOT EXISTS exchange_rates (
+  base TEXT NOT NULL,
+  currency TEXT NOT NULL,
+  rate REAL NOT NULL,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY(base, currency)
+);
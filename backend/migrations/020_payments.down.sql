@@ -0,0 +1,4 @@
+UPDATE reservations SET status='pending' WHERE status='awaiting_payment';
+
+DROP TABLE IF EXISTS payment_events;
+DROP TABLE IF EXISTS payments;
@@ -0,0 +1,30 @@
+CREATE TABLE payments (
+  id TEXT PRIMARY KEY,
+  reservation_id TEXT NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+  provider TEXT NOT NULL,
+  provider_ref TEXT NOT NULL,
+  status TEXT NOT NULL,
+  amount_minor INTEGER NOT NULL,
+  captured_minor INTEGER NOT NULL DEFAULT 0,
+  refunded_minor INTEGER NOT NULL DEFAULT 0,
+  currency TEXT NOT NULL,
+  failure_reason TEXT NOT NULL DEFAULT '',
+  created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX idx_payments_provider_ref ON payments(provider, provider_ref);
+CREATE INDEX idx_payments_reservation ON payments(reservation_id, created_at);
+
+-- Webhook deliveries already applied, so a redelivered event is a no-op.
+CREATE TABLE payment_events (
+  provider TEXT NOT NULL,
+  event_id TEXT NOT NULL,
+  payment_id TEXT NOT NULL REFERENCES payments(id) ON DELETE CASCADE,
+  status TEXT NOT NULL,
+  received_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (provider, event_id)
+);
+
+-- Bookings that were never paid for wait for payment like new ones do.
+UPDATE reservations SET status='awaiting_payment' WHERE status='pending';
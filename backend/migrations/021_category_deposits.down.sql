@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_payments_reservation;
+DELETE FROM payments WHERE kind='deposit';
+ALTER TABLE payments DROP COLUMN method;
+ALTER TABLE payments DROP COLUMN kind;
+CREATE INDEX idx_payments_reservation ON payments(reservation_id, created_at);
+
+DROP TABLE IF EXISTS category_deposits;
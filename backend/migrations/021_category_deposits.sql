@@ -0,0 +1,14 @@
+CREATE TABLE category_deposits (
+  category TEXT PRIMARY KEY,
+  amount_minor INTEGER NOT NULL,
+  currency TEXT NOT NULL,
+  updated_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A reservation has rental payments and, from pickup on, deposit holds. The
+-- payment method is kept so the deposit can be held on the same card.
+ALTER TABLE payments ADD COLUMN kind TEXT NOT NULL DEFAULT 'rental';
+ALTER TABLE payments ADD COLUMN method TEXT NOT NULL DEFAULT '';
+
+DROP INDEX idx_payments_reservation;
+CREATE INDEX idx_payments_reservation ON payments(reservation_id, kind, created_at);
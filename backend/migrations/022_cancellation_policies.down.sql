@@ -0,0 +1,5 @@
+ALTER TABLE reservations DROP COLUMN refund_minor;
+ALTER TABLE reservations DROP COLUMN cancellation_fee_minor;
+ALTER TABLE reservations DROP COLUMN cancellation_policy;
+DROP TABLE IF EXISTS cancellation_policy_categories;
+DROP TABLE IF EXISTS cancellation_policies;
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS cancellation_policies (
+  id TEXT PRIMARY KEY,
+  name TEXT NOT NULL,
+  tiers TEXT NOT NULL DEFAULT '[]',
+  is_default INTEGER NOT NULL DEFAULT 0,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS cancellation_policy_categories (
+  category TEXT PRIMARY KEY,
+  policy_id TEXT NOT NULL REFERENCES cancellation_policies(id)
+);
+
+-- A booking keeps a copy of the policy it was made under, and what the
+-- cancellation cost once it is cancelled.
+ALTER TABLE reservations ADD COLUMN cancellation_policy TEXT;
+ALTER TABLE reservations ADD COLUMN cancellation_fee_minor INTEGER;
+ALTER TABLE reservations ADD COLUMN refund_minor INTEGER;
@@ -0,0 +1,39 @@
+-- The next number is taken from invoice_sequences in the same transaction
+-- that inserts the invoice, so a failed insert leaves no gap.
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+  year INTEGER PRIMARY KEY,
+  last_number INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+  id TEXT PRIMARY KEY,
+  number TEXT NOT NULL UNIQUE,
+  year INTEGER NOT NULL,
+  sequence INTEGER NOT NULL,
+  reservation_id TEXT NOT NULL UNIQUE REFERENCES reservations(id),
+  user_id TEXT NOT NULL,
+  issued_at DATETIME NOT NULL,
+  seller_name TEXT NOT NULL,
+  seller_address TEXT NOT NULL DEFAULT '',
+  seller_tax_id TEXT NOT NULL DEFAULT '',
+  seller_email TEXT NOT NULL DEFAULT '',
+  customer_name TEXT NOT NULL,
+  customer_email TEXT NOT NULL DEFAULT '',
+  subtotal_minor INTEGER NOT NULL,
+  tax_minor INTEGER NOT NULL,
+  total_minor INTEGER NOT NULL,
+  currency TEXT NOT NULL,
+  UNIQUE(year, sequence)
+);
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+  invoice_id TEXT NOT NULL REFERENCES invoices(id),
+  position INTEGER NOT NULL,
+  kind TEXT NOT NULL,
+  code TEXT NOT NULL,
+  label TEXT NOT NULL,
+  quantity INTEGER NOT NULL,
+  unit_price_minor INTEGER NOT NULL,
+  amount_minor INTEGER NOT NULL,
+  PRIMARY KEY(invoice_id, position)
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_reservations_promo_code;
+ALTER TABLE reservations DROP COLUMN discount_minor;
+ALTER TABLE reservations DROP COLUMN promo_code;
+ALTER TABLE reservations DROP COLUMN promo_code_id;
+DROP TABLE IF EXISTS promo_codes;
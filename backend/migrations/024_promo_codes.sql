@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS promo_codes (
+  id TEXT PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE,
+  kind TEXT NOT NULL,
+  percent REAL NOT NULL DEFAULT 0,
+  amount_minor INTEGER NOT NULL DEFAULT 0,
+  currency TEXT NOT NULL,
+  min_days INTEGER NOT NULL DEFAULT 0,
+  valid_from DATETIME,
+  valid_to DATETIME,
+  max_uses INTEGER NOT NULL DEFAULT 0,
+  max_uses_per_user INTEGER NOT NULL DEFAULT 0,
+  categories TEXT NOT NULL DEFAULT '[]',
+  active INTEGER NOT NULL DEFAULT 1,
+  created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+-- The code is copied so reports keep it after the promo code is deleted.
+ALTER TABLE reservations ADD COLUMN promo_code_id TEXT;
+ALTER TABLE reservations ADD COLUMN promo_code TEXT NOT NULL DEFAULT '';
+ALTER TABLE reservations ADD COLUMN discount_minor INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_reservations_promo_code ON reservations(promo_code_id, user_id);
@@ -0,0 +1 @@
+ALTER TABLE reservations DROP COLUMN hold_expires_at;
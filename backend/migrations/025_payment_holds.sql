@@ -0,0 +1,6 @@
+-- A booking awaiting payment only holds the car until hold_expires_at.
+ALTER TABLE reservations ADD COLUMN hold_expires_at DATETIME;
+
+-- Bookings moved to awaiting_payment when payments were introduced get a
+-- week to be paid for.
+UPDATE reservations SET hold_expires_at=datetime('now', '+7 days') WHERE status='awaiting_payment';
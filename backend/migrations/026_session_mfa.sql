@@ -0,0 +1,3 @@
+-- Sessions started with a second factor. Admin sessions without one, such as
+-- those opened before two-factor login was required, are no longer accepted.
+ALTER TABLE sessions ADD COLUMN mfa_verified INTEGER NOT NULL DEFAULT 0;
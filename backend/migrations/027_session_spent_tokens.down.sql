@@ -0,0 +1,6 @@
+ALTER TABLE sessions ADD COLUMN previous_token_hash TEXT;
+UPDATE sessions SET previous_token_hash=(SELECT token_hash FROM session_spent_tokens t WHERE t.session_id=sessions.id ORDER BY t.spent_at DESC, t.rowid DESC LIMIT 1);
+CREATE INDEX IF NOT EXISTS idx_sessions_previous_token ON sessions(previous_token_hash);
+
+DROP INDEX IF EXISTS idx_session_spent_tokens_session;
+DROP TABLE IF EXISTS session_spent_tokens;
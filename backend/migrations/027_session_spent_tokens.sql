@@ -0,0 +1,15 @@
+-- Every refresh token a session has rotated away from, not only the last
+-- one, so replaying any of them is caught as theft.
+CREATE TABLE IF NOT EXISTS session_spent_tokens (
+  token_hash TEXT PRIMARY KEY,
+  session_id TEXT NOT NULL,
+  spent_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  FOREIGN KEY(session_id) REFERENCES sessions(id)
+);
+CREATE INDEX IF NOT EXISTS idx_session_spent_tokens_session ON session_spent_tokens(session_id);
+
+INSERT OR IGNORE INTO session_spent_tokens(token_hash, session_id)
+SELECT previous_token_hash, id FROM sessions WHERE previous_token_hash IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_sessions_previous_token;
+ALTER TABLE sessions DROP COLUMN previous_token_hash;
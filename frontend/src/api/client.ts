@@ -1,3 +1,18 @@
 import axios from 'axios'
 export const api = axios.create({ baseURL: 'http://localhost:8080/api' })
 api.interceptors.request.use((cfg) => { const t = localStorage.getItem('token'); if (t) cfg.headers.Authorization = `Bearer ${t}`; return cfg })
+
+let refreshing: Promise<string>|null = null
+const refreshToken = () => {
+  refreshing ??= api.post('/auth/refresh', { refreshToken: localStorage.getItem('refreshToken') })
+    .then(({data}) => { localStorage.setItem('token', data.token); localStorage.setItem('refreshToken', data.refreshToken); return data.token as string })
+    .finally(() => { refreshing = null })
+  return refreshing
+}
+api.interceptors.response.use((res) => res, async (err) => {
+  const cfg = err.config
+  if (err.response?.status !== 401 || !cfg || cfg._retry || cfg.url?.startsWith('/auth/') || !localStorage.getItem('refreshToken')) throw err
+  cfg._retry = true
+  try { cfg.headers.Authorization = `Bearer ${await refreshToken()}` } catch { ['token','refreshToken','user'].forEach((k)=>localStorage.removeItem(k)); throw err }
+  return api(cfg)
+})
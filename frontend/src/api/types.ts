@@ -1,4 +1,16 @@
-export type User = { id: string; username: string; role: 'admin'|'user' }
-export type Car = { id:string; brand:string; model:string; year:number; category:string; transmission:string; fuel:string; seats:number; dailyPrice:number; status:string; mileage:number; description:string; images:string[]; createdAt:string }
-export type Extra = { id:string; name:string; pricePerDay:number }
-export type Reservation = { id:string; carId:string; userId:string; startDate:string; endDate:string; pickupLocation:string; dropoffLocation:string; notes:string; status:string; totalPrice:number; extras:Extra[]; car?:Partial<Car>; username?:string }
+export type User = { id: string; username: string; role: 'admin'|'fleet_manager'|'agent'|'user'; permissions?: string[] }
+export type MfaChallenge = { mfaRequired: true; enrollmentRequired: boolean; preAuthToken: string; expiresAt: string }
+export type TotpEnrollment = { secret: string; otpauthUrl: string }
+export type Car = { id:string; brand:string; model:string; year:number; category:string; transmission:string; fuel:string; seats:number; dailyPrice:number; currency?:string; hourlyRates?:{ maxHours:number; pricePerHour:number }[]; status:string; mileage:number; description:string; images:string[]; createdAt:string; occupancy?:{ from:string; to:string; occupied:boolean; bookable:boolean }; rentalDays?:number; rentalHours?:number; totalPrice?:number }
+export type Branch = { id:string; name:string; timezone:string; taxRate?:number|null; taxName?:string }
+export type PriceLine = { kind:'base'|'adjustment'|'extra'|'fee'|'discount'|'tax'|'deposit'; code:string; label:string; quantity:number; unitPrice:number; amount:number }
+export type CancellationPolicy = { id:string; name:string; tiers:{ hoursBefore:number; feePercent:number }[]; categories?:string[]; isDefault:boolean; createdAt:string }
+export type PromoCode = { id:string; code:string; kind:'percent'|'fixed'; percent?:number; amount?:number; currency:string; minDays:number; validFrom?:string; validTo?:string; maxUses:number; maxUsesPerUser:number; categories:string[]; active:boolean; createdAt:string; uses:number; discounted:number }
+export type CancellationQuote = { reservationId:string; allowed:boolean; policy?:CancellationPolicy; hoursBefore:number; feePercent:number; paid:number; fee:number; refund:number; currency:string }
+export type Quote = { quoteId:string; currency:string; expiresAt:string; carId:string; startDate:string; endDate:string; pickupLocation:string; timezone:string; days:number; hours:number; promoCode?:string; lines:PriceLine[]; discount?:number; subtotal:number; tax:number; total:number; deposit:number; cancellationPolicy?:CancellationPolicy }
+export type Extra = { id:string; name:string; pricePerDay:number; currency?:string }
+export type ExchangeRates = { base:string; items:{ currency:string; rate:number; updatedAt:string }[] }
+export type Payment = { id:string; reservationId:string; kind:'rental'|'deposit'; provider:string; providerRef:string; status:'pending'|'authorized'|'captured'|'partially_refunded'|'refunded'|'voided'|'failed'; amount:number; captured:number; refunded:number; currency:string; failureReason?:string; createdAt:string; updatedAt:string }
+export type InvoiceParty = { name:string; address?:string; taxId?:string; email?:string }
+export type Invoice = { id:string; number:string; year:number; sequence:number; reservationId:string; userId:string; issuedAt:string; seller:InvoiceParty; customer:InvoiceParty; lines?:PriceLine[]; subtotal:number; tax:number; total:number; currency:string }
+export type Reservation = { id:string; carId:string; userId:string; startDate:string; endDate:string; pickupLocation:string; dropoffLocation:string; notes:string; status:string; totalPrice:number; currency?:string; timezone?:string; canCancel?:boolean; extras:Extra[]; lineItems?:PriceLine[]; payment?:Payment; deposit?:Payment; car?:Partial<Car>; username?:string; cancellationPolicy?:CancellationPolicy; cancellationFee?:number; refund?:number; promoCode?:string; discount?:number }
@@ -1,14 +1,18 @@
 import { createContext, useContext, useState } from 'react'
 import { api } from '../api/client'
-import { User } from '../api/types'
+import { MfaChallenge, TotpEnrollment, User } from '../api/types'
 
-type Ctx = { user: User|null; login:(u:string,p:string)=>Promise<void>; register:(u:string,p:string)=>Promise<void>; logout:()=>void }
+type Ctx = { user: User|null; login:(u:string,p:string)=>Promise<MfaChallenge|null>; startTotpEnrollment:(preAuthToken:string)=>Promise<TotpEnrollment>; verifyTotp:(preAuthToken:string,code:string)=>Promise<string[]|undefined>; register:(u:string,p:string)=>Promise<void>; logout:()=>void }
 const AuthContext = createContext<Ctx>({} as Ctx)
 export const useAuth = ()=>useContext(AuthContext)
+export const can = (user:User|null, permission:string) => !!user && (user.role==='admin' || !!user.permissions?.includes(permission))
 export function AuthProvider({children}:{children:React.ReactNode}) {
   const [user, setUser] = useState<User|null>(JSON.parse(localStorage.getItem('user')||'null'))
-  const login = async (username:string,password:string) => { const {data}=await api.post('/auth/login',{username,password}); localStorage.setItem('token',data.token); localStorage.setItem('user',JSON.stringify(data.user)); setUser(data.user) }
+  const store = (data:{token:string; refreshToken:string; user:User; permissions?:string[]}) => { const u={...data.user,permissions:data.permissions??[]}; localStorage.setItem('token',data.token); localStorage.setItem('refreshToken',data.refreshToken); localStorage.setItem('user',JSON.stringify(u)); setUser(u) }
+  const login = async (username:string,password:string) => { const {data}=await api.post('/auth/login',{username,password}); if (data.mfaRequired) return data as MfaChallenge; store(data); return null }
+  const startTotpEnrollment = async (preAuthToken:string) => { const {data}=await api.post('/auth/login/totp/setup',{preAuthToken}); return data as TotpEnrollment }
+  const verifyTotp = async (preAuthToken:string,code:string) => { const {data}=await api.post('/auth/login/totp',{preAuthToken,code}); store(data); return data.recoveryCodes as string[]|undefined }
   const register = async (username:string,password:string)=>{ await api.post('/auth/register',{username,password}) }
-  const logout = ()=>{ localStorage.removeItem('token'); localStorage.removeItem('user'); setUser(null) }
-  return <AuthContext.Provider value={{user,login,register,logout}}>{children}</AuthContext.Provider>
+  const logout = ()=>{ const refreshToken=localStorage.getItem('refreshToken'); if (refreshToken) api.post('/auth/logout',{refreshToken}).catch(()=>{}); localStorage.removeItem('token'); localStorage.removeItem('refreshToken'); localStorage.removeItem('user'); setUser(null) }
+  return <AuthContext.Provider value={{user,login,startTotpEnrollment,verifyTotp,register,logout}}>{children}</AuthContext.Provider>
 }
@@ -1,4 +1,6 @@
 import { ReactNode, forwardRef } from 'react'
+import type { CancellationPolicy, PriceLine } from '../api/types'
+import { useMoney } from '../hooks/useMoney'
 
 export const Input = forwardRef<HTMLInputElement, any>(function Input(
 	{ className, ...p },
@@ -25,3 +27,25 @@ export const Table=({head,rows}:{head:string[];rows:ReactNode[]})=>(
 		</table>
 	</div>
 )
+export const PriceLines=({total,currency,lines}:{total:number;currency?:string;lines?:PriceLine[]})=>{
+	const { money } = useMoney()
+	if (!lines?.length) return <>{money(total, currency)}</>
+	return (
+		<details>
+			<summary className='cursor-pointer'>{money(total, currency)}</summary>
+			{lines.map((l,i)=>(
+				<div key={i} className={`flex justify-between gap-3 text-xs ${l.kind==='deposit'?'text-slate-500':''}`}>
+					<span>{l.label}{l.quantity>1?` ${l.quantity} × ${money(l.unitPrice, currency)}`:''}</span>
+					<span>{money(l.amount, currency)}</span>
+				</div>
+			))}
+		</details>
+	)
+}
+// CancellationTerms lists a policy's fees from the longest notice down, e.g.
+// "Flexible: <48h 50% · <24h 100%". Earlier cancellations are free.
+export const CancellationTerms=({policy}:{policy?:CancellationPolicy})=>{
+	if (!policy?.tiers.length) return null
+	const tiers=[...policy.tiers].sort((a,b)=>b.hoursBefore-a.hoursBefore)
+	return <span>{policy.name}: {tiers.map(t=>`<${t.hoursBefore}h ${t.feePercent}%`).join(' · ')}</span>
+}
@@ -0,0 +1,57 @@
+import { useEffect, useState } from 'react'
+import { useQuery } from '@tanstack/react-query'
+import { api } from '../api/client'
+import type { ExchangeRates } from '../api/types'
+
+const CURRENCY_KEY = 'displayCurrency'
+const CURRENCY_EVENT = 'app:currency-change'
+
+function readCurrency(): string {
+	return localStorage.getItem(CURRENCY_KEY) || ''
+}
+
+function formatIn(amount: number, currency: string) {
+	try {
+		return new Intl.NumberFormat(undefined, { style: 'currency', currency }).format(amount)
+	} catch {
+		return `${amount.toFixed(2)} ${currency}`
+	}
+}
+
+// useMoney formats prices in their own currency, or converted to the chosen
+// display currency (marked with ≈) when the admin has entered a rate for it.
+// Charges are always made in the price's own currency.
+export function useMoney() {
+	const [display, setDisplayState] = useState(readCurrency)
+	const { data } = useQuery<ExchangeRates>({ queryKey: ['exchange-rates'], queryFn: async () => (await api.get('/exchange-rates')).data, staleTime: 5 * 60_000 })
+
+	useEffect(() => {
+		const onChange = () => setDisplayState(readCurrency())
+		window.addEventListener('storage', onChange)
+		window.addEventListener(CURRENCY_EVENT, onChange)
+		return () => {
+			window.removeEventListener('storage', onChange)
+			window.removeEventListener(CURRENCY_EVENT, onChange)
+		}
+	}, [])
+
+	const setDisplay = (next: string) => {
+		if (next) localStorage.setItem(CURRENCY_KEY, next)
+		else localStorage.removeItem(CURRENCY_KEY)
+		setDisplayState(next)
+		window.dispatchEvent(new Event(CURRENCY_EVENT))
+	}
+
+	const base = data?.base || 'USD'
+	const currencies = [base, ...(data?.items || []).map(r => r.currency)]
+
+	const money = (amount: number, currency: string = base) => {
+		const rate = data?.items.find(r => r.currency === display)?.rate
+		if (display && display !== currency && currency === base && rate) {
+			return `≈ ${formatIn(Math.round(amount * rate * 100) / 100, display)}`
+		}
+		return formatIn(amount, currency)
+	}
+
+	return { money, display: display || base, setDisplay, currencies }
+}
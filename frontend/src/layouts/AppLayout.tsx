@@ -1,11 +1,12 @@
 import { useEffect, useState, type MouseEvent } from 'react'
 import { Link, NavLink, Outlet, useLocation } from 'react-router-dom'
 import toast from 'react-hot-toast'
-import { useAuth } from '../auth/AuthContext'
+import { can, useAuth } from '../auth/AuthContext'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 
 type NavItem = { to: string; label: string; icon: JSX.Element }
-type NavItemKey = { to: string; key: string; icon: JSX.Element }
+type NavItemKey = { to: string; key: string; icon: JSX.Element; permission?: string }
 
 function icon(path: string) {
 	return (
@@ -23,9 +24,9 @@ const userItems: NavItemKey[] = [
 ]
 
 const adminItems: NavItemKey[] = [
-	{ to: '/admin/dashboard', key: 'dashboard', icon: icon('M4 13h7V4H4v9zm9 7h7V4h-7v16zM4 20h7v-5H4v5z') },
-	{ to: '/admin/cars', key: 'manageCars', icon: icon('M3 13l2-5a2 2 0 0 1 2-1h10a2 2 0 0 1 2 1l2 5M5 13h14v5H5v-5zm2 3h.01M17 16h.01') },
-	{ to: '/admin/reservations', key: 'reservations', icon: icon('M7 3h10M5 7h14M6 11h12M7 15h10M9 19h6') },
+	{ to: '/admin/dashboard', key: 'dashboard', permission: 'dashboard:read', icon: icon('M4 13h7V4H4v9zm9 7h7V4h-7v16zM4 20h7v-5H4v5z') },
+	{ to: '/admin/cars', key: 'manageCars', permission: 'cars:write', icon: icon('M3 13l2-5a2 2 0 0 1 2-1h10a2 2 0 0 1 2 1l2 5M5 13h14v5H5v-5zm2 3h.01M17 16h.01') },
+	{ to: '/admin/reservations', key: 'reservations', permission: 'reservations:read', icon: icon('M7 3h10M5 7h14M6 11h12M7 15h10M9 19h6') },
 ]
 
 function SidebarLink({ to, label, icon: iconNode, onClick }: NavItem & { onClick?: (e: MouseEvent<HTMLAnchorElement>) => void }) {
@@ -48,6 +49,7 @@ function SidebarLink({ to, label, icon: iconNode, onClick }: NavItem & { onClick
 export default function AppLayout() {
 	const { user, logout } = useAuth()
 	const { lang, setLang } = useLanguage()
+	const { display, setDisplay, currencies } = useMoney()
 	const { pathname } = useLocation()
 	const [mobileMenuOpen, setMobileMenuOpen] = useState(false)
 	const t = lang === 'bs'
@@ -55,6 +57,7 @@ export default function AppLayout() {
 			navigation: 'Navigacija',
 			admin: 'Admin',
 			language: 'Jezik',
+			currency: 'Valuta',
 			logout: 'Odjava',
 			fallbackUser: 'Korisnik',
 			roleUser: 'korisnik',
@@ -73,6 +76,7 @@ export default function AppLayout() {
 			navigation: 'Navigation',
 			admin: 'Admin',
 			language: 'Language',
+			currency: 'Currency',
 			logout: 'Logout',
 			fallbackUser: 'User',
 			roleUser: 'user',
@@ -93,7 +97,7 @@ export default function AppLayout() {
 		label: t[item.key as keyof typeof t] as string,
 		icon: item.icon,
 	}))
-	const localizedAdminItems: NavItem[] = adminItems.map(item => ({
+	const localizedAdminItems: NavItem[] = adminItems.filter(item => !item.permission || can(user, item.permission)).map(item => ({
 		to: item.to,
 		label: t[item.key as keyof typeof t] as string,
 		icon: item.icon,
@@ -140,7 +144,7 @@ export default function AppLayout() {
 				</div>
 			</div>
 
-			{user?.role === 'admin' && (
+			{localizedAdminItems.length > 0 && (
 				<div className='mb-4'>
 					<p className='text-xs uppercase tracking-wide text-slate-400'>{t.admin}</p>
 					<div className='mt-2 space-y-1.5'>
@@ -178,6 +182,14 @@ export default function AppLayout() {
 							BS
 						</button>
 					</div>
+					{currencies.length > 1 && (
+						<>
+							<p className='text-xs uppercase tracking-wide text-slate-400 mt-3 mb-2'>{t.currency}</p>
+							<select value={display} onChange={e => setDisplay(e.target.value)} className='w-full h-9 rounded-lg border border-slate-300 bg-white px-2 text-sm'>
+								{currencies.map(c => <option key={c} value={c}>{c}</option>)}
+							</select>
+						</>
+					)}
 				</div>
 				{user ? (
 					<>
@@ -189,7 +201,7 @@ export default function AppLayout() {
 							</div>
 							<div>
 								<p className='text-sm font-semibold text-slate-900'>{user.username}</p>
-								<p className='text-xs text-slate-500 capitalize'>{user.role === 'admin' ? 'admin' : t.roleUser}</p>
+								<p className='text-xs text-slate-500 capitalize'>{user.role === 'user' ? t.roleUser : user.role.replace('_', ' ')}</p>
 							</div>
 						</div>
 						<button
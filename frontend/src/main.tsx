@@ -22,9 +22,9 @@ ReactDOM.createRoot(document.getElementById('root')!).render(
   <Route element={<ProtectedRoute><AppLayout/></ProtectedRoute>}>
     <Route path='my-reservations' element={<MyReservationsPage/>}/>
     <Route path='wishlist' element={<WishlistPage/>}/>
-    <Route path='admin/cars' element={<ProtectedRoute admin><AdminCarsPage/></ProtectedRoute>}/>
-    <Route path='admin/reservations' element={<ProtectedRoute admin><AdminReservationsPage/></ProtectedRoute>}/>
-    <Route path='admin/dashboard' element={<ProtectedRoute admin><AdminDashboardPage/></ProtectedRoute>}/>
+    <Route path='admin/cars' element={<ProtectedRoute permission='cars:write'><AdminCarsPage/></ProtectedRoute>}/>
+    <Route path='admin/reservations' element={<ProtectedRoute permission='reservations:read'><AdminReservationsPage/></ProtectedRoute>}/>
+    <Route path='admin/dashboard' element={<ProtectedRoute permission='dashboard:read'><AdminDashboardPage/></ProtectedRoute>}/>
   </Route>
   <Route path='*' element={<Navigate to='/'/>}/>
 </Routes>
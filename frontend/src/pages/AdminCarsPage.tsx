@@ -16,6 +16,7 @@ type CarForm = {
 	fuel: string
 	seats: number | string
 	dailyPrice: number | string
+	hourlyRates: string
 	status: string
 	mileage: number | string
 	description: string
@@ -25,13 +26,13 @@ type CarForm = {
 const CAR_CATEGORIES = ['sedan', 'suv', 'hatchback', 'wagon', 'coupe', 'convertible', 'pickup', 'van']
 const TRANSMISSIONS = ['manual', 'automatic']
 const FUELS = ['gasoline', 'diesel', 'hybrid', 'electric', 'lpg']
-const CAR_STATUSES = ['available', 'rented', 'maintenance']
+const CAR_STATUSES = ['available', 'maintenance', 'retired']
 const copy: Record<'en' | 'bs', {
 	title: string
 	searchPlaceholder: string
 	allStatus: string
 	statusAvailable: string
-	statusRented: string
+	statusRetired: string
 	statusMaintenance: string
 	closeForm: string
 	addNewCar: string
@@ -50,6 +51,7 @@ const copy: Record<'en' | 'bs', {
 	fuel: string
 	seats: string
 	dailyPrice: string
+	hourlyRates: string
 	status: string
 	mileage: string
 	description: string
@@ -86,7 +88,7 @@ const copy: Record<'en' | 'bs', {
 		searchPlaceholder: 'Search Car Name',
 		allStatus: 'All Status',
 		statusAvailable: 'Available',
-		statusRented: 'Rented',
+		statusRetired: 'Retired',
 		statusMaintenance: 'Maintenance',
 		closeForm: 'Close New Car Form',
 		addNewCar: 'Add New Car',
@@ -105,6 +107,7 @@ const copy: Record<'en' | 'bs', {
 		fuel: 'Fuel',
 		seats: 'Seats',
 		dailyPrice: 'Daily Price',
+		hourlyRates: 'Hourly rates (hours:price, e.g. 4:15, 12:12)',
 		status: 'Status',
 		mileage: 'Mileage',
 		description: 'Description',
@@ -141,7 +144,7 @@ const copy: Record<'en' | 'bs', {
 		searchPlaceholder: 'Pretraga Naziva Auta',
 		allStatus: 'Svi Statusi',
 		statusAvailable: 'Dostupno',
-		statusRented: 'Iznajmljeno',
+		statusRetired: 'Povučeno',
 		statusMaintenance: 'Servis',
 		closeForm: 'Zatvori Formu',
 		addNewCar: 'Dodaj Novo Vozilo',
@@ -160,6 +163,7 @@ const copy: Record<'en' | 'bs', {
 		fuel: 'Gorivo',
 		seats: 'Sjedista',
 		dailyPrice: 'Dnevna Cijena',
+		hourlyRates: 'Cijene po satu (sati:cijena, npr. 4:15, 12:12)',
 		status: 'Status',
 		mileage: 'Kilometraza',
 		description: 'Opis',
@@ -231,8 +235,8 @@ const formatOptionLabel = (value: string, lang: 'en' | 'bs'): string => {
 		if (value === 'pickup') return 'Pickup'
 		if (value === 'van') return 'Kombi'
 		if (value === 'available') return 'Dostupno'
-		if (value === 'rented') return 'Iznajmljeno'
 		if (value === 'maintenance') return 'Servis'
+		if (value === 'retired') return 'Povučeno'
 	}
 	return value.charAt(0).toUpperCase() + value.slice(1)
 }
@@ -261,17 +265,32 @@ const validateCarForm = (v: CarForm, t: (typeof copy)['en']): string | null => {
 
 const localizeStatus = (status: string, t: (typeof copy)['en']): string => {
 	if (status === 'available') return t.statusAvailable
-	if (status === 'rented') return t.statusRented
 	if (status === 'maintenance') return t.statusMaintenance
+	if (status === 'retired') return t.statusRetired
 	return status
 }
 
+// "4:15, 12:12" -> up to 4h at 15/h, up to 12h at 12/h
+const parseHourlyRates = (value: string) =>
+	value
+		.split(',')
+		.map(x => x.trim())
+		.filter(Boolean)
+		.map(x => {
+			const [maxHours, pricePerHour] = x.split(':')
+			return { maxHours: Number(maxHours), pricePerHour: Number(pricePerHour) }
+		})
+
+const formatHourlyRates = (rates: { maxHours: number; pricePerHour: number }[] | undefined) =>
+	Array.isArray(rates) ? rates.map(r => `${r.maxHours}:${r.pricePerHour}`).join(', ') : ''
+
 const toPayload = (v: CarForm) => ({
 	...v,
 	year: +v.year,
 	seats: +v.seats,
 	mileage: +v.mileage,
 	dailyPrice: +v.dailyPrice,
+	hourlyRates: parseHourlyRates(String(v.hourlyRates || '')),
 	category: normalizeEnum(v.category),
 	transmission: normalizeEnum(v.transmission),
 	fuel: normalizeEnum(v.fuel),
@@ -364,6 +383,7 @@ export default function AdminCarsPage() {
 			fuel: normalizeEnum(c.fuel),
 			seats: c.seats || '',
 			dailyPrice: c.dailyPrice || '',
+			hourlyRates: formatHourlyRates(c.hourlyRates),
 			status: normalizeEnum(c.status),
 			mileage: c.mileage || '',
 			description: c.description || '',
@@ -416,8 +436,8 @@ export default function AdminCarsPage() {
 				<Select value={statusFilter} onChange={e => setStatusFilter(e.target.value)}>
 					<option value=''>{t.allStatus}</option>
 					<option value='available'>{t.statusAvailable}</option>
-					<option value='rented'>{t.statusRented}</option>
 					<option value='maintenance'>{t.statusMaintenance}</option>
+					<option value='retired'>{t.statusRetired}</option>
 				</Select>
 				<div className='flex items-center'>
 					<button
@@ -472,6 +492,7 @@ export default function AdminCarsPage() {
 					</Select>
 					<Input placeholder={t.seats} {...registerCreate('seats')} />
 					<Input placeholder={t.dailyPrice} {...registerCreate('dailyPrice')} />
+					<Input placeholder={t.hourlyRates} {...registerCreate('hourlyRates')} />
 					<Select {...registerCreate('status')} defaultValue='available'>
 						{CAR_STATUSES.map(x => <option key={x} value={x}>{formatOptionLabel(x, lang)}</option>)}
 					</Select>
@@ -590,6 +611,7 @@ export default function AdminCarsPage() {
 					</Select>
 					<Input placeholder={t.seats} {...registerEdit('seats')} />
 					<Input placeholder={t.dailyPrice} {...registerEdit('dailyPrice')} />
+					<Input placeholder={t.hourlyRates} {...registerEdit('hourlyRates')} />
 					<Select {...registerEdit('status')}>
 						<option value=''>{t.status}</option>
 						{CAR_STATUSES.map(x => <option key={x} value={x}>{formatOptionLabel(x, lang)}</option>)}
@@ -1,19 +1,22 @@
 import { useQuery } from '@tanstack/react-query'
 import { api } from '../api/client'
+import type { Invoice } from '../api/types'
 import { Table } from '../components/UI'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 
-function MetricCard({ name, value }: { name: string; value: number }) {
+function MetricCard({ name, value }: { name: string; value: string }) {
 	return (
 		<div className='bg-white p-3 rounded border border-slate-200'>
 			<p className='text-xs text-slate-500'>{name}</p>
-			<p className='text-xl font-semibold'>{Number(value).toFixed(0)}</p>
+			<p className='text-xl font-semibold'>{value}</p>
 		</div>
 	)
 }
 
 export default function AdminDashboardPage() {
 	const { lang } = useLanguage()
+	const { money } = useMoney()
 	const t = lang === 'bs'
 		? {
 			title: 'Pregled',
@@ -33,6 +36,11 @@ export default function AdminDashboardPage() {
 			activeRentals: 'Aktivni Najmovi',
 			pendingReservations: 'Rezervacije na cekanju',
 			revenue: 'Prihod',
+			depositCharges: 'Naplaceno iz depozita',
+			invoices: 'Racuni',
+			number: 'Broj',
+			issued: 'Izdat',
+			customer: 'Kupac',
 		}
 		: {
 			title: 'Dashboard',
@@ -52,11 +60,26 @@ export default function AdminDashboardPage() {
 			activeRentals: 'Active Rentals',
 			pendingReservations: 'Pending Reservations',
 			revenue: 'Revenue',
+			depositCharges: 'Deposit Charges',
+			invoices: 'Invoices',
+			number: 'Number',
+			issued: 'Issued',
+			customer: 'Customer',
 		}
 	const { data } = useQuery({
 		queryKey: ['dash'],
 		queryFn: async () => (await api.get('/admin/dashboard')).data,
 	})
+	// Needs reservations:read, which not every dashboard user has.
+	const { data: invoices } = useQuery<{ items: Invoice[] }>({
+		queryKey: ['admin-invoices'],
+		queryFn: async () => (await api.get('/admin/invoices')).data,
+		retry: false,
+	})
+	const openInvoice = async (id: string) => {
+		const res = await api.get(`/admin/invoices/${id}`, { params: { format: 'pdf' }, responseType: 'blob' })
+		window.open(URL.createObjectURL(res.data), '_blank')
+	}
 	if (!data) return null
 	const m = data.metrics
 	const metricLabel = (key: string) => {
@@ -64,15 +87,21 @@ export default function AdminDashboardPage() {
 		if (key === 'availableCars') return t.availableCars
 		if (key === 'activeRentals') return t.activeRentals
 		if (key === 'pendingReservations') return t.pendingReservations
-		if (key === 'revenue') return t.revenue
 		return key
 	}
+	// Money is totalled per currency, one card each.
+	const totals = (name: string, byCurrency: Record<string, number> = {}) =>
+		Object.entries(byCurrency).map(([currency, amount]) => (
+			<MetricCard key={`${name}-${currency}`} name={`${name} (${currency})`} value={money(amount, currency)} />
+		))
 
 	return (
 		<div className='space-y-3'>
 			<h1 className='text-xl'>{t.title}</h1>
 			<div className='grid grid-cols-2 md:grid-cols-5 gap-2'>
-				{Object.entries(m).map(([k, v]) => <MetricCard key={k} name={metricLabel(k)} value={Number(v)} />)}
+				{Object.entries(m).map(([k, v]) => <MetricCard key={k} name={metricLabel(k)} value={Number(v).toFixed(0)} />)}
+				{totals(t.revenue, data.revenue)}
+				{totals(t.depositCharges, data.depositCharges)}
 			</div>
 
 			<div className='bg-white p-3 rounded border border-slate-200'>
@@ -92,11 +121,30 @@ export default function AdminDashboardPage() {
 						<td className='p-2'>{r.username}</td>
 						<td className='p-2'>{r.car?.brand} {r.car?.model}</td>
 						<td className='p-2'>{r.status}</td>
-						<td className='p-2'>${r.totalPrice}</td>
+						<td className='p-2'>{money(r.totalPrice, r.currency)}</td>
 					</>
 				))}
 			/>
 
+			{invoices && (
+				<div className='bg-white p-3 rounded border border-slate-200'>
+					<h2 className='font-medium mb-2'>{t.invoices}</h2>
+					<Table
+						head={[t.number, t.issued, t.customer, t.total]}
+						rows={invoices.items.map(inv => (
+							<>
+								<td className='p-2'>
+									<button className='underline' onClick={() => openInvoice(inv.id)}>{inv.number}</button>
+								</td>
+								<td className='p-2'>{inv.issuedAt.slice(0, 10)}</td>
+								<td className='p-2'>{inv.customer.name}</td>
+								<td className='p-2'>{money(inv.total, inv.currency)}</td>
+							</>
+						))}
+					/>
+				</div>
+			)}
+
 			<div className='bg-white p-3 rounded border border-slate-200'>
 				<h2 className='font-medium mb-2'>{t.auditLog}</h2>
 				<Table
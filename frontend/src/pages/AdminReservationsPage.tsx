@@ -2,7 +2,7 @@ import { useMemo, useState } from 'react'
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import toast from 'react-hot-toast'
 import { api } from '../api/client'
-import { Input, Select, Table } from '../components/UI'
+import { Input, PriceLines, Select, Table } from '../components/UI'
 import { useLanguage } from '../hooks/useLanguage'
 
 const copy = {
@@ -18,6 +18,12 @@ const copy = {
 		actions: 'Actions',
 		statusChanged: 'Status changed to',
 		updateFailed: 'Failed to update status',
+		payment: 'Payment',
+		deposit: 'Deposit',
+		cancellationFee: 'Cancellation fee',
+		promoCode: 'Promo code',
+		depositCharge: 'Amount to keep from the deposit',
+		awaiting_payment: 'Awaiting payment',
 		pending: 'Pending',
 		approved: 'Approved',
 		denied: 'Denied',
@@ -37,6 +43,12 @@ const copy = {
 		actions: 'Akcije',
 		statusChanged: 'Status promijenjen na',
 		updateFailed: 'Azuriranje statusa nije uspjelo',
+		payment: 'Placanje',
+		deposit: 'Depozit',
+		cancellationFee: 'Naknada za otkazivanje',
+		promoCode: 'Promo kod',
+		depositCharge: 'Iznos koji se zadrzava od depozita',
+		awaiting_payment: 'Ceka placanje',
 		pending: 'Na cekanju',
 		approved: 'Odobreno',
 		denied: 'Odbijeno',
@@ -47,6 +59,7 @@ const copy = {
 } as const
 
 const localizeStatus = (s: string, t: (typeof copy)['en']) => {
+	if (s === 'awaiting_payment') return t.awaiting_payment
 	if (s === 'pending') return t.pending
 	if (s === 'approved') return t.approved
 	if (s === 'denied') return t.denied
@@ -68,8 +81,8 @@ export default function AdminReservationsPage() {
 		refetchInterval: 10000,
 	})
 	const m = useMutation({
-		mutationFn: ({ id, status }: { id: string; status: string }) =>
-			api.patch(`/admin/reservations/${id}/status`, { status }),
+		mutationFn: ({ id, status, depositCharge }: { id: string; status: string; depositCharge?: number }) =>
+			api.patch(`/admin/reservations/${id}/status`, { status, depositCharge }),
 		onSuccess: async (_, vars) => {
 			toast.success(`${t.statusChanged} ${localizeStatus(vars.status, t)}`)
 			await qc.invalidateQueries({ queryKey: ['admin-res'] })
@@ -80,6 +93,25 @@ export default function AdminReservationsPage() {
 		},
 	})
 	const statuses = ['approved', 'denied', 'active', 'completed']
+	// Mirrors the server-side state machine; anything else is rejected with 409.
+	// Approving captures the payment, denying or cancelling releases it.
+	// Pickup holds the deposit; completing keeps the charge entered here.
+	const nextStatuses: Record<string, string[]> = {
+		awaiting_payment: ['cancelled'],
+		pending: ['approved', 'denied', 'cancelled'],
+		approved: ['active', 'cancelled'],
+		active: ['completed'],
+	}
+
+	const changeStatus = (r: any, status: string) => {
+		if (status !== 'completed' || r.deposit?.status !== 'authorized') {
+			m.mutate({ id: r.id, status })
+			return
+		}
+		const input = window.prompt(`${t.depositCharge} (${r.deposit.amount} ${r.deposit.currency})`, '0')
+		if (input === null) return
+		m.mutate({ id: r.id, status, depositCharge: Number(input) || 0 })
+	}
 
 	const rowsData = useMemo(
 		() =>
@@ -102,6 +134,7 @@ export default function AdminReservationsPage() {
 				<Input placeholder={t.searchPlaceholder} value={query} onChange={e => setQuery(e.target.value)} />
 				<Select value={statusFilter} onChange={e => setStatusFilter(e.target.value)}>
 					<option value=''>{t.allStatuses}</option>
+					<option value='awaiting_payment'>{t.awaiting_payment}</option>
 					<option value='pending'>{t.pending}</option>
 					{statuses.map(s => <option key={s} value={s}>{localizeStatus(s, t)}</option>)}
 					<option value='cancelled'>{t.cancelled}</option>
@@ -113,12 +146,18 @@ export default function AdminReservationsPage() {
 					<>
 						<td className='p-2'>{r.username}</td>
 						<td className='p-2'>{r.car?.brand} {r.car?.model}</td>
-						<td className='p-2'>{r.startDate.slice(0, 10)}-{r.endDate.slice(0, 10)}</td>
+						<td className='p-2'>{r.startDate.slice(0, 16).replace('T', ' ')}-{r.endDate.slice(0, 16).replace('T', ' ')} <span className='text-xs text-slate-500'>{r.timezone}</span></td>
 						<td className='p-2'>{localizeStatus(r.status, t)}</td>
-						<td className='p-2'>${r.totalPrice}</td>
+						<td className='p-2'>
+							<PriceLines total={r.totalPrice} currency={r.currency} lines={r.lineItems} />
+							{r.payment && <div className='text-xs text-slate-500' title={r.payment.failureReason}>{t.payment}: {r.payment.status.replace('_', ' ')}</div>}
+							{r.promoCode && <div className='text-xs text-slate-500'>{t.promoCode}: {r.promoCode} (-{r.discount} {r.currency})</div>}
+							{r.cancellationFee > 0 && <div className='text-xs text-slate-500'>{t.cancellationFee}: {r.cancellationFee} {r.currency}</div>}
+							{r.deposit && <div className='text-xs text-slate-500' title={r.deposit.failureReason}>{t.deposit}: {r.deposit.status.replace('_', ' ')}{r.deposit.captured > 0 && ` (${r.deposit.captured} ${r.deposit.currency})`}</div>}
+						</td>
 						<td className='p-2 space-x-2'>
-							{statuses.map(s => (
-								<button key={s} onClick={() => m.mutate({ id: r.id, status: s })} disabled={m.isPending || r.status === s}>
+							{(nextStatuses[r.status] || []).map(s => (
+								<button key={s} onClick={() => changeStatus(r, s)} disabled={m.isPending}>
 									{localizeStatus(s, t)}
 								</button>
 							))}
@@ -2,11 +2,13 @@ import { useMutation, useQuery } from '@tanstack/react-query'
 import { useEffect, useMemo, useState } from 'react'
 import { useNavigate, useParams } from 'react-router-dom'
 import { api } from '../api/client'
-import { Button, Input } from '../components/UI'
+import { Button, CancellationTerms, Input, Select } from '../components/UI'
+import type { Branch, Quote } from '../api/types'
 import { useForm } from 'react-hook-form'
 import toast from 'react-hot-toast'
 import { resolveImageSrc } from '../utils/image'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 import { useAuth } from '../auth/AuthContext'
 
 const copy = {
@@ -28,10 +30,17 @@ const copy = {
 		perDay: '/day',
 		reserve: 'Reserve',
 		pickupLocation: 'Pickup location',
+		localTimeHint: 'Pickup and return times are local to the pickup location.',
 		dropoffLocation: 'Dropoff location',
 		notes: 'Notes',
+		promoCode: 'Promo code',
 		submitting: 'Submitting...',
 		submit: 'Submit',
+		getQuote: 'Get quote',
+		quoteTotal: 'Total',
+		quoteValidUntil: 'Price held until',
+		cancellation: 'Cancellation fee before pickup',
+		quoteFailed: 'Failed to get a quote',
 		reviewsTitle: 'Reviews',
 		totalReviews: 'reviews',
 		noReviews: 'No reviews yet.',
@@ -64,10 +73,17 @@ const copy = {
 		perDay: '/dan',
 		reserve: 'Rezervisi',
 		pickupLocation: 'Lokacija preuzimanja',
+		localTimeHint: 'Vrijeme preuzimanja i vracanja je lokalno vrijeme lokacije preuzimanja.',
 		dropoffLocation: 'Lokacija vracanja',
 		notes: 'Napomena',
+		promoCode: 'Promo kod',
 		submitting: 'Slanje...',
 		submit: 'Potvrdi',
+		getQuote: 'Izracunaj cijenu',
+		quoteTotal: 'Ukupno',
+		quoteValidUntil: 'Cijena vazi do',
+		cancellation: 'Naknada za otkazivanje prije preuzimanja',
+		quoteFailed: 'Izracun cijene nije uspio',
 		reviewsTitle: 'Recenzije',
 		totalReviews: 'recenzija',
 		noReviews: 'Jos nema recenzija.',
@@ -84,6 +100,9 @@ const copy = {
 	},
 } as const
 
+// Times come back with the pickup branch's offset; show that wall-clock time.
+const formatRangeTime = (value: string) => value.slice(0, 16).replace('T', ' ')
+
 function InfoItem({ icon, label, value }: { icon: JSX.Element; label: string; value: React.ReactNode }) {
 	return (
 		<div className='rounded-xl border border-slate-200 bg-slate-50/90 p-3 transition hover:border-cyan-200 hover:bg-cyan-50/40'>
@@ -103,6 +122,7 @@ export default function CarDetailPage() {
 	const navigate = useNavigate()
 	const { user } = useAuth()
 	const { lang } = useLanguage()
+	const { money } = useMoney()
 	const t = copy[lang]
 	const [imageIndex, setImageIndex] = useState(0)
 	const [showFullscreen, setShowFullscreen] = useState(false)
@@ -113,6 +133,7 @@ export default function CarDetailPage() {
 	})
 	const { data: car } = useQuery({ queryKey: ['car', id], queryFn: async () => (await api.get('/cars/' + id)).data })
 	const { data: extras = [] } = useQuery({ queryKey: ['extras'], queryFn: async () => (await api.get('/extras')).data })
+	const { data: branches = [] } = useQuery({ queryKey: ['branches'], queryFn: async () => (await api.get('/branches')).data.items as Branch[] })
 	const { data: availability } = useQuery({
 		queryKey: ['availability', id],
 		queryFn: async () => (await api.get(`/cars/${id}/availability`)).data,
@@ -121,10 +142,28 @@ export default function CarDetailPage() {
 		queryKey: ['car-reviews', id],
 		queryFn: async () => (await api.get(`/cars/${id}/reviews`)).data,
 	})
-	const { register, handleSubmit } = useForm<any>()
+	const { register, handleSubmit, watch } = useForm<any>()
+	const [quote, setQuote] = useState<Quote | null>(null)
 	const { register: registerReview, handleSubmit: handleSubmitReview, reset: resetReview, watch: watchReview } = useForm<any>({
 		defaultValues: { rating: 5, comment: '' },
 	})
+	// A quote only holds for the exact booking it was made for.
+	useEffect(() => {
+		const sub = watch(() => setQuote(null))
+		return () => sub.unsubscribe()
+	}, [watch])
+	const bookingPayload = (v: any) => ({
+		...v,
+		carId: id,
+		extraIds: Object.entries(v)
+			.filter(([k, val]) => k.startsWith('ex_') && val)
+			.map(([k]) => k.replace('ex_', '')),
+	})
+	const quoteMutation = useMutation({
+		mutationFn: async (v: any) => (await api.post('/quotes', v)).data as Quote,
+		onSuccess: data => setQuote(data),
+		onError: (err: any) => toast.error(err?.response?.data?.error || t.quoteFailed),
+	})
 	const m = useMutation({
 		mutationFn: (v: any) => api.post('/reservations', v),
 		onSuccess: () => toast.success(t.reservationSubmitted),
@@ -245,7 +284,7 @@ export default function CarDetailPage() {
 						<ul className='text-sm space-y-1'>
 							{availability.items.map((x: any, i: number) => (
 								<li key={i}>
-									{x.startDate.slice(0, 10)} - {x.endDate.slice(0, 10)} ({x.status})
+									{formatRangeTime(x.startDate)} - {formatRangeTime(x.endDate)} ({x.status})
 								</li>
 							))}
 						</ul>
@@ -339,7 +378,7 @@ export default function CarDetailPage() {
 								/>
 								<InfoItem
 									label={t.price}
-									value={`$${car.dailyPrice}${t.perDay}`}
+									value={`${money(car.dailyPrice, car.currency)}${t.perDay}`}
 									icon={
 										<svg viewBox='0 0 24 24' className='w-4 h-4' fill='none' stroke='currentColor' strokeWidth='2'>
 											<path d='M12 1v22M17 5a4 4 0 0 0-4-2H9a4 4 0 0 0 0 8h6a4 4 0 0 1 0 8H9a4 4 0 0 1-4-2' strokeLinecap='round' strokeLinejoin='round' />
@@ -357,13 +396,7 @@ export default function CarDetailPage() {
 							toast.error(t.loginRequired)
 							return
 						}
-						m.mutate({
-							...v,
-							carId: id,
-							extraIds: Object.entries(v)
-								.filter(([k, val]) => k.startsWith('ex_') && val)
-								.map(([k]) => k.replace('ex_', '')),
-						})
+						m.mutate({ ...bookingPayload(v), quoteId: quote?.quoteId })
 					})}
 					className='bg-white rounded-xl border border-slate-200 overflow-hidden'
 				>
@@ -385,17 +418,52 @@ export default function CarDetailPage() {
 					</button>
 					<div className={`overflow-hidden transition-all duration-300 ease-in-out ${openSections.reserve ? 'max-h-[1100px] opacity-100' : 'max-h-0 opacity-0'}`}>
 						<div className='space-y-2 px-3 pb-3'>
-							<Input type='date' {...register('startDate')} />
-							<Input type='date' {...register('endDate')} />
-							<Input placeholder={t.pickupLocation} {...register('pickupLocation')} />
+							<Input type='datetime-local' step={3600} {...register('startDate')} />
+							<Input type='datetime-local' step={3600} {...register('endDate')} />
+							<Select {...register('pickupLocation')} defaultValue=''>
+								<option value=''>{t.pickupLocation}</option>
+								{branches.map(b => <option key={b.id} value={b.name}>{b.name} ({b.timezone})</option>)}
+							</Select>
+							<p className='text-xs text-slate-500'>{t.localTimeHint}</p>
 							<Input placeholder={t.dropoffLocation} {...register('dropoffLocation')} />
 							<Input placeholder={t.notes} {...register('notes')} />
+							<Input placeholder={t.promoCode} {...register('promoCode')} />
 							{visibleExtras.map((e: any) => (
 								<label key={e.id} className='block'>
-									<input type='checkbox' {...register('ex_' + e.id)} /> {e.name} (+${e.pricePerDay}{t.perDay})
+									<input type='checkbox' {...register('ex_' + e.id)} /> {e.name} (+{money(e.pricePerDay, e.currency)}{t.perDay})
 								</label>
 							))}
-							<div className='pt-2'>
+							{quote && (
+								<div className='rounded-lg border border-slate-200 p-2 text-sm'>
+									{quote.lines.map((l, i) => (
+										<div key={i} className={`flex justify-between ${l.kind === 'deposit' ? 'text-slate-500' : l.kind === 'discount' ? 'text-emerald-700' : ''}`}>
+											<span>{l.label}</span>
+											<span>{money(l.amount, quote.currency)}</span>
+										</div>
+									))}
+									<div className='flex justify-between font-semibold border-t border-slate-200 mt-1 pt-1'>
+										<span>{t.quoteTotal}</span>
+										<span>{money(quote.total, quote.currency)}</span>
+									</div>
+									<p className='text-xs text-slate-500'>{t.quoteValidUntil} {new Date(quote.expiresAt).toLocaleTimeString()}</p>
+									{quote.cancellationPolicy && <p className='text-xs text-slate-500'>{t.cancellation}: <CancellationTerms policy={quote.cancellationPolicy} /></p>}
+								</div>
+							)}
+							<div className='pt-2 grid grid-cols-2 gap-2'>
+								<Button
+									type='button'
+									className='w-full'
+									disabled={quoteMutation.isPending}
+									onClick={handleSubmit(v => {
+										if (!user) {
+											toast.error(t.loginRequired)
+											return
+										}
+										quoteMutation.mutate(bookingPayload(v))
+									})}
+								>
+									{t.getQuote}
+								</Button>
 								<Button className='w-full' disabled={m.isPending}>
 									{m.isPending ? t.submitting : t.submit}
 								</Button>
@@ -9,6 +9,7 @@ import { resolveImageSrc } from '../utils/image'
 import { useAuth } from '../auth/AuthContext'
 import { getWishlistIds, toggleWishlistId } from '../utils/wishlist'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 
 const copy: Record<'en' | 'bs', {
 	brandName: string
@@ -33,7 +34,7 @@ const copy: Record<'en' | 'bs', {
 	allFuel: string
 	allStatus: string
 	statusAvailable: string
-	statusRented: string
+	statusRetired: string
 	statusMaintenance: string
 	sortNewest: string
 	sortPriceAsc: string
@@ -44,6 +45,9 @@ const copy: Record<'en' | 'bs', {
 	next: string
 	reserved: string
 	perDay: string
+	availableFrom: string
+	availableTo: string
+	totalFor: string
 	transmissionManual: string
 	transmissionAutomatic: string
 	fuelGasoline: string
@@ -75,7 +79,7 @@ const copy: Record<'en' | 'bs', {
 		allFuel: 'All Fuel',
 		allStatus: 'All Status',
 		statusAvailable: 'Available',
-		statusRented: 'Rented',
+		statusRetired: 'Retired',
 		statusMaintenance: 'Maintenance',
 		sortNewest: 'Newest',
 		sortPriceAsc: 'Price Asc',
@@ -86,6 +90,9 @@ const copy: Record<'en' | 'bs', {
 		next: 'Next',
 		reserved: 'Reserved',
 		perDay: '/day',
+		availableFrom: 'Available from',
+		availableTo: 'Available to',
+		totalFor: 'total for',
 		transmissionManual: 'Manual',
 		transmissionAutomatic: 'Automatic',
 		fuelGasoline: 'Gasoline',
@@ -117,7 +124,7 @@ const copy: Record<'en' | 'bs', {
 		allFuel: 'Sva Goriva',
 		allStatus: 'Svi Statusi',
 		statusAvailable: 'Dostupno',
-		statusRented: 'Iznajmljeno',
+		statusRetired: 'Povučeno',
 		statusMaintenance: 'Servis',
 		sortNewest: 'Najnovije',
 		sortPriceAsc: 'Cijena Raste',
@@ -128,6 +135,9 @@ const copy: Record<'en' | 'bs', {
 		next: 'Dalje',
 		reserved: 'Rezervisano',
 		perDay: '/dan',
+		availableFrom: 'Dostupno od',
+		availableTo: 'Dostupno do',
+		totalFor: 'ukupno za',
 		transmissionManual: 'Manuelni',
 		transmissionAutomatic: 'Automatski',
 		fuelGasoline: 'Benzin',
@@ -175,6 +185,7 @@ const TRANSMISSIONS = [
 export default function CarsPage() {
 	const { user } = useAuth()
 	const { lang } = useLanguage()
+	const { money } = useMoney()
 	const [sp, setSp] = useSearchParams()
 	const [showFilters, setShowFilters] = useState(false)
 	const [q, setQ] = useState(sp.get('q') || '')
@@ -195,7 +206,7 @@ export default function CarsPage() {
 	const limit = 6
 	const currentYear = new Date().getFullYear()
 	const years = Array.from({ length: currentYear - 1989 }, (_, i) => String(currentYear - i))
-	const activeFilterKeys = ['brand', 'model', 'minPrice', 'maxPrice', 'minYear', 'maxYear', 'maxMileage', 'fuel', 'transmission', 'status']
+	const activeFilterKeys = ['brand', 'model', 'minPrice', 'maxPrice', 'minYear', 'maxYear', 'maxMileage', 'fuel', 'transmission', 'status', 'from', 'to']
 	const activeFiltersCount = activeFilterKeys.reduce((acc, key) => (sp.get(key) ? acc + 1 : acc), 0)
 
 	useEffect(() => {
@@ -376,11 +387,13 @@ export default function CarsPage() {
 							</option>
 						))}
 					</Select>
+					<Input type='date' aria-label={t.availableFrom} title={t.availableFrom} value={sp.get('from') || ''} onChange={e => setParam('from', e.target.value)} />
+					<Input type='date' aria-label={t.availableTo} title={t.availableTo} value={sp.get('to') || ''} min={sp.get('from') || undefined} onChange={e => setParam('to', e.target.value)} />
 					<Select value={sp.get('status') || ''} onChange={e => setParam('status', e.target.value)}>
 						<option value=''>{t.allStatus}</option>
 						<option value='available'>{t.statusAvailable}</option>
-						<option value='rented'>{t.statusRented}</option>
 						<option value='maintenance'>{t.statusMaintenance}</option>
+						<option value='retired'>{t.statusRetired}</option>
 					</Select>
 					<Select value={sp.get('sort') || 'newest'} onChange={e => setParam('sort', e.target.value)}>
 						<option value='newest'>{t.sortNewest}</option>
@@ -421,9 +434,9 @@ export default function CarsPage() {
 							<div className='relative'>
 								<img
 									src={resolveImageSrc(c.images?.[0])}
-									className={`h-36 w-full object-cover rounded transition ${c.status === 'rented' ? 'grayscale opacity-60' : ''}`}
+									className={`h-36 w-full object-cover rounded transition ${c.occupancy && !c.occupancy.bookable ? 'grayscale opacity-60' : ''}`}
 								/>
-								{c.status === 'rented' && (
+								{c.occupancy?.occupied && (
 									<span className='absolute top-2 left-2 text-xs px-2 py-1 rounded bg-amber-500 text-white'>
 										{t.reserved}
 									</span>
@@ -453,7 +466,8 @@ export default function CarsPage() {
 								</button>
 							</div>
 							<h2>{c.brand} {c.model}</h2>
-							<p>${c.dailyPrice}{t.perDay}</p>
+							<p>{money(c.dailyPrice, c.currency)}{t.perDay}</p>
+							{c.totalPrice != null && <p className='text-sm text-slate-600'>{money(c.totalPrice, c.currency)} {t.totalFor} {c.rentalDays ? `${c.rentalDays}d` : ''}{c.rentalHours ? ` ${c.rentalHours}h` : ''}</p>}
 						</Link>
 					))}
 				</div>
@@ -6,6 +6,7 @@ import { Link, Navigate, useNavigate } from 'react-router-dom'
 import toast from 'react-hot-toast'
 import { Button, Input } from '../components/UI'
 import { useState } from 'react'
+import { MfaChallenge, TotpEnrollment } from '../api/types'
 
 const schema = z.object({
 	username: z.string().min(3, 'Username must be at least 3 characters'),
@@ -14,18 +15,42 @@ const schema = z.object({
 
 export default function LoginPage() {
 	const nav = useNavigate()
-	const { user, login } = useAuth()
+	const { user, login, startTotpEnrollment, verifyTotp } = useAuth()
 	const [showPassword, setShowPassword] = useState(false)
+	const [challenge, setChallenge] = useState<MfaChallenge | null>(null)
+	const [enrollment, setEnrollment] = useState<TotpEnrollment | null>(null)
+	const [code, setCode] = useState('')
+	const [verifying, setVerifying] = useState(false)
+	const [recoveryCodes, setRecoveryCodes] = useState<string[] | null>(null)
 	const {
 		register,
 		handleSubmit,
 		formState: { errors, isSubmitting },
 	} = useForm({ resolver: zodResolver(schema) })
 
-	if (user) {
+	if (user && !recoveryCodes) {
 		return <Navigate to='/cars' replace />
 	}
 
+	const submitCode = async (e: React.FormEvent) => {
+		e.preventDefault()
+		if (!challenge) return
+		setVerifying(true)
+		try {
+			const codes = await verifyTotp(challenge.preAuthToken, code)
+			toast.success('Logged in')
+			if (codes?.length) {
+				setRecoveryCodes(codes)
+			} else {
+				nav('/cars')
+			}
+		} catch {
+			toast.error('Invalid code')
+		} finally {
+			setVerifying(false)
+		}
+	}
+
 	return (
 		<div className='relative min-h-screen flex items-center justify-center px-4 py-10 overflow-hidden bg-slate-950'>
 			<div className='pointer-events-none absolute inset-0 bg-gradient-to-br from-slate-950 via-slate-900 to-blue-950' />
@@ -49,56 +74,89 @@ export default function LoginPage() {
 				</Link>
 				<h1 className='mb-5 text-center text-2xl font-bold text-slate-900'>Login</h1>
 
-				<form
-					onSubmit={handleSubmit(async (v) => {
-						try {
-							await login(v.username, v.password)
-							toast.success('Logged in')
-							nav('/cars')
-						} catch {
-							toast.error('Invalid credentials')
-						}
-					})}
-					className='space-y-3'
-				>
-					<div>
-						<Input placeholder='Username' {...register('username')} />
-						<p className='mt-1 text-xs text-rose-600'>{errors.username?.message as string}</p>
+				{recoveryCodes ? (
+					<div className='space-y-3 text-sm text-slate-700'>
+						<p>Two-factor authentication is on. Store these recovery codes somewhere safe; each one works once if you lose your authenticator.</p>
+						<ul className='grid grid-cols-2 gap-1 rounded-lg bg-slate-100 p-3 font-mono'>
+							{recoveryCodes.map(c => <li key={c}>{c}</li>)}
+						</ul>
+						<Button className='w-full' onClick={() => nav('/cars')}>Continue</Button>
 					</div>
-					<div>
-						<div className='relative'>
-							<Input
-								type={showPassword ? 'text' : 'password'}
-								placeholder='Password'
-								{...register('password')}
-								className='pr-10'
-							/>
-							<button
-								type='button'
-								aria-label={showPassword ? 'Hide password' : 'Show password'}
-								onClick={() => setShowPassword(v => !v)}
-								className={`no-lift absolute right-2 top-1/2 -translate-y-1/2 rounded-md p-1 transition-colors ${
-									showPassword
-										? 'bg-blue-50 text-blue-700'
-										: 'text-slate-500'
-								}`}
-							>
-								{showPassword ? (
-									<svg viewBox='0 0 24 24' className='h-5 w-5' fill='none' stroke='currentColor' strokeWidth='2'>
-										<path d='M3 3l18 18M10.6 10.6a3 3 0 0 0 4.2 4.2M9.9 4.2A10.9 10.9 0 0 1 12 4c6.3 0 10 8 10 8a18.7 18.7 0 0 1-3.2 4.2M6.6 6.6A18.8 18.8 0 0 0 2 12s3.7 8 10 8a10.8 10.8 0 0 0 5.4-1.4' strokeLinecap='round' strokeLinejoin='round' />
-									</svg>
-								) : (
-									<svg viewBox='0 0 24 24' className='h-5 w-5' fill='none' stroke='currentColor' strokeWidth='2'>
-										<path d='M2 12s3.7-8 10-8 10 8 10 8-3.7 8-10 8-10-8-10-8z' strokeLinecap='round' strokeLinejoin='round' />
-										<circle cx='12' cy='12' r='3' />
-									</svg>
+				) : challenge ? (
+					<form onSubmit={submitCode} className='space-y-3'>
+						{challenge.enrollmentRequired ? (
+							<div className='space-y-2 text-sm text-slate-700'>
+								<p>Your account requires two-factor authentication. Add this key to your authenticator app, then enter the 6-digit code.</p>
+								{enrollment && (
+									<>
+										<p className='break-all rounded-lg bg-slate-100 p-2 font-mono'>{enrollment.secret}</p>
+										<a href={enrollment.otpauthUrl} className='font-medium text-blue-700 hover:text-blue-600'>Open in authenticator app</a>
+									</>
 								)}
-							</button>
+							</div>
+						) : (
+							<p className='text-sm text-slate-700'>Enter the code from your authenticator app or one of your recovery codes.</p>
+						)}
+						<Input placeholder='Code' autoComplete='one-time-code' value={code} onChange={e => setCode(e.target.value)} />
+						<Button className='w-full' disabled={verifying || !code}>Verify</Button>
+					</form>
+				) : (
+					<form
+						onSubmit={handleSubmit(async (v) => {
+							try {
+								const mfa = await login(v.username, v.password)
+								if (mfa) {
+									setChallenge(mfa)
+									if (mfa.enrollmentRequired) setEnrollment(await startTotpEnrollment(mfa.preAuthToken))
+									return
+								}
+								toast.success('Logged in')
+								nav('/cars')
+							} catch {
+								toast.error('Invalid credentials')
+							}
+						})}
+						className='space-y-3'
+					>
+						<div>
+							<Input placeholder='Username' {...register('username')} />
+							<p className='mt-1 text-xs text-rose-600'>{errors.username?.message as string}</p>
 						</div>
-						<p className='mt-1 text-xs text-rose-600'>{errors.password?.message as string}</p>
-					</div>
-					<Button className='w-full' disabled={isSubmitting}>Login</Button>
-				</form>
+						<div>
+							<div className='relative'>
+								<Input
+									type={showPassword ? 'text' : 'password'}
+									placeholder='Password'
+									{...register('password')}
+									className='pr-10'
+								/>
+								<button
+									type='button'
+									aria-label={showPassword ? 'Hide password' : 'Show password'}
+									onClick={() => setShowPassword(v => !v)}
+									className={`no-lift absolute right-2 top-1/2 -translate-y-1/2 rounded-md p-1 transition-colors ${
+										showPassword
+											? 'bg-blue-50 text-blue-700'
+											: 'text-slate-500'
+									}`}
+								>
+									{showPassword ? (
+										<svg viewBox='0 0 24 24' className='h-5 w-5' fill='none' stroke='currentColor' strokeWidth='2'>
+											<path d='M3 3l18 18M10.6 10.6a3 3 0 0 0 4.2 4.2M9.9 4.2A10.9 10.9 0 0 1 12 4c6.3 0 10 8 10 8a18.7 18.7 0 0 1-3.2 4.2M6.6 6.6A18.8 18.8 0 0 0 2 12s3.7 8 10 8a10.8 10.8 0 0 0 5.4-1.4' strokeLinecap='round' strokeLinejoin='round' />
+										</svg>
+									) : (
+										<svg viewBox='0 0 24 24' className='h-5 w-5' fill='none' stroke='currentColor' strokeWidth='2'>
+											<path d='M2 12s3.7-8 10-8 10 8 10 8-3.7 8-10 8-10-8-10-8z' strokeLinecap='round' strokeLinejoin='round' />
+											<circle cx='12' cy='12' r='3' />
+										</svg>
+									)}
+								</button>
+							</div>
+							<p className='mt-1 text-xs text-rose-600'>{errors.password?.message as string}</p>
+						</div>
+						<Button className='w-full' disabled={isSubmitting}>Login</Button>
+					</form>
+				)}
 
 				<p className='mt-4 text-center text-sm text-slate-600'>
 					Don't have an account?{' '}
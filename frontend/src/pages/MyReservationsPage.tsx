@@ -1,10 +1,12 @@
 import { useMutation, useQuery, useQueryClient } from '@tanstack/react-query'
 import toast from 'react-hot-toast'
 import { api } from '../api/client'
-import { Table } from '../components/UI'
+import type { CancellationQuote } from '../api/types'
+import { PriceLines, Table } from '../components/UI'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 
-const FLOW = ['pending', 'approved', 'active', 'completed'] as const
+const FLOW = ['awaiting_payment', 'pending', 'approved', 'active', 'completed'] as const
 const copy = {
 	en: {
 		title: 'My Reservations',
@@ -17,6 +19,19 @@ const copy = {
 		cancel: 'Cancel',
 		cancelledOk: 'Reservation cancelled',
 		cancelFail: 'Failed to cancel reservation',
+		pay: 'Pay',
+		paidOk: 'Payment received',
+		payFail: 'Payment failed',
+		invoice: 'Invoice',
+		invoiceFail: 'Failed to download the invoice',
+		payment: 'Payment',
+		deposit: 'Deposit',
+		confirmFree: 'Cancel this reservation? Cancelling now is free.',
+		confirmFee: 'Cancel this reservation? Cancelling now costs',
+		refund: 'Refund',
+		cancellationFee: 'Cancellation fee',
+		promoCode: 'Promo code',
+		awaiting_payment: 'Awaiting payment',
 		pending: 'Pending',
 		approved: 'Approved',
 		active: 'Active',
@@ -35,6 +50,19 @@ const copy = {
 		cancel: 'Otkazi',
 		cancelledOk: 'Rezervacija je otkazana',
 		cancelFail: 'Otkazivanje rezervacije nije uspjelo',
+		pay: 'Plati',
+		paidOk: 'Placanje primljeno',
+		payFail: 'Placanje nije uspjelo',
+		invoice: 'Racun',
+		invoiceFail: 'Preuzimanje racuna nije uspjelo',
+		payment: 'Placanje',
+		deposit: 'Depozit',
+		confirmFree: 'Otkazati rezervaciju? Otkazivanje je sada besplatno.',
+		confirmFee: 'Otkazati rezervaciju? Otkazivanje sada kosta',
+		refund: 'Povrat',
+		cancellationFee: 'Naknada za otkazivanje',
+		promoCode: 'Promo kod',
+		awaiting_payment: 'Ceka placanje',
 		pending: 'Na cekanju',
 		approved: 'Odobreno',
 		active: 'Aktivno',
@@ -45,6 +73,7 @@ const copy = {
 } as const
 
 const statusText = (status: string, t: (typeof copy)['en']) => {
+	if (status === 'awaiting_payment') return t.awaiting_payment
 	if (status === 'pending') return t.pending
 	if (status === 'approved') return t.approved
 	if (status === 'active') return t.active
@@ -89,14 +118,23 @@ export default function MyReservationsPage() {
 	const { lang } = useLanguage()
 	const t = copy[lang]
 	const qc = useQueryClient()
+	const { money } = useMoney()
 	const { data = [] } = useQuery({
 		queryKey: ['myres'],
 		queryFn: async () => (await api.get('/reservations/my')).data,
 		refetchInterval: 10000,
 	})
 	const m = useMutation({
-		mutationFn: (id: string) => api.patch(`/reservations/${id}/cancel`),
-		onSuccess: async () => {
+		// Shows what cancelling costs under the booking's policy before doing it.
+		mutationFn: async (id: string) => {
+			const q: CancellationQuote = (await api.get(`/reservations/${id}/cancellation`)).data
+			const prompt = q.fee > 0 ? `${t.confirmFee} ${money(q.fee, q.currency)}. ${t.refund}: ${money(q.refund, q.currency)}.` : t.confirmFree
+			if (!window.confirm(prompt)) return false
+			await api.patch(`/reservations/${id}/cancel`)
+			return true
+		},
+		onSuccess: async (cancelled) => {
+			if (!cancelled) return
 			toast.success(t.cancelledOk)
 			await qc.invalidateQueries({ queryKey: ['myres'] })
 		},
@@ -105,6 +143,25 @@ export default function MyReservationsPage() {
 			toast.error(msg)
 		},
 	})
+	const pay = useMutation({
+		mutationFn: (id: string) => api.post(`/reservations/${id}/pay`),
+		onSuccess: async () => {
+			toast.success(t.paidOk)
+			await qc.invalidateQueries({ queryKey: ['myres'] })
+		},
+		onError: async (err: any) => {
+			toast.error(err?.response?.data?.error || t.payFail)
+			await qc.invalidateQueries({ queryKey: ['myres'] })
+		},
+	})
+	const downloadInvoice = async (id: string) => {
+		try {
+			const res = await api.get(`/reservations/${id}/invoice`, { params: { format: 'pdf' }, responseType: 'blob' })
+			window.open(URL.createObjectURL(res.data), '_blank')
+		} catch {
+			toast.error(t.invoiceFail)
+		}
+	}
 
 	return (
 		<div>
@@ -114,12 +171,24 @@ export default function MyReservationsPage() {
 				rows={data.map((r: any) => (
 					<>
 						<td className='p-2'>{r.car?.brand} {r.car?.model}</td>
-						<td className='p-2'>{r.startDate.slice(0, 10)} - {r.endDate.slice(0, 10)}</td>
+						<td className='p-2'>{r.startDate.slice(0, 16).replace('T', ' ')} - {r.endDate.slice(0, 16).replace('T', ' ')} <span className='text-xs text-slate-500'>{r.timezone}</span></td>
 						<td className='p-2 capitalize'>{statusText(r.status, t)}</td>
 						<td className='p-2'><StatusTimeline status={r.status} t={t} /></td>
-						<td className='p-2'>${r.totalPrice}</td>
 						<td className='p-2'>
-							<button onClick={() => m.mutate(r.id)} disabled={m.isPending || ['cancelled', 'completed', 'denied'].includes(r.status)}>
+							<PriceLines total={r.totalPrice} currency={r.currency} lines={r.lineItems} />
+							{r.payment && <div className='text-xs text-slate-500'>{t.payment}: {r.payment.status.replace('_', ' ')}</div>}
+							{r.promoCode && <div className='text-xs text-slate-500'>{t.promoCode}: {r.promoCode} (-{money(r.discount ?? 0, r.currency)})</div>}
+							{r.cancellationFee != null && <div className='text-xs text-slate-500'>{t.cancellationFee}: {money(r.cancellationFee, r.currency)}, {t.refund}: {money(r.refund ?? 0, r.currency)}</div>}
+							{r.deposit && <div className='text-xs text-slate-500'>{t.deposit}: {r.deposit.status.replace('_', ' ')}{r.deposit.captured > 0 && ` (${r.deposit.captured} ${r.deposit.currency})`}</div>}
+						</td>
+						<td className='p-2 space-x-2'>
+							{r.status === 'awaiting_payment' && r.payment?.status !== 'pending' && (
+								<button onClick={() => pay.mutate(r.id)} disabled={pay.isPending}>
+									{t.pay}
+								</button>
+							)}
+							{r.status === 'completed' && <button onClick={() => downloadInvoice(r.id)}>{t.invoice}</button>}
+							<button onClick={() => m.mutate(r.id)} disabled={m.isPending || !r.canCancel}>
 								{t.cancel}
 							</button>
 						</td>
@@ -8,6 +8,7 @@ import { Car } from '../api/types'
 import { resolveImageSrc } from '../utils/image'
 import { getWishlistIds, toggleWishlistId } from '../utils/wishlist'
 import { useLanguage } from '../hooks/useLanguage'
+import { useMoney } from '../hooks/useMoney'
 
 const copy = {
 	en: {
@@ -27,6 +28,7 @@ const copy = {
 export default function WishlistPage() {
 	const { user } = useAuth()
 	const { lang } = useLanguage()
+	const { money } = useMoney()
 	const t = copy[lang]
 	const { data } = useQuery({
 		queryKey: ['wishlist-cars'],
@@ -58,7 +60,7 @@ export default function WishlistPage() {
 							<Link to={`/cars/${c.id}`} className='block'>
 								<img src={resolveImageSrc(c.images?.[0])} className='h-36 w-full object-cover rounded' />
 								<h2 className='mt-2'>{c.brand} {c.model}</h2>
-								<p>${c.dailyPrice}/day</p>
+								<p>{money(c.dailyPrice, c.currency)}/day</p>
 							</Link>
 							<button
 								type='button'